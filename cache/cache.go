@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is absent or expired.
+var ErrMiss = errors.New("cache: miss")
+
+// Cache stores opaque values under string keys. Every value may carry tags so
+// a whole group of keys can be dropped at once with InvalidateTags.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	Delete(ctx context.Context, keys ...string) error
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+type namespaced struct {
+	next   Cache
+	prefix string
+}
+
+// WithNamespace prefixes every key and tag with ns, so several services or
+// handlers can share one backend without colliding.
+func WithNamespace(c Cache, ns string) Cache {
+	return &namespaced{next: c, prefix: ns + ":"}
+}
+
+func (n *namespaced) prefixed(keys []string) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = n.prefix + k
+	}
+	return out
+}
+
+func (n *namespaced) Get(ctx context.Context, key string) ([]byte, error) {
+	return n.next.Get(ctx, n.prefix+key)
+}
+
+func (n *namespaced) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	return n.next.Set(ctx, n.prefix+key, value, ttl, n.prefixed(tags)...)
+}
+
+func (n *namespaced) Delete(ctx context.Context, keys ...string) error {
+	return n.next.Delete(ctx, n.prefixed(keys)...)
+}
+
+func (n *namespaced) InvalidateTags(ctx context.Context, tags ...string) error {
+	return n.next.InvalidateTags(ctx, n.prefixed(tags)...)
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testCache runs the behaviour every Cache shares against c, which must be
+// empty.
+func testCache(t *testing.T, c Cache) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(missing) error = %v, want ErrMiss", err)
+	}
+	if err := c.Set(ctx, "a", []byte("1"), time.Minute, "t1", "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "b", []byte("2"), 0, "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "c", []byte("3"), time.Minute, "t3"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "a"); err != nil || string(v) != "1" {
+		t.Errorf("Get(a) = %q, %v, want 1", v, err)
+	}
+
+	if err := c.InvalidateTags(ctx, "t2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, ErrMiss) {
+			t.Errorf("Get(%s) after invalidating its tag error = %v, want ErrMiss", key, err)
+		}
+	}
+	if v, err := c.Get(ctx, "c"); err != nil || string(v) != "3" {
+		t.Errorf("Get(c) with another tag = %q, %v, want 3", v, err)
+	}
+
+	// Keys stored after an invalidation are tracked under the tag again.
+	if err := c.Set(ctx, "a", []byte("4"), time.Minute, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InvalidateTags(ctx, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(a) after the second invalidation error = %v, want ErrMiss", err)
+	}
+
+	if err := c.Delete(ctx, "c", "never-set"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "c"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(c) after Delete error = %v, want ErrMiss", err)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+	shared := NewLRU(10)
+	a, b := WithNamespace(shared, "a"), WithNamespace(shared, "b")
+	testCache(t, a)
+
+	if err := a.Set(ctx, "k", []byte("a"), 0, "t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set(ctx, "k", []byte("b"), 0, "t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.InvalidateTags(ctx, "t"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.Get(ctx, "k"); err != nil || string(v) != "b" {
+		t.Errorf("other namespace after invalidation = %q, %v, want b", v, err)
+	}
+	if v, err := shared.Get(ctx, "b:k"); err != nil || string(v) != "b" {
+		t.Errorf("prefixed key = %q, %v, want b", v, err)
+	}
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Fallback serves from primary, typically Redis, and switches to secondary,
+// typically an in-process LRU, while primary fails. After an error primary
+// is left alone for cooldown, so requests do not each wait on a dead
+// server, then tried again.
+//
+// Writes go to both so secondary is warm when primary goes down. Deletes
+// and invalidations made while primary is down do not reach it, so it may
+// serve entries they dropped until those expire.
+type Fallback struct {
+	primary   Cache
+	secondary Cache
+	cooldown  time.Duration
+	// downUntil is when primary is tried again, in Unix nanoseconds.
+	downUntil atomic.Int64
+}
+
+func NewFallback(primary, secondary Cache, cooldown time.Duration) *Fallback {
+	return &Fallback{primary: primary, secondary: secondary, cooldown: cooldown}
+}
+
+// degraded reports whether primary is being skipped.
+func (f *Fallback) degraded() bool {
+	return time.Now().UnixNano() < f.downUntil.Load()
+}
+
+// try calls fn on primary unless it is cooling down, and reports whether it
+// succeeded. Misses count as success.
+func (f *Fallback) try(ctx context.Context, fn func(Cache) error) bool {
+	if f.degraded() {
+		return false
+	}
+	err := fn(f.primary)
+	if err == nil || errors.Is(err, ErrMiss) {
+		return true
+	}
+	if f.downUntil.Swap(time.Now().Add(f.cooldown).UnixNano()) < time.Now().UnixNano() {
+		slog.WarnContext(ctx, "Cache backend failed, using the fallback", "cooldown", f.cooldown.String(), "error", err)
+	}
+	return false
+}
+
+func (f *Fallback) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	var err error
+	if f.try(ctx, func(c Cache) error {
+		value, err = c.Get(ctx, key)
+		return err
+	}) {
+		return value, err
+	}
+	return f.secondary.Get(ctx, key)
+}
+
+func (f *Fallback) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	f.try(ctx, func(c Cache) error { return c.Set(ctx, key, value, ttl, tags...) })
+	return f.secondary.Set(ctx, key, value, ttl, tags...)
+}
+
+func (f *Fallback) Delete(ctx context.Context, keys ...string) error {
+	f.try(ctx, func(c Cache) error { return c.Delete(ctx, keys...) })
+	return f.secondary.Delete(ctx, keys...)
+}
+
+func (f *Fallback) InvalidateTags(ctx context.Context, tags ...string) error {
+	f.try(ctx, func(c Cache) error { return c.InvalidateTags(ctx, tags...) })
+	return f.secondary.InvalidateTags(ctx, tags...)
+}
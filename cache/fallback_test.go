@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+// flaky is a Cache that fails every call while down is set.
+type flaky struct {
+	Cache
+	down  atomic.Bool
+	calls atomic.Int64
+}
+
+func (f *flaky) call() error {
+	f.calls.Add(1)
+	if f.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+func (f *flaky) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := f.call(); err != nil {
+		return nil, err
+	}
+	return f.Cache.Get(ctx, key)
+}
+
+func (f *flaky) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	return f.Cache.Set(ctx, key, value, ttl, tags...)
+}
+
+func (f *flaky) Delete(ctx context.Context, keys ...string) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	return f.Cache.Delete(ctx, keys...)
+}
+
+func (f *flaky) InvalidateTags(ctx context.Context, tags ...string) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	return f.Cache.InvalidateTags(ctx, tags...)
+}
+
+func TestFallback(t *testing.T) {
+	testCache(t, NewFallback(&flaky{Cache: NewLRU(10)}, NewLRU(10), time.Minute))
+}
+
+func TestFallbackWritesBoth(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := NewLRU(10), NewLRU(10)
+	f := NewFallback(primary, secondary, time.Minute)
+	f.Set(ctx, "k", []byte("v"), 0, "t")
+
+	for name, c := range map[string]Cache{"primary": primary, "secondary": secondary} {
+		if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v" {
+			t.Errorf("%s Get = %q, %v, want v", name, v, err)
+		}
+	}
+	f.InvalidateTags(ctx, "t")
+	for name, c := range map[string]Cache{"primary": primary, "secondary": secondary} {
+		if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrMiss) {
+			t.Errorf("%s Get after invalidation error = %v, want ErrMiss", name, err)
+		}
+	}
+}
+
+func TestFallbackWhilePrimaryIsDown(t *testing.T) {
+	ctx := context.Background()
+	primary := &flaky{Cache: NewLRU(10)}
+	f := NewFallback(primary, NewLRU(10), 50*time.Millisecond)
+	f.Set(ctx, "k", []byte("v"), 0)
+
+	primary.down.Store(true)
+	if v, err := f.Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("Get with primary down = %q, %v, want v from the fallback", v, err)
+	}
+	// A miss on the fallback is a miss, not the primary's error.
+	if _, err := f.Get(ctx, "missing"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(missing) with primary down error = %v, want ErrMiss", err)
+	}
+	if err := f.Set(ctx, "k2", []byte("v2"), 0); err != nil {
+		t.Errorf("Set with primary down error = %v, want nil", err)
+	}
+	// Primary is not retried while it cools down.
+	if calls := primary.calls.Load(); calls != 2 {
+		t.Errorf("primary was called %d times, want 2: the initial Set and the failing Get", calls)
+	}
+
+	primary.down.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := f.Get(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := primary.calls.Load(); calls != 3 {
+		t.Errorf("primary was called %d times after the cooldown, want 3", calls)
+	}
+	// Misses on a healthy primary do not fall through to the secondary.
+	if _, err := f.Get(ctx, "k2"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(k2) error = %v, want the primary's miss", err)
+	}
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process cache holding at most size entries. The least
+// recently used entry is evicted first.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	tags  map[string]map[string]struct{}
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+func NewLRU(size int) *LRU {
+	if size <= 0 {
+		size = 1024
+	}
+	return &LRU{
+		size:  size,
+		ll:    list.New(),
+		items: map[string]*list.Element{},
+		tags:  map[string]map[string]struct{}{},
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, ErrMiss
+	}
+	c.ll.MoveToFront(el)
+	return e.value, nil
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	e := &lruEntry{key: key, value: value, tags: tags}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	c.items[key] = c.ll.PushFront(e)
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = map[string]struct{}{}
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+func (c *LRU) InvalidateTags(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.items[key]; ok {
+				c.remove(el)
+			}
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+// remove must be called with c.mu held.
+func (c *LRU) remove(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	for _, tag := range e.tags {
+		delete(c.tags[tag], e.key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	testCache(t, NewLRU(10))
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	c.Set(ctx, "a", []byte("1"), 0, "t")
+	c.Set(ctx, "b", []byte("2"), 0, "t")
+	// Reading a makes b the least recently used entry.
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(b) error = %v, want it evicted", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("Get(%s) error = %v, want a hit", key, err)
+		}
+	}
+	// Evicted keys leave their tags.
+	if _, ok := c.tags["t"]["b"]; ok {
+		t.Error("evicted key is still listed under its tag")
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	c.Set(ctx, "short", []byte("1"), 10*time.Millisecond)
+	c.Set(ctx, "forever", []byte("2"), 0)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(short) error = %v, want it expired", err)
+	}
+	if _, err := c.Get(ctx, "forever"); err != nil {
+		t.Errorf("Get(forever) error = %v, want a hit", err)
+	}
+	if c.ll.Len() != 1 {
+		t.Errorf("%d entries left, want the expired one removed", c.ll.Len())
+	}
+}
+
+func TestLRUReplace(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	c.Set(ctx, "k", []byte("1"), 0, "old")
+	c.Set(ctx, "k", []byte("2"), 0, "new")
+
+	// Replacing a value drops the tags it was stored with.
+	c.InvalidateTags(ctx, "old")
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "2" {
+		t.Errorf("Get(k) = %q, %v, want 2", v, err)
+	}
+	c.InvalidateTags(ctx, "new")
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get(k) after invalidating its tag error = %v, want ErrMiss", err)
+	}
+}
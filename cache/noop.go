@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Noop never stores anything. Every Get is a miss.
+type Noop struct{}
+
+func NewNoop() Noop {
+	return Noop{}
+}
+
+func (Noop) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+func (Noop) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	return nil
+}
+
+func (Noop) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+func (Noop) InvalidateTags(ctx context.Context, tags ...string) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// Redis keeps values in Redis. Tags are Redis sets named "tag:<tag>" that
+// hold the keys stored with that tag.
+type Redis struct {
+	rdb *redis.Client
+}
+
+func NewRedis(rdb *redis.Client) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+// addToTag adds ARGV[2] to the tag set KEYS[1] and raises the set's TTL to
+// ARGV[1] seconds, so the set outlives every key it points at. The TTL is
+// never lowered, and a set that already has no expiry, because a key
+// without TTL is in it, keeps none.
+var addToTag = redis.NewScript(`
+local ttl = redis.call("TTL", KEYS[1])
+redis.call("SADD", KEYS[1], ARGV[2])
+if ttl == -2 or (ttl >= 0 and ttl < tonumber(ARGV[1])) then
+	return redis.call("EXPIRE", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+func tagKey(tag string) string {
+	return "tag:" + tag
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := c.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+	return val, err
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, key, value, ttl)
+	for _, tag := range tags {
+		if ttl > 0 {
+			addToTag.Eval(ctx, pipe, []string{tagKey(tag)}, int(ttl.Seconds())+1, key)
+		} else {
+			pipe.SAdd(ctx, tagKey(tag), key)
+			pipe.Persist(ctx, tagKey(tag))
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.rdb.Del(ctx, keys...).Err()
+}
+
+func (c *Redis) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := c.rdb.SMembers(ctx, tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+		if err := c.Delete(ctx, append(keys, tagKey(tag))...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
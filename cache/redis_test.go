@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// redisForTest returns a client for the emptied database named by
+// TEST_REDIS_URL and skips the test when the variable is not set.
+func redisForTest(t *testing.T) *redis.Client {
+	t.Helper()
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL is not set")
+	}
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb := redis.NewClient(opt)
+	t.Cleanup(func() { rdb.Close() })
+	if err := rdb.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	return rdb
+}
+
+func TestRedis(t *testing.T) {
+	testCache(t, NewRedis(redisForTest(t)))
+}
+
+func TestRedisTagTTL(t *testing.T) {
+	ctx := context.Background()
+	rdb := redisForTest(t)
+	c := NewRedis(rdb)
+
+	c.Set(ctx, "a", []byte("1"), time.Minute, "t")
+	c.Set(ctx, "b", []byte("2"), time.Hour, "t")
+	c.Set(ctx, "c", []byte("3"), time.Minute, "t")
+	// The tag set outlives its longest-lived key.
+	if ttl := rdb.TTL(ctx, tagKey("t")).Val(); ttl <= time.Minute || ttl > time.Hour+time.Second {
+		t.Errorf("tag TTL = %v, want just over an hour", ttl)
+	}
+
+	c.Set(ctx, "d", []byte("4"), 0, "t")
+	c.Set(ctx, "e", []byte("5"), time.Minute, "t")
+	// Once it points at a key without expiry it never expires.
+	if ttl := rdb.TTL(ctx, tagKey("t")).Val(); ttl != -1 {
+		t.Errorf("tag TTL with a persistent key = %v, want none", ttl)
+	}
+}
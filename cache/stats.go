@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// Stats counts the outcome of Get calls.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	Errors uint64
+}
+
+// HitRatio is the share of lookups answered from the cache, 0 before the
+// first lookup. Errors count as misses.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses + s.Errors
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Counting passes every call to the wrapped Cache and counts the results
+// of Get.
+type Counting struct {
+	Cache
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+func NewCounting(c Cache) *Counting {
+	return &Counting{Cache: c}
+}
+
+func (c *Counting) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.Cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+	case errors.Is(err, ErrMiss):
+		c.misses.Add(1)
+	default:
+		c.errors.Add(1)
+	}
+	return value, err
+}
+
+func (c *Counting) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Errors: c.errors.Load()}
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCounting(t *testing.T) {
+	ctx := context.Background()
+	c := NewCounting(NewLRU(10))
+	c.Set(ctx, "k", []byte("v"), 0)
+	c.Get(ctx, "k")
+	c.Get(ctx, "k")
+	c.Get(ctx, "missing")
+
+	stats := c.Stats()
+	if stats != (Stats{Hits: 2, Misses: 1}) {
+		t.Errorf("Stats = %+v, want 2 hits and 1 miss", stats)
+	}
+	if got, want := stats.HitRatio(), 2.0/3; got != want {
+		t.Errorf("HitRatio = %v, want %v", got, want)
+	}
+	if got := (Stats{}).HitRatio(); got != 0 {
+		t.Errorf("HitRatio before any lookup = %v, want 0", got)
+	}
+}
@@ -0,0 +1,61 @@
+// Package comic reads and writes chapters as comic book archives: CBZ
+// files, ZIP archives of page images with an optional ComicInfo.xml.
+package comic
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+// ComicInfo is the subset of the ComicRack ComicInfo.xml schema (v2.0)
+// mapped to mangas and chapters.
+type ComicInfo struct {
+	XMLName     xml.Name `xml:"ComicInfo"`
+	Title       string   `xml:"Title,omitempty"`
+	Series      string   `xml:"Series,omitempty"`
+	Number      string   `xml:"Number,omitempty"`
+	Summary     string   `xml:"Summary,omitempty"`
+	Year        int      `xml:"Year,omitempty"`
+	Month       int      `xml:"Month,omitempty"`
+	Day         int      `xml:"Day,omitempty"`
+	Writer      string   `xml:"Writer,omitempty"`
+	Genre       string   `xml:"Genre,omitempty"`
+	Web         string   `xml:"Web,omitempty"`
+	PageCount   int      `xml:"PageCount,omitempty"`
+	LanguageISO string   `xml:"LanguageISO,omitempty"`
+	// Manga is "Yes", "YesAndRightToLeft" or "No".
+	Manga string `xml:"Manga,omitempty"`
+}
+
+// maxComicInfoSize bounds ComicInfo.xml, real ones are a few kilobytes.
+const maxComicInfoSize = 1 << 20
+
+// ParseComicInfo decodes a ComicInfo.xml document.
+func ParseComicInfo(r io.Reader) (ComicInfo, error) {
+	var info ComicInfo
+	err := xml.NewDecoder(io.LimitReader(r, maxComicInfoSize)).Decode(&info)
+	return info, err
+}
+
+// Date returns the release date, or the zero time when Year is missing.
+// A missing month or day counts as the first.
+func (c ComicInfo) Date() time.Time {
+	if c.Year <= 0 {
+		return time.Time{}
+	}
+	month, day := max(c.Month, 1), max(c.Day, 1)
+	return time.Date(c.Year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Genres splits the comma separated Genre element.
+func (c ComicInfo) Genres() []string {
+	var genres []string
+	for _, g := range strings.Split(c.Genre, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			genres = append(genres, g)
+		}
+	}
+	return genres
+}
@@ -0,0 +1,391 @@
+package comic
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/store"
+	_ "golang.org/x/image/webp"
+)
+
+// prefetch is how many pages are fetched ahead of the one being written,
+// and so roughly how many are held in memory.
+const prefetch = 4
+
+// Export is a manga and the chapters to put in one archive, in order.
+type Export struct {
+	Manga    store.Manga
+	Chapters []store.Chapter
+}
+
+// Filename names the archive after the manga and the chapter numbers.
+func (x Export) Filename(ext string) string {
+	first, last := x.Chapters[0].Chapter, x.Chapters[len(x.Chapters)-1].Chapter
+	if first == last {
+		return fmt.Sprintf("%s - %03d.%s", x.Manga.Name, first, ext)
+	}
+	return fmt.Sprintf("%s - %03d-%03d.%s", x.Manga.Name, first, last, ext)
+}
+
+// ComicInfo describes the export. Single chapters get their number and
+// name, ranges only the series.
+func (x Export) ComicInfo() ComicInfo {
+	info := ComicInfo{
+		Series:  x.Manga.Name,
+		Summary: x.Manga.Describe,
+		Year:    x.Manga.Published,
+		Writer:  x.Manga.Author,
+		Genre:   strings.Join(x.Manga.Genres, ", "),
+		Manga:   "Yes",
+	}
+	if x.Manga.Country == "JP" {
+		info.Manga = "YesAndRightToLeft"
+	}
+	if len(x.Chapters) == 1 {
+		info.Number = strconv.Itoa(x.Chapters[0].Chapter)
+		info.Title = x.Chapters[0].Name
+	}
+	for _, c := range x.Chapters {
+		info.PageCount += len(c.Img)
+	}
+	return info
+}
+
+// Exporter writes chapters as CBZ and EPUB archives. Archives are streamed:
+// only the next few pages are held in memory, whatever the archive size.
+type Exporter struct {
+	pages PageSource
+	now   func() time.Time
+}
+
+func NewExporter(pages PageSource) *Exporter {
+	return &Exporter{pages: pages, now: time.Now}
+}
+
+// page is a fetched page: the index-th, from 0, of chapter.
+type page struct {
+	chapter     int
+	index       int
+	data        []byte
+	contentType string
+	err         error
+}
+
+// fetch sends the pages of x in order, fetching up to prefetch pages ahead.
+// The channel is closed after the last page or the first error.
+func (e *Exporter) fetch(ctx context.Context, x Export) <-chan page {
+	out := make(chan page, prefetch)
+	go func() {
+		defer close(out)
+		for _, c := range x.Chapters {
+			for i, url := range c.Img {
+				p := page{chapter: c.Chapter, index: i}
+				p.data, p.err = e.pages.Page(ctx, url)
+				if p.err == nil {
+					p.contentType, p.err = images.Sniff(p.data)
+				}
+				if p.err != nil {
+					p.err = fmt.Errorf("comic: chapter %d page %d: %w", c.Chapter, i+1, p.err)
+				}
+				select {
+				case out <- p:
+				case <-ctx.Done():
+					return
+				}
+				if p.err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// WriteCBZ writes x as a CBZ: the pages, in folders per chapter when there
+// are several, and a ComicInfo.xml.
+func (e *Exporter) WriteCBZ(ctx context.Context, w io.Writer, x Export) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	zw := zip.NewWriter(w)
+	modified := e.now()
+
+	for p := range e.fetch(ctx, x) {
+		if p.err != nil {
+			return p.err
+		}
+		name := fmt.Sprintf("%04d.%s", p.index+1, images.Extension(p.contentType))
+		if len(x.Chapters) > 1 {
+			name = fmt.Sprintf("Chapter %04d/%s", p.chapter, name)
+		}
+		if err := storeFile(zw, name, p.data, modified); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	info, err := xml.MarshalIndent(x.ComicInfo(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := deflateFile(zw, "ComicInfo.xml", append([]byte(xml.Header), info...), modified); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// storeFile writes data uncompressed, with the CRC and sizes in the local
+// header rather than in a trailing data descriptor. Images are compressed
+// already, and EPUB requires this form for the mimetype file.
+func storeFile(zw *zip.Writer, name string, data []byte, modified time.Time) error {
+	fh := &zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(data)),
+	}
+	if !modified.IsZero() {
+		fh.ModifiedDate, fh.ModifiedTime = msDosTime(modified)
+	}
+	w, err := zw.CreateRaw(fh)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func deflateFile(zw *zip.Writer, name string, data []byte, modified time.Time) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// msDosTime converts t to the date and time fields of a ZIP header.
+func msDosTime(t time.Time) (date, tm uint16) {
+	t = t.UTC()
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	tm = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, tm
+}
+
+// identifier derives a stable urn:uuid (version 5 style) for x, so
+// downloading the same chapters twice yields the same book.
+func (x Export) identifier() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\x00", x.Manga.Name)
+	for _, c := range x.Chapters {
+		fmt.Fprintf(h, "%d\x00", c.Chapter)
+	}
+	b := h.Sum(nil)[:16]
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// defaultViewport is used for pages whose size cannot be decoded, such as
+// AVIF ones.
+var defaultViewport = image.Point{X: 800, Y: 1200}
+
+// epubPage is a page written to an EPUB, remembered for the package
+// document written at the end.
+type epubPage struct {
+	id          string
+	chapter     int
+	contentType string
+	ext         string
+}
+
+// WriteEPUB writes x as a fixed-layout EPUB 3 with one page per image,
+// right to left for Japanese mangas.
+func (e *Exporter) WriteEPUB(ctx context.Context, w io.Writer, x Export) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	zw := zip.NewWriter(w)
+	modified := e.now()
+
+	if err := storeFile(zw, "mimetype", []byte("application/epub+zip"), modified); err != nil {
+		return err
+	}
+	if err := deflateFile(zw, "META-INF/container.xml", []byte(containerXML), modified); err != nil {
+		return err
+	}
+
+	// Entries may come in any order after mimetype, so pages are streamed
+	// first and the package document listing them is written last.
+	var pages []epubPage
+	for p := range e.fetch(ctx, x) {
+		if p.err != nil {
+			return p.err
+		}
+		ep := epubPage{
+			id:          fmt.Sprintf("c%04d-%04d", p.chapter, p.index+1),
+			chapter:     p.chapter,
+			contentType: p.contentType,
+			ext:         images.Extension(p.contentType),
+		}
+		size := defaultViewport
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(p.data)); err == nil {
+			size = image.Point{X: cfg.Width, Y: cfg.Height}
+		}
+		if err := storeFile(zw, "OEBPS/images/"+ep.id+"."+ep.ext, p.data, modified); err != nil {
+			return err
+		}
+		title := fmt.Sprintf("%s %d, page %d", x.Manga.Name, p.chapter, p.index+1)
+		if err := deflateFile(zw, "OEBPS/pages/"+ep.id+".xhtml", pageXHTML(title, ep, size), modified); err != nil {
+			return err
+		}
+		pages = append(pages, ep)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := deflateFile(zw, "OEBPS/nav.xhtml", navXHTML(x, pages), modified); err != nil {
+		return err
+	}
+	if err := deflateFile(zw, "OEBPS/content.opf", packageOPF(x, pages, modified), modified); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func esc(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func pageXHTML(title string, p epubPage, size image.Point) []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>%s</title>
+  <meta name="viewport" content="width=%d, height=%d"/>
+  <style>html, body { margin: 0; padding: 0; } img { display: block; width: 100%%; height: 100%%; }</style>
+</head>
+<body>
+  <img src="../images/%s.%s" alt="%s"/>
+</body>
+</html>
+`, esc(title), size.X, size.Y, p.id, p.ext, esc(title)))
+}
+
+func navXHTML(x Export, pages []epubPage) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>%s</title></head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <ol>
+`, esc(x.Manga.Name))
+	first := map[int]string{}
+	for _, p := range pages {
+		if _, ok := first[p.chapter]; !ok {
+			first[p.chapter] = p.id
+		}
+	}
+	for _, c := range x.Chapters {
+		id, ok := first[c.Chapter]
+		if !ok {
+			continue
+		}
+		label := "Chapter " + strconv.Itoa(c.Chapter)
+		if c.Name != "" {
+			label += ": " + c.Name
+		}
+		fmt.Fprintf(&b, "      <li><a href=\"pages/%s.xhtml\">%s</a></li>\n", id, esc(label))
+	}
+	b.WriteString("    </ol>\n  </nav>\n</body>\n</html>\n")
+	return []byte(b.String())
+}
+
+func packageOPF(x Export, pages []epubPage, modified time.Time) []byte {
+	info := x.ComicInfo()
+	title := x.Manga.Name
+	if len(x.Chapters) == 1 {
+		title += " " + strconv.Itoa(x.Chapters[0].Chapter)
+	} else {
+		title += fmt.Sprintf(" %d-%d", x.Chapters[0].Chapter, x.Chapters[len(x.Chapters)-1].Chapter)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" prefix="rendition: http://www.idpf.org/vocab/rendition/#">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:language>und</dc:language>
+`, x.identifier(), esc(title))
+	if info.Writer != "" {
+		fmt.Fprintf(&b, "    <dc:creator>%s</dc:creator>\n", esc(info.Writer))
+	}
+	if info.Summary != "" {
+		fmt.Fprintf(&b, "    <dc:description>%s</dc:description>\n", esc(info.Summary))
+	}
+	for _, g := range x.Manga.Genres {
+		fmt.Fprintf(&b, "    <dc:subject>%s</dc:subject>\n", esc(g))
+	}
+	if info.Year > 0 {
+		fmt.Fprintf(&b, "    <dc:date>%04d</dc:date>\n", info.Year)
+	}
+	fmt.Fprintf(&b, `    <meta property="dcterms:modified">%s</meta>
+    <meta property="rendition:layout">pre-paginated</meta>
+    <meta property="rendition:orientation">portrait</meta>
+    <meta property="rendition:spread">none</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+`, modified.UTC().Format(time.RFC3339))
+	for i, p := range pages {
+		cover := ""
+		if i == 0 {
+			cover = ` properties="cover-image"`
+		}
+		fmt.Fprintf(&b, "    <item id=\"page-%s\" href=\"pages/%s.xhtml\" media-type=\"application/xhtml+xml\"/>\n", p.id, p.id)
+		fmt.Fprintf(&b, "    <item id=\"image-%s\" href=\"images/%s.%s\" media-type=\"%s\"%s/>\n", p.id, p.id, p.ext, p.contentType, cover)
+	}
+	direction := "ltr"
+	if info.Manga == "YesAndRightToLeft" {
+		direction = "rtl"
+	}
+	fmt.Fprintf(&b, "  </manifest>\n  <spine page-progression-direction=\"%s\">\n", direction)
+	for _, p := range pages {
+		fmt.Fprintf(&b, "    <itemref idref=\"page-%s\"/>\n", p.id)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,188 @@
+package comic
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chimas/GoProject/store"
+)
+
+// pageMap serves the PNG of the given size for each URL it knows.
+type pageMap map[string]image.Point
+
+func (m pageMap) Page(ctx context.Context, url string) ([]byte, error) {
+	size, ok := m[url]
+	if !ok {
+		return nil, errors.New("no such page")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, size.X, size.Y))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func testExport() (Export, pageMap) {
+	pages := pageMap{"a/1": {10, 20}, "a/2": {10, 20}, "b/1": {30, 40}}
+	return Export{
+		Manga: store.Manga{Name: "Berserk", Author: "Kentaro Miura", Country: "JP", Genres: []string{"action", "horror"}, Published: 1989},
+		Chapters: []store.Chapter{
+			{Chapter: 1, Name: "The Black Swordsman", Img: []string{"a/1", "a/2"}},
+			{Chapter: 2, Img: []string{"b/1"}},
+		},
+	}, pages
+}
+
+func newTestExporter(pages PageSource) *Exporter {
+	e := NewExporter(pages)
+	e.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return e
+}
+
+func readZip(t *testing.T, data []byte) (*zip.Reader, []string) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	return zr, names
+}
+
+func readFile(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFilename(t *testing.T) {
+	x, _ := testExport()
+	if got := x.Filename("cbz"); got != "Berserk - 001-002.cbz" {
+		t.Errorf("Filename = %q", got)
+	}
+	x.Chapters = x.Chapters[1:]
+	if got := x.Filename("epub"); got != "Berserk - 002.epub" {
+		t.Errorf("single chapter Filename = %q", got)
+	}
+}
+
+func TestComicInfo(t *testing.T) {
+	x, _ := testExport()
+	info := x.ComicInfo()
+	if info.Series != "Berserk" || info.PageCount != 3 || info.Manga != "YesAndRightToLeft" || info.Genre != "action, horror" || info.Number != "" {
+		t.Errorf("ComicInfo = %+v", info)
+	}
+	x.Chapters = x.Chapters[:1]
+	if info := x.ComicInfo(); info.Number != "1" || info.Title != "The Black Swordsman" {
+		t.Errorf("single chapter ComicInfo = %+v, want its number and name", info)
+	}
+}
+
+func TestWriteCBZ(t *testing.T) {
+	x, pages := testExport()
+	var buf bytes.Buffer
+	if err := newTestExporter(pages).WriteCBZ(context.Background(), &buf, x); err != nil {
+		t.Fatal(err)
+	}
+	zr, names := readZip(t, buf.Bytes())
+	want := []string{"Chapter 0001/0001.png", "Chapter 0001/0002.png", "Chapter 0002/0001.png", "ComicInfo.xml"}
+	if !slices.Equal(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+	var info ComicInfo
+	if err := xml.Unmarshal([]byte(readFile(t, zr, "ComicInfo.xml")), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Series != "Berserk" || info.PageCount != 3 {
+		t.Errorf("ComicInfo.xml = %+v", info)
+	}
+
+	x.Chapters = x.Chapters[1:]
+	buf.Reset()
+	if err := newTestExporter(pages).WriteCBZ(context.Background(), &buf, x); err != nil {
+		t.Fatal(err)
+	}
+	if _, names := readZip(t, buf.Bytes()); !slices.Equal(names, []string{"0001.png", "ComicInfo.xml"}) {
+		t.Errorf("single chapter entries = %v, want no folders", names)
+	}
+}
+
+func TestWriteCBZMissingPage(t *testing.T) {
+	x, pages := testExport()
+	delete(pages, "a/2")
+	err := newTestExporter(pages).WriteCBZ(context.Background(), io.Discard, x)
+	if err == nil || !strings.Contains(err.Error(), "chapter 1 page 2") {
+		t.Errorf("error = %v, want one naming chapter 1 page 2", err)
+	}
+}
+
+func TestWriteEPUB(t *testing.T) {
+	x, pages := testExport()
+	var buf bytes.Buffer
+	if err := newTestExporter(pages).WriteEPUB(context.Background(), &buf, x); err != nil {
+		t.Fatal(err)
+	}
+	zr, names := readZip(t, buf.Bytes())
+
+	// EPUB readers find the mimetype by its fixed offset: first and stored.
+	if names[0] != "mimetype" || zr.File[0].Method != zip.Store || readFile(t, zr, "mimetype") != "application/epub+zip" {
+		t.Errorf("first entry = %s (method %d), want a stored mimetype", names[0], zr.File[0].Method)
+	}
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/images/c0002-0001.png", "OEBPS/pages/c0001-0002.xhtml"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("entries = %v, missing %s", names, name)
+		}
+	}
+
+	page := readFile(t, zr, "OEBPS/pages/c0002-0001.xhtml")
+	if !strings.Contains(page, `content="width=30, height=40"`) {
+		t.Errorf("page viewport is not the image size:\n%s", page)
+	}
+	opf := readFile(t, zr, "OEBPS/content.opf")
+	for _, want := range []string{
+		`page-progression-direction="rtl"`,
+		`<meta property="dcterms:modified">2024-05-01T12:00:00Z</meta>`,
+		`<dc:title>Berserk 1-2</dc:title>`,
+		x.identifier(),
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf is missing %s:\n%s", want, opf)
+		}
+	}
+	if nav := readFile(t, zr, "OEBPS/nav.xhtml"); !strings.Contains(nav, "Chapter 1: The Black Swordsman") {
+		t.Errorf("nav.xhtml has no entry for chapter 1:\n%s", nav)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	x, _ := testExport()
+	id := x.identifier()
+	if id != x.identifier() || !strings.HasPrefix(id, "urn:uuid:") || len(id) != len("urn:uuid:")+36 {
+		t.Errorf("identifier = %q, want a stable urn:uuid", id)
+	}
+	x.Chapters = x.Chapters[:1]
+	if x.identifier() == id {
+		t.Error("identifier does not depend on the chapters")
+	}
+}
@@ -0,0 +1,239 @@
+package comic
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/store"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvalidArchive  = errors.New("comic: invalid archive")
+	ErrNoPages         = errors.New("comic: archive has no images")
+	ErrTooManyPages    = errors.New("comic: archive has too many pages")
+	ErrNoChapterNumber = errors.New("comic: chapter number is missing")
+)
+
+// MaxPages bounds the pages imported from one archive.
+const MaxPages = 1000
+
+// Options controls how an archive becomes a chapter.
+type Options struct {
+	Manga string
+	// Chapter is the chapter number. Zero takes it from ComicInfo.xml and
+	// then from the archive name, as in "Chapter 12.cbz" or "c012.zip".
+	Chapter int
+	// Name is the chapter name. Empty takes the ComicInfo.xml title.
+	Name string
+	// ComicInfo, when set, is used instead of the archive's ComicInfo.xml.
+	ComicInfo *ComicInfo
+	// Replace replaces the pages and name of an existing chapter instead
+	// of failing with store.ErrConflict.
+	Replace bool
+}
+
+// Result reports one imported archive.
+type Result struct {
+	File         string        `json:"file"`
+	Chapter      store.Chapter `json:"chapter"`
+	Replaced     bool          `json:"replaced"`
+	Deduplicated int           `json:"deduplicated"`
+	// Skipped lists entries that are not images, such as text files.
+	Skipped []string `json:"skipped"`
+	Err     error    `json:"-"`
+}
+
+// Importer turns CBZ and ZIP archives into chapters, storing the pages
+// through an images.Uploader.
+type Importer struct {
+	uploader *images.Uploader
+	mangas   store.MangaStore
+	catalog  store.CatalogStore
+}
+
+func NewImporter(uploader *images.Uploader, mangas store.MangaStore, catalog store.CatalogStore) *Importer {
+	return &Importer{uploader: uploader, mangas: mangas, catalog: catalog}
+}
+
+// Import reads the archive named filename from r and creates its chapter.
+func (im *Importer) Import(ctx context.Context, r io.ReaderAt, size int64, filename string, opts Options) (Result, error) {
+	result := Result{File: filename, Skipped: []string{}}
+	if _, err := im.mangas.ByName(ctx, opts.Manga); err != nil {
+		return result, err
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return result, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, filename, err)
+	}
+
+	var pages []*zip.File
+	var infoFile *zip.File
+	for _, f := range zr.File {
+		name := f.Name
+		switch {
+		case f.FileInfo().IsDir():
+		case strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "."):
+			result.Skipped = append(result.Skipped, name)
+		case strings.EqualFold(path.Base(name), "ComicInfo.xml"):
+			infoFile = f
+		default:
+			pages = append(pages, f)
+		}
+	}
+	if len(pages) > MaxPages {
+		return result, ErrTooManyPages
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return NaturalLess(pages[i].Name, pages[j].Name)
+	})
+
+	info := opts.ComicInfo
+	if info == nil && infoFile != nil {
+		rc, err := infoFile.Open()
+		if err != nil {
+			return result, err
+		}
+		parsed, err := ParseComicInfo(rc)
+		rc.Close()
+		if err != nil {
+			return result, fmt.Errorf("%w: %s: ComicInfo.xml: %v", ErrInvalidArchive, filename, err)
+		}
+		info = &parsed
+	}
+	if info == nil {
+		info = &ComicInfo{}
+	}
+
+	number := opts.Chapter
+	if number == 0 {
+		number, err = chapterNumber(info.Number, filename)
+		if err != nil {
+			return result, err
+		}
+	}
+	name := opts.Name
+	if name == "" {
+		name = info.Title
+	}
+
+	// Pages are stored as they are read. A failed import may leave some
+	// behind, which a retry reuses since keys are content hashes.
+	urls := pq.StringArray{}
+	for _, f := range pages {
+		rc, err := f.Open()
+		if err != nil {
+			return result, err
+		}
+		img, err := im.uploader.Upload(ctx, rc)
+		rc.Close()
+		if errors.Is(err, images.ErrUnsupportedType) {
+			result.Skipped = append(result.Skipped, f.Name)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("comic: %s: %s: %w", filename, f.Name, err)
+		}
+		if img.Deduplicated {
+			result.Deduplicated++
+		}
+		urls = append(urls, img.URL)
+	}
+	if len(urls) == 0 {
+		return result, ErrNoPages
+	}
+
+	chapter := store.Chapter{Chapter: number, Name: name, Img: urls, AnimeName: opts.Manga, CreatedAt: info.Date()}
+	result.Chapter, err = im.catalog.CreateChapter(ctx, chapter)
+	if errors.Is(err, store.ErrConflict) && opts.Replace {
+		result.Replaced = true
+		result.Chapter, err = im.catalog.UpdateChapter(ctx, opts.Manga, number, chapter)
+	}
+	return result, err
+}
+
+// ImportFile imports the archive at path.
+func (im *Importer) ImportFile(ctx context.Context, path string, opts Options) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{File: path}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Result{File: path}, err
+	}
+	return im.Import(ctx, f, info.Size(), filepath.Base(path), opts)
+}
+
+// ImportDir imports every .cbz and .zip file in dir into one manga, in
+// natural order. Chapter numbers come from each archive, opts.Chapter and
+// opts.Name are ignored. A failed archive does not stop the others, its
+// error is in Result.Err.
+func (im *Importer) ImportDir(ctx context.Context, dir string, opts Options) ([]Result, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if !e.IsDir() && (ext == ".cbz" || ext == ".zip") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return NaturalLess(names[i], names[j])
+	})
+
+	opts.Chapter, opts.Name, opts.ComicInfo = 0, "", nil
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result, err := im.ImportFile(ctx, filepath.Join(dir, name), opts)
+		result.File, result.Err = name, err
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+var (
+	chapterInName = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:chapter|chap|ch|c)[ ._-]*0*(\d+)`)
+	lastNumber    = regexp.MustCompile(`(\d+)\D*$`)
+)
+
+// chapterNumber reads the chapter number from a ComicInfo Number, or else
+// from the archive name.
+func chapterNumber(number, filename string) (int, error) {
+	if number = strings.TrimSpace(number); number != "" {
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%w: ComicInfo.xml number %q is not a positive whole number", ErrNoChapterNumber, number)
+		}
+		return n, nil
+	}
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	m := chapterInName.FindStringSubmatch(base)
+	if m == nil {
+		m = lastNumber.FindStringSubmatch(base)
+	}
+	if m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: set it or name the archive like \"Chapter 12.cbz\"", ErrNoChapterNumber)
+}
@@ -0,0 +1,39 @@
+package comic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChapterNumber(t *testing.T) {
+	tests := []struct {
+		number, filename string
+		want             int
+	}{
+		{"12", "whatever.cbz", 12},
+		{" 7 ", "Chapter 3.cbz", 7},
+		{"", "Chapter 12.cbz", 12},
+		{"", "Berserk v01 ch005.cbz", 5},
+		{"", "Berserk_c042_[group].zip", 42},
+		{"", "Berserk - Chap.9 (2004).cbz", 9},
+		{"", "Berserk 013.cbz", 13},
+		{"", "013 - The Guardians of Desire.cbz", 13},
+	}
+	for _, tt := range tests {
+		got, err := chapterNumber(tt.number, tt.filename)
+		if err != nil || got != tt.want {
+			t.Errorf("chapterNumber(%q, %q) = %d, %v, want %d", tt.number, tt.filename, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct{ number, filename string }{
+		{"1.5", "Chapter 1.cbz"},
+		{"0", "Chapter 1.cbz"},
+		{"", "Berserk.cbz"},
+		{"", "Chapter 0.cbz"},
+	} {
+		if _, err := chapterNumber(tt.number, tt.filename); !errors.Is(err, ErrNoChapterNumber) {
+			t.Errorf("chapterNumber(%q, %q) error = %v, want ErrNoChapterNumber", tt.number, tt.filename, err)
+		}
+	}
+}
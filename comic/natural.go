@@ -0,0 +1,49 @@
+package comic
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NaturalLess orders names the way people number pages: runs of digits
+// compare by value, so "page2" sorts before "page10", and letters compare
+// case-insensitively. Names equal under those rules fall back to byte
+// order so the result is total.
+func NaturalLess(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isDigit(ra[i]) && isDigit(rb[j]) {
+			si, sj := i, j
+			for i < len(ra) && isDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && isDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return ca < cb
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
@@ -0,0 +1,39 @@
+package comic
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"page10.png", "Page2.png", "page1.png", "page01b.png", "cover.jpg", "page001.png", "page2a.png", "p.png"}
+	slices.SortFunc(names, func(a, b string) int {
+		if NaturalLess(a, b) {
+			return -1
+		}
+		if NaturalLess(b, a) {
+			return 1
+		}
+		return 0
+	})
+	want := []string{"cover.jpg", "p.png", "page001.png", "page1.png", "page01b.png", "Page2.png", "page2a.png", "page10.png"}
+	if !slices.Equal(names, want) {
+		t.Errorf("sorted = %q, want %q", names, want)
+	}
+}
+
+func TestNaturalLessIsStrict(t *testing.T) {
+	pairs := [][2]string{{"a", "a"}, {"a1", "a01"}, {"A", "a"}, {"", "x"}, {"x9", "x10"}}
+	for _, p := range pairs {
+		a, b := p[0], p[1]
+		if NaturalLess(a, b) && NaturalLess(b, a) {
+			t.Errorf("%q and %q are both less than each other", a, b)
+		}
+		if a != b && !NaturalLess(a, b) && !NaturalLess(b, a) {
+			t.Errorf("distinct %q and %q compare equal", a, b)
+		}
+	}
+	if NaturalLess("a", "a") {
+		t.Error(`NaturalLess("a", "a") = true`)
+	}
+}
@@ -0,0 +1,58 @@
+package comic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/chimas/GoProject/images"
+)
+
+// maxPageSize bounds a page fetched for export.
+const maxPageSize = 50 << 20
+
+// PageSource reads the page images of chapters by URL.
+type PageSource interface {
+	Page(ctx context.Context, url string) ([]byte, error)
+}
+
+// Pages reads uploaded pages from image storage and fetches the rest, which
+// older chapters link to on other hosts, over HTTP.
+type Pages struct {
+	uploader *images.Uploader
+	client   *http.Client
+}
+
+func NewPages(uploader *images.Uploader, client *http.Client) *Pages {
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+	return &Pages{uploader: uploader, client: client}
+}
+
+func (p *Pages) Page(ctx context.Context, url string) ([]byte, error) {
+	if key, ok := p.uploader.KeyOf(url); ok {
+		rc, _, err := p.uploader.Open(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxPageSize))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("comic: GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+}
@@ -0,0 +1,409 @@
+// Package config holds the service configuration. Load fills a Config from
+// defaults, a YAML or TOML file, the environment and command-line flags,
+// each overriding the one before, and Validate checks it before anything
+// is started.
+//
+// Every field has a key in the file (the yaml tag, dotted by section: db.url),
+// an environment variable (the env tag, prefixed by the env tag of the
+// section for shared types such as TLS) and a flag named like the key
+// (-db.url). Secrets are masked by Print.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Log     Log     `yaml:"log"`
+	Tracing Tracing `yaml:"tracing"`
+	DB      DB      `yaml:"db"`
+	Redis   Redis   `yaml:"redis"`
+	Cache   Cache   `yaml:"cache"`
+	Search  Search  `yaml:"search"`
+	CORS    CORS    `yaml:"cors"`
+	Auth    Auth    `yaml:"auth"`
+	Storage Storage `yaml:"storage"`
+	Catalog Catalog `yaml:"catalog"`
+}
+
+type Server struct {
+	Port int `yaml:"port" env:"PORT"`
+	// ShutdownGrace is how long in-flight requests get to finish after
+	// SIGINT or SIGTERM. The timeouts go to http.Server, downloads and
+	// uploads lift the read and write timeouts for themselves.
+	ShutdownGrace     time.Duration `yaml:"shutdownGrace" env:"SHUTDOWN_GRACE"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT"`
+	// MaxBodyBytes limits JSON request bodies. Image and archive uploads
+	// have their own limits under Storage.
+	MaxBodyBytes int64 `yaml:"maxBodyBytes" env:"MAX_BODY_BYTES"`
+	// SeedFile loads mangas into the in-memory store when DB.URL is empty.
+	SeedFile string `yaml:"seedFile" env:"SEED_FILE"`
+}
+
+type Log struct {
+	// Format is "json" or "text".
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+}
+
+type Tracing struct {
+	// Exporter is "otlp", configured by the standard OTEL_EXPORTER_OTLP_*
+	// variables, "file", which appends spans as JSON to File, or empty to
+	// disable tracing.
+	Exporter string `yaml:"exporter" env:"TRACE_EXPORTER"`
+	File     string `yaml:"file" env:"TRACE_FILE"`
+}
+
+type DB struct {
+	// URL is a Postgres connection string. Empty serves from memory.
+	URL            string `yaml:"url" env:"DB_URL" secret:"url"`
+	MigrateOnStart bool   `yaml:"migrateOnStart" env:"MIGRATE_ON_START"`
+	// The pool settings go to sql.DB. Zero keeps its own defaults:
+	// unlimited open connections, two idle ones, none ever closed for age.
+	MaxOpenConns    int           `yaml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" env:"DB_CONN_MAX_IDLE_TIME"`
+	TLS             TLS           `yaml:"tls" env:"DB_TLS_"`
+	// Connect is how long startup waits for Postgres. Once running, the
+	// pool reconnects by itself.
+	Connect Retry `yaml:"connect" env:"DB_CONNECT_"`
+	// ReadyWhileDown makes /readyz report a Postgres outage as "degraded"
+	// instead of failing, so load balancers keep instances that answer
+	// reads from stale cache entries. It needs cache.ttl.stale and a cache.
+	// Off, an instance without Postgres is taken out of rotation.
+	ReadyWhileDown bool `yaml:"readyWhileDown" env:"DB_READY_WHILE_DOWN"`
+}
+
+type Redis struct {
+	URL string `yaml:"url" env:"REDIS_URL" secret:"url"`
+	TLS TLS    `yaml:"tls" env:"REDIS_TLS_"`
+	// Connect is how long startup waits for Redis before starting without
+	// it. The client reconnects by itself.
+	Connect Retry `yaml:"connect" env:"REDIS_CONNECT_"`
+	// RetryAfter is how long the cache stays in process after a Redis
+	// error before trying Redis again.
+	RetryAfter time.Duration `yaml:"retryAfter" env:"REDIS_RETRY_AFTER"`
+}
+
+// TLS secures the connection to Postgres or Redis.
+type TLS struct {
+	// Mode is disable, require (encrypted but not verified), verify-ca (the
+	// certificate chains to a trusted CA) or verify-full (and names the
+	// host). Empty keeps what the URL says: its sslmode for Postgres,
+	// verify-full for rediss:// and no TLS for redis://.
+	Mode string `yaml:"mode" env:"MODE"`
+	// CAFile is a PEM bundle trusted instead of the system roots.
+	CAFile string `yaml:"caFile" env:"CA_FILE"`
+	// CertFile and KeyFile are a client certificate, for servers that
+	// authenticate clients with one.
+	CertFile string `yaml:"certFile" env:"CERT_FILE"`
+	KeyFile  string `yaml:"keyFile" env:"KEY_FILE"`
+	// ServerName is the name verify-full checks instead of the URL's host,
+	// for servers reached by an address their certificate does not name.
+	// Postgres needs db.url as a URL to use it.
+	ServerName string `yaml:"serverName" env:"SERVER_NAME"`
+}
+
+// Retry is how a connection is attempted at startup: up to Attempts
+// times, waiting Backoff after the first failure and doubling that up to
+// MaxBackoff.
+type Retry struct {
+	Attempts   int           `yaml:"attempts" env:"ATTEMPTS"`
+	Backoff    time.Duration `yaml:"backoff" env:"BACKOFF"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" env:"MAX_BACKOFF"`
+	// Timeout bounds every new connection, at startup and later, so a
+	// server dropping packets fails fast. Postgres rounds it up to whole
+	// seconds and a connect_timeout in db.url wins. Zero leaves it to the
+	// driver.
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+}
+
+type Cache struct {
+	// Backend is "redis", "lru" or "none". Empty picks redis when Redis.URL
+	// is set and lru otherwise.
+	Backend string `yaml:"backend" env:"CACHE_BACKEND"`
+	// LRUSize is the number of entries the lru backend keeps.
+	LRUSize int      `yaml:"lruSize" env:"CACHE_LRU_SIZE"`
+	TTL     CacheTTL `yaml:"ttl"`
+}
+
+// CacheTTL is how long each kind of manga response stays cached.
+type CacheTTL struct {
+	Mangas  time.Duration `yaml:"mangas" env:"CACHE_TTL_MANGAS"`
+	Manga   time.Duration `yaml:"manga" env:"CACHE_TTL_MANGA"`
+	Popular time.Duration `yaml:"popular" env:"CACHE_TTL_POPULAR"`
+	Filter  time.Duration `yaml:"filter" env:"CACHE_TTL_FILTER"`
+	Chapter time.Duration `yaml:"chapter" env:"CACHE_TTL_CHAPTER"`
+	Search  time.Duration `yaml:"search" env:"CACHE_TTL_SEARCH"`
+	// Stale is how much longer responses are kept past their TTL, to be
+	// served while Postgres is unavailable. Zero keeps nothing stale.
+	Stale time.Duration `yaml:"stale" env:"CACHE_TTL_STALE"`
+}
+
+type Search struct {
+	// Backend is "postgres" or "memory". Empty picks postgres when DB.URL
+	// is set and memory otherwise.
+	Backend string `yaml:"backend" env:"SEARCH_BACKEND"`
+}
+
+type CORS struct {
+	// AllowedOrigins are full origins such as https://example.com. In the
+	// environment and on the command line they are separated by commas.
+	AllowedOrigins []string `yaml:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS"`
+}
+
+type Auth struct {
+	// HS256Secret and JWKSFile enable HS256 and RS256 bearer tokens. Without
+	// either the /user and /admin endpoints answer 503, the rest still
+	// work.
+	HS256Secret string `yaml:"hs256Secret" env:"AUTH_HS256_SECRET" secret:"true"`
+	JWKSFile    string `yaml:"jwksFile" env:"AUTH_JWKS_FILE"`
+	Issuer      string `yaml:"issuer" env:"AUTH_ISSUER"`
+	Audience    string `yaml:"audience" env:"AUTH_AUDIENCE"`
+}
+
+type Storage struct {
+	// Backend is "local" or "s3". Local images go to Dir, S3 ones to
+	// S3.Bucket. Image URLs are PublicURL followed by the key, "/images/"
+	// serves them through the API.
+	Backend   string `yaml:"backend" env:"IMAGE_BACKEND"`
+	Dir       string `yaml:"dir" env:"IMAGE_DIR"`
+	PublicURL string `yaml:"publicURL" env:"IMAGE_PUBLIC_URL"`
+	// MaxBytes limits a single image.
+	MaxBytes int64 `yaml:"maxBytes" env:"IMAGE_MAX_BYTES"`
+	// CacheDir keeps resized variants. ProxyURL is where the API's /images/
+	// endpoint is reachable, thumbnail URLs point there.
+	CacheDir string `yaml:"cacheDir" env:"IMAGE_CACHE_DIR"`
+	ProxyURL string `yaml:"proxyURL" env:"IMAGE_PROXY_URL"`
+	// ArchiveMaxBytes limits a chapter archive uploaded for import.
+	ArchiveMaxBytes int64 `yaml:"archiveMaxBytes" env:"ARCHIVE_MAX_BYTES"`
+	S3              S3    `yaml:"s3"`
+}
+
+// S3 is an S3-compatible bucket. The keys need s3:ListBucket on it besides
+// reading, writing and deleting objects, see images.S3Config.
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	Region    string `yaml:"region" env:"S3_REGION"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	AccessKey string `yaml:"accessKey" env:"S3_ACCESS_KEY" secret:"true"`
+	SecretKey string `yaml:"secretKey" env:"S3_SECRET_KEY" secret:"true"`
+}
+
+// Catalog is the vocabulary the admin API accepts for Manga.Status and
+// Manga.Genres. In the environment and on the command line the lists are
+// separated by commas. Removing a value does not touch the mangas that
+// already use it, but they cannot be saved again until it is changed.
+type Catalog struct {
+	Statuses []string `yaml:"statuses" env:"CATALOG_STATUSES"`
+	Genres   []string `yaml:"genres" env:"CATALOG_GENRES"`
+}
+
+var defaultRetry = Retry{Attempts: 5, Backoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second, Timeout: 5 * time.Second}
+
+// Default returns the configuration used for anything no source sets.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:              4000,
+			ShutdownGrace:     15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			MaxBodyBytes:      1 << 20,
+		},
+		Log: Log{Format: "json", Level: "info"},
+		DB: DB{
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			Connect:         defaultRetry,
+		},
+		Redis:   Redis{Connect: defaultRetry, RetryAfter: 5 * time.Second},
+		Tracing: Tracing{File: "data/traces.jsonl"},
+		Cache: Cache{
+			LRUSize: 4096,
+			TTL: CacheTTL{
+				Mangas:  time.Minute,
+				Manga:   time.Minute,
+				Popular: 5 * time.Minute,
+				Filter:  30 * time.Second,
+				Chapter: 10 * time.Minute,
+				Search:  30 * time.Second,
+				Stale:   10 * time.Minute,
+			},
+		},
+		CORS: CORS{AllowedOrigins: []string{
+			"http://localhost:4000",
+			"http://localhost:3000",
+			"https://golang-on-koyeb-mankago.koyeb.app",
+			"https://manka-next.vercel.app",
+		}},
+		Storage: Storage{
+			Backend:         "local",
+			Dir:             "data/images",
+			PublicURL:       "/images/",
+			MaxBytes:        10 << 20,
+			CacheDir:        "data/image-cache",
+			ProxyURL:        "/images/",
+			ArchiveMaxBytes: 500 << 20,
+		},
+		Catalog: Catalog{
+			Statuses: []string{"announced", "ongoing", "finished", "frozen", "cancelled"},
+			Genres: []string{
+				"action", "adventure", "comedy", "drama", "fantasy", "harem", "historical", "horror",
+				"isekai", "josei", "martial arts", "mecha", "military", "music", "mystery", "psychological",
+				"romance", "school", "sci-fi", "seinen", "shoujo", "shounen", "slice of life", "sports",
+				"supernatural", "thriller",
+			},
+		},
+	}
+}
+
+// Validate reports every invalid setting at once. It checks values, not
+// what a particular command needs: migrate still refuses to run without a
+// database.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), key, "must be one of %q, got %q", allowed, value)
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 1<<16, "server.port", "must be between 1 and 65535")
+	for key, d := range map[string]time.Duration{
+		"server.shutdownGrace":     c.Server.ShutdownGrace,
+		"server.readHeaderTimeout": c.Server.ReadHeaderTimeout,
+		"server.readTimeout":       c.Server.ReadTimeout,
+		"server.writeTimeout":      c.Server.WriteTimeout,
+		"server.idleTimeout":       c.Server.IdleTimeout,
+		"cache.ttl.mangas":         c.Cache.TTL.Mangas,
+		"cache.ttl.manga":          c.Cache.TTL.Manga,
+		"cache.ttl.popular":        c.Cache.TTL.Popular,
+		"cache.ttl.filter":         c.Cache.TTL.Filter,
+		"cache.ttl.chapter":        c.Cache.TTL.Chapter,
+		"cache.ttl.search":         c.Cache.TTL.Search,
+	} {
+		check(d > 0, key, "must be positive")
+	}
+	check(c.Server.MaxBodyBytes > 0, "server.maxBodyBytes", "must be positive")
+
+	oneOf("log.format", c.Log.Format, "json", "text")
+	oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+	oneOf("tracing.exporter", c.Tracing.Exporter, "", "otlp", "file")
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file", "is required with the file exporter")
+
+	check(c.DB.URL == "" || validURL(c.DB.URL, "postgres", "postgresql") || strings.Contains(c.DB.URL, "="),
+		"db.url", "must be a postgres:// URL or key=value connection string")
+	check(c.DB.MaxOpenConns >= 0, "db.maxOpenConns", "must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.maxIdleConns", "must not be negative")
+	check(c.DB.ConnMaxLifetime >= 0, "db.connMaxLifetime", "must not be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.connMaxIdleTime", "must not be negative")
+	check(c.DB.MaxIdleConns <= c.DB.MaxOpenConns || c.DB.MaxOpenConns == 0, "db.maxIdleConns", "must not exceed db.maxOpenConns")
+	check(c.Redis.RetryAfter > 0, "redis.retryAfter", "must be positive")
+	check(c.Cache.TTL.Stale >= 0, "cache.ttl.stale", "must not be negative")
+	check(!c.DB.ReadyWhileDown || c.Cache.TTL.Stale > 0 && c.Cache.Backend != "none", "db.readyWhileDown", "needs cache.ttl.stale and a cache backend other than none")
+	check(c.Redis.URL == "" || validURL(c.Redis.URL, "redis", "rediss", "unix"), "redis.url", "must be a redis://, rediss:// or unix:// URL")
+	errs = append(errs, c.DB.TLS.validate("db.tls")...)
+	errs = append(errs, c.Redis.TLS.validate("redis.tls")...)
+	check(c.DB.TLS.ServerName == "" || validURL(c.DB.URL, "postgres", "postgresql"), "db.tls.serverName", "needs db.url as a postgres:// URL")
+	check(c.Redis.TLS.Mode != "disable" || !strings.HasPrefix(c.Redis.URL, "rediss:"), "redis.tls.mode", "cannot disable TLS for a rediss:// URL")
+	check(c.Redis.TLS.Mode != "" || c.Redis.TLS.CAFile == "" && c.Redis.TLS.CertFile == "" || strings.HasPrefix(c.Redis.URL, "rediss:"), "redis.tls", "certificates are set but the URL is redis://, use rediss:// or set mode")
+	errs = append(errs, c.DB.Connect.validate("db.connect")...)
+	errs = append(errs, c.Redis.Connect.validate("redis.connect")...)
+
+	oneOf("cache.backend", c.Cache.Backend, "", "redis", "lru", "none")
+	check(c.Cache.Backend != "redis" || c.Redis.URL != "", "redis.url", "is required with the redis cache backend")
+	check(c.Cache.LRUSize > 0, "cache.lruSize", "must be positive")
+	oneOf("search.backend", c.Search.Backend, "", "postgres", "memory")
+	check(c.Search.Backend != "postgres" || c.DB.URL != "", "db.url", "is required with the postgres search backend")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(validOrigin(origin), "cors.allowedOrigins", "%q is not an http(s) origin without path, query or fragment", origin)
+	}
+
+	oneOf("storage.backend", c.Storage.Backend, "local", "s3")
+	if c.Storage.Backend == "s3" {
+		check(c.Storage.S3.Endpoint != "", "storage.s3.endpoint", "is required with the s3 backend")
+		check(c.Storage.S3.Bucket != "", "storage.s3.bucket", "is required with the s3 backend")
+	} else {
+		check(c.Storage.Dir != "", "storage.dir", "is required with the local backend")
+	}
+	check(c.Storage.MaxBytes > 0, "storage.maxBytes", "must be positive")
+	check(c.Storage.ArchiveMaxBytes > 0, "storage.archiveMaxBytes", "must be positive")
+	check(c.Storage.CacheDir != "", "storage.cacheDir", "is required")
+
+	for key, values := range map[string][]string{"catalog.statuses": c.Catalog.Statuses, "catalog.genres": c.Catalog.Genres} {
+		check(len(values) > 0, key, "must not be empty")
+		for i, v := range values {
+			check(strings.TrimSpace(v) != "", key, "has a blank entry")
+			check(!slices.Contains(values[:i], v), key, "lists %q twice", v)
+		}
+	}
+
+	slices.SortFunc(errs, func(a, b error) int {
+		if a.Error() < b.Error() {
+			return -1
+		}
+		return 1
+	})
+	return errors.Join(errs...)
+}
+
+func (t TLS) validate(key string) []error {
+	var errs []error
+	if !slices.Contains([]string{"", "disable", "require", "verify-ca", "verify-full"}, t.Mode) {
+		errs = append(errs, fmt.Errorf("%s.mode: must be disable, require, verify-ca or verify-full, got %q", key, t.Mode))
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("%s: certFile and keyFile go together", key))
+	}
+	if t.Mode == "disable" && (t.CAFile != "" || t.CertFile != "") {
+		errs = append(errs, fmt.Errorf("%s: certificates are set but mode is disable", key))
+	}
+	return errs
+}
+
+func (r Retry) validate(key string) []error {
+	var errs []error
+	if r.Attempts < 1 {
+		errs = append(errs, fmt.Errorf("%s.attempts: must be at least 1", key))
+	}
+	if r.Backoff <= 0 || r.MaxBackoff < r.Backoff {
+		errs = append(errs, fmt.Errorf("%s.backoff: must be positive and at most maxBackoff", key))
+	}
+	if r.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout: must not be negative", key))
+	}
+	return errs
+}
+
+// validOrigin reports whether raw is a bare origin. The CORS middleware
+// compares origins exactly, so https://example.com/ would never match.
+func validOrigin(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && validURL(raw, "http", "https") && u.User == nil &&
+		u.Path == "" && u.RawQuery == "" && !u.ForceQuery && u.Fragment == ""
+}
+
+func validURL(raw string, schemes ...string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && slices.Contains(schemes, u.Scheme) && (u.Host != "" || u.Scheme == "unix")
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes a configuration file named name in a temporary
+// directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 5000
+  shutdownGrace: 20s
+log:
+  level: debug
+  format: text
+cache:
+  lruSize: 10
+catalog:
+  statuses: [ongoing, finished]
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("CACHE_LRU_SIZE", "20")
+	// Empty variables do not wipe out the file.
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("CATALOG_GENRES", "action,slice of life")
+
+	cfg, err := Load("test", []string{"-cache.lruSize", "30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := Default()
+	checks := []struct {
+		key       string
+		got, want any
+	}{
+		{"server.port", cfg.Server.Port, 5000},
+		{"server.shutdownGrace", cfg.Server.ShutdownGrace, 20 * time.Second},
+		{"server.readTimeout", cfg.Server.ReadTimeout, def.Server.ReadTimeout},
+		{"log.level", cfg.Log.Level, "warn"},
+		{"log.format", cfg.Log.Format, "text"},
+		{"cache.lruSize", cfg.Cache.LRUSize, 30},
+		{"cors.allowedOrigins", cfg.CORS.AllowedOrigins, []string{"https://a.example", "https://b.example"}},
+		{"catalog.statuses", cfg.Catalog.Statuses, []string{"ongoing", "finished"}},
+		{"catalog.genres", cfg.Catalog.Genres, []string{"action", "slice of life"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.key, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadFlagOverridesConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "ignored.yaml", "server:\n  port: 1\n"))
+	path := writeFile(t, "config.toml", "[server]\nport = 6000\n\n[db.tls]\nmode = \"verify-full\"\n")
+
+	cfg, err := Load("test", []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 6000 || cfg.DB.TLS.Mode != "verify-full" {
+		t.Errorf("port = %d, db.tls.mode = %q, want the TOML file", cfg.Server.Port, cfg.DB.TLS.Mode)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		usage bool
+	}{
+		{name: "unknown flag", args: []string{"-nope", "1"}, usage: true},
+		{name: "argument", args: []string{"serve"}, usage: true},
+		{name: "bad flag value", args: []string{"-server.port", "many"}, usage: true},
+		{name: "bad variable", env: map[string]string{"HTTP_READ_TIMEOUT": "5"}},
+		{name: "unknown key", file: "config.yaml:server:\n  prot: 1\n"},
+		{name: "extension", file: "config.json:{}"},
+		{name: "missing file", file: "-"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			switch {
+			case tc.file == "-":
+				t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+			case tc.file != "":
+				name, content, _ := strings.Cut(tc.file, ":")
+				t.Setenv("CONFIG_FILE", writeFile(t, name, content))
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load("test", tc.args)
+			if err == nil {
+				t.Fatal("Load succeeded")
+			}
+			if got := errors.Is(err, ErrUsage); got != tc.usage {
+				t.Errorf("errors.Is(%v, ErrUsage) = %v, want %v", err, got, tc.usage)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("default configuration is invalid: %v", err)
+	}
+	cfg := Default()
+	cfg.DB.URL, cfg.DB.TLS.ServerName = "postgres://10.0.0.5/manga", "db.internal"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("server name with a URL: %v", err)
+	}
+	if Default().DB.ReadyWhileDown {
+		t.Error("Postgres is optional for readiness by default")
+	}
+
+	tests := []struct {
+		key    string
+		modify func(*Config)
+	}{
+		{"server.port", func(c *Config) { c.Server.Port = 70000 }},
+		{"server.readTimeout", func(c *Config) { c.Server.ReadTimeout = 0 }},
+		{"log.level", func(c *Config) { c.Log.Level = "verbose" }},
+		{"tracing.file", func(c *Config) { c.Tracing.Exporter, c.Tracing.File = "file", "" }},
+		{"db.url", func(c *Config) { c.DB.URL = "mysql://localhost/manga" }},
+		{"db.maxIdleConns", func(c *Config) { c.DB.MaxOpenConns, c.DB.MaxIdleConns = 5, 10 }},
+		{"db.tls.mode", func(c *Config) { c.DB.TLS.Mode = "prefer" }},
+		{"db.tls", func(c *Config) { c.DB.TLS.CertFile = "client.pem" }},
+		{"db.tls.serverName", func(c *Config) { c.DB.URL, c.DB.TLS.ServerName = "host=10.0.0.5", "db.internal" }},
+		{"db.connect.attempts", func(c *Config) { c.DB.Connect.Attempts = 0 }},
+		{"redis.connect.backoff", func(c *Config) { c.Redis.Connect.MaxBackoff = time.Millisecond }},
+		{"redis.url", func(c *Config) { c.Cache.Backend = "redis" }},
+		{"redis.tls.mode", func(c *Config) { c.Redis.URL, c.Redis.TLS.Mode = "rediss://cache:6380", "disable" }},
+		{"cache.ttl.stale", func(c *Config) { c.Cache.TTL.Stale = -time.Second }},
+		{"db.readyWhileDown", func(c *Config) { c.DB.ReadyWhileDown, c.Cache.Backend = true, "none" }},
+		{"db.readyWhileDown", func(c *Config) { c.DB.ReadyWhileDown, c.Cache.TTL.Stale = true, 0 }},
+		{"search.backend", func(c *Config) { c.Search.Backend = "elastic" }},
+		{"cors.allowedOrigins", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/"} }},
+		{"storage.s3.bucket", func(c *Config) { c.Storage.Backend, c.Storage.S3.Endpoint = "s3", "https://s3.example" }},
+		{"catalog.statuses", func(c *Config) { c.Catalog.Statuses = nil }},
+		{"catalog.genres", func(c *Config) { c.Catalog.Genres = []string{"action", " "} }},
+		{"catalog.genres", func(c *Config) { c.Catalog.Genres = []string{"action", "drama", "action"} }},
+	}
+	for _, tc := range tests {
+		cfg := Default()
+		tc.modify(&cfg)
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.key) {
+			t.Errorf("%s: Validate() = %v, want an error naming it", tc.key, err)
+		}
+	}
+
+	// Every error is reported, not only the first.
+	cfg = Default()
+	cfg.Server.Port = 0
+	cfg.Log.Format = "xml"
+	if err := cfg.Validate(); err == nil || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("Validate() = %v, want two errors", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	cfg := Default()
+	cfg.DB.URL = "postgres://manga:hunter2@db:5432/manga"
+	cfg.Redis.URL = "redis://cache:6379"
+	cfg.Auth.HS256Secret = "s3cret"
+	cfg.Storage.S3.SecretKey = "aws-secret"
+
+	var buf bytes.Buffer
+	if err := Print(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "s3cret", "aws-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printed configuration shows %q", secret)
+		}
+	}
+	for _, want := range []string{"manga:REDACTED@db:5432", "redis://cache:6379", "hs256Secret: REDACTED"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printed configuration lacks %q:\n%s", want, out)
+		}
+	}
+	if cfg.DB.URL != "postgres://manga:hunter2@db:5432/manga" {
+		t.Error("Print modified its argument")
+	}
+
+	// The output loads back into the same configuration, secrets aside.
+	t.Setenv("CONFIG_FILE", writeFile(t, "printed.yaml", out))
+	loaded, err := Load("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.DB.URL, loaded.Auth.HS256Secret, loaded.Storage.S3.SecretKey = cfg.DB.URL, cfg.Auth.HS256Secret, cfg.Storage.S3.SecretKey
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("reloaded configuration differs:\n got %+v\nwant %+v", loaded, cfg)
+	}
+}
+
+func TestMaskURL(t *testing.T) {
+	tests := map[string]string{
+		"postgres://manga@db/manga":                   "postgres://manga@db/manga",
+		"host=db password=hunter2":                    "REDACTED",
+		"rediss://:pw@cache:6380/0":                   "rediss://:REDACTED@cache:6380/0",
+		"postgres://u:p@db/manga?sslmode=verify-full": "postgres://u:REDACTED@db/manga?sslmode=verify-full",
+	}
+	for in, want := range tests {
+		if got := maskURL(in); got != want {
+			t.Errorf("maskURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUsage wraps errors in command-line flags, as opposed to errors in the
+// configuration they point at.
+var ErrUsage = errors.New("config: invalid flags")
+
+// Load reads the configuration once for the whole process: Default, then
+// the file named by -config or CONFIG_FILE (.yaml, .yml or .toml), then
+// environment variables including those in a .env file, then the flags in
+// args. Empty environment variables are ignored, so a blank one left by a
+// deployment template cannot wipe out the file. The result is not
+// validated, call Validate.
+func Load(name string, args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fs.String("config", "", "YAML or TOML configuration file, default $CONFIG_FILE")
+	type setting struct{ key, value string }
+	var flags []setting
+	walk(&cfg, func(key, env string, _ reflect.StructField, _ reflect.Value) {
+		usage := "overrides " + key
+		if env != "" {
+			usage += " and $" + env
+		}
+		fs.Func(key, usage, func(value string) error {
+			flags = append(flags, setting{key, value})
+			return nil
+		})
+	})
+	if err := fs.Parse(args); err != nil {
+		return cfg, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("%w: unexpected argument %q", ErrUsage, fs.Arg(0))
+	}
+
+	// .env only fills variables the environment does not already have.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("config: .env: %w", err)
+	}
+	if *file == "" {
+		*file = os.Getenv("CONFIG_FILE")
+	}
+	if *file != "" {
+		if err := loadFile(&cfg, *file); err != nil {
+			return cfg, err
+		}
+	}
+
+	var errs []error
+	walk(&cfg, func(key, env string, _ reflect.StructField, v reflect.Value) {
+		if value := os.Getenv(env); env != "" && value != "" {
+			if err := set(v, value); err != nil {
+				errs = append(errs, fmt.Errorf("config: $%s: %w", env, err))
+			}
+		}
+	})
+	for _, s := range flags {
+		walk(&cfg, func(key, _ string, _ reflect.StructField, v reflect.Value) {
+			if key == s.key {
+				if err := set(v, s.value); err != nil {
+					errs = append(errs, fmt.Errorf("%w: -%s: %w", ErrUsage, key, err))
+				}
+			}
+		})
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// loadFile decodes a YAML or TOML file over cfg. Unknown keys are errors, a
+// misspelt setting would otherwise be silently ignored.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		// TOML goes through YAML so one set of tags and one duration
+		// parser serve both formats.
+		var tree map[string]any
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(tree); err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config: %s: want a .yaml, .yml or .toml file", path)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// walk calls fn for every setting of cfg with its dotted key and its
+// environment variable, if it has one.
+func walk(cfg *Config, fn func(key, env string, field reflect.StructField, v reflect.Value)) {
+	var visit func(prefix, envPrefix string, v reflect.Value)
+	visit = func(prefix, envPrefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+			env := field.Tag.Get("env")
+			if env != "" {
+				env = envPrefix + env
+			}
+			if field.Type.Kind() == reflect.Struct {
+				visit(key+".", env, v.Field(i))
+				continue
+			}
+			fn(key, env, field, v.Field(i))
+		}
+	}
+	visit("", "", reflect.ValueOf(cfg).Elem())
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses s into v. Lists are separated by commas.
+func set(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const mask = "REDACTED"
+
+// Print writes cfg as a YAML file Load would accept, with secrets masked:
+// fields tagged secret:"true" entirely, secret:"url" only the password.
+func Print(w io.Writer, cfg Config) error {
+	walk(&cfg, func(_, _ string, field reflect.StructField, v reflect.Value) {
+		secret := field.Tag.Get("secret")
+		if secret == "" || v.String() == "" {
+			return
+		}
+		switch secret {
+		case "true":
+			v.SetString(mask)
+		case "url":
+			v.SetString(maskURL(v.String()))
+		}
+	})
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// maskURL hides the password of a URL. Strings that do not parse, such as
+// key=value connection strings, may hold one anywhere and are masked whole.
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return mask
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), mask)
+	}
+	return u.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/chimas/GoProject/config"
+)
+
+const configUsage = `usage: config print [-config file] [-<key> value ...]`
+
+// loadConfig loads and validates the configuration for a command.
+func loadConfig(name string, args []string) (config.Config, error) {
+	cfg, err := config.Load(name, args)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// configExit is the exit code for an error from loadConfig.
+func configExit(err error) int {
+	if errors.Is(err, config.ErrUsage) {
+		return exitUsage
+	}
+	return exitConfig
+}
+
+// runConfig implements the `config` subcommand and returns the exit code.
+// `config print` shows the configuration the server would start with,
+// secrets masked, followed by any validation errors.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return exitUsage
+	}
+	cfg, err := config.Load("config print", args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return configExit(err)
+	}
+	if err := config.Print(os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfig
+	}
+	return exitOK
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// QueryHook is called before a statement runs on a pooled connection, the
+// function it returns once the statement finishes: for queries, when their
+// rows are closed, so reading the result counts too. Metrics and tracing
+// observe queries this way without touching the stores.
+type QueryHook func(ctx context.Context, query string) func(err error)
+
+// WithHooks wraps c so that hooks see every query and exec.
+func WithHooks(c driver.Connector, hooks ...QueryHook) driver.Connector {
+	if len(hooks) == 0 {
+		return c
+	}
+	return &hookedConnector{Connector: c, hooks: hooks}
+}
+
+type hookedConnector struct {
+	driver.Connector
+	hooks []QueryHook
+}
+
+func (c *hookedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &hookedConn{Conn: conn, hooks: c.hooks}, nil
+}
+
+// hookedConn forwards the optional driver interfaces lib/pq implements, so
+// database/sql keeps its fast paths.
+type hookedConn struct {
+	driver.Conn
+	hooks []QueryHook
+}
+
+func (c *hookedConn) before(ctx context.Context, query string) func(error) {
+	done := make([]func(error), len(c.hooks))
+	for i, hook := range c.hooks {
+		done[i] = hook(ctx, query)
+	}
+	return func(err error) {
+		for i := len(done) - 1; i >= 0; i-- {
+			done[i](err)
+		}
+	}
+}
+
+func (c *hookedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	after := c.before(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		after(err)
+		return nil, err
+	}
+	return &hookedRows{Rows: rows, after: after}, nil
+}
+
+func (c *hookedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	after := c.before(ctx, query)
+	res, err := e.ExecContext(ctx, query, args)
+	after(err)
+	return res, err
+}
+
+func (c *hookedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *hookedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *hookedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *hookedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *hookedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// hookedRows reports the query finished when the rows are closed, with the
+// first error reading them. It forwards the optional interfaces lib/pq rows
+// implement so Rows.ColumnTypes and NextResultSet keep working.
+type hookedRows struct {
+	driver.Rows
+	after func(error)
+	err   error
+}
+
+func (r *hookedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+func (r *hookedRows) Close() error {
+	err := r.Rows.Close()
+	if r.after != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		r.after(r.err)
+		r.after = nil
+	}
+	return err
+}
+
+func (r *hookedRows) HasNextResultSet() bool {
+	n, ok := r.Rows.(driver.RowsNextResultSet)
+	return ok && n.HasNextResultSet()
+}
+
+func (r *hookedRows) NextResultSet() error {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *hookedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if t, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return t.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *hookedRows) ColumnTypeLength(index int) (int64, bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return t.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *hookedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return t.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func (r *hookedRows) ColumnTypeScanType(index int) reflect.Type {
+	if t, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return t.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+var (
+	statementOp    = regexp.MustCompile(`(?i)\b(select|insert|update|delete)\b`)
+	statementTable = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+"?([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// StatementName names a statement by its first operation and table,
+// "select Anime" say, which stays the same however the query text varies.
+func StatementName(query string) string {
+	op := statementOp.FindString(query)
+	if op == "" {
+		return "other"
+	}
+	name := strings.ToLower(op)
+	if m := statementTable.FindStringSubmatch(query); m != nil {
+		name += " " + m[1]
+	}
+	return name
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStatementName(t *testing.T) {
+	tests := map[string]string{
+		`SELECT * FROM "Anime" WHERE name = $1`:                        "select Anime",
+		`select count(*) from "Rating"`:                                "select Rating",
+		`INSERT INTO "Favorite" ("userId", "animeId") VALUES ($1, $2)`: "insert Favorite",
+		`UPDATE "Anime" SET popularity = popularity + 1`:               "update Anime",
+		`DELETE FROM "User" WHERE email = $1`:                          "delete User",
+		`WITH s AS (SELECT 1) SELECT * FROM s`:                         "select s",
+		`SELECT pg_advisory_lock($1)`:                                  "select",
+		`BEGIN`:                                                        "other",
+	}
+	for query, want := range tests {
+		if got := StatementName(query); got != want {
+			t.Errorf("StatementName(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+// fakeRows yields n rows, then fails with err, or io.EOF when err is nil.
+type fakeRows struct {
+	n   int
+	err error
+}
+
+func (r *fakeRows) Columns() []string { return []string{"a"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	r.n--
+	return nil
+}
+
+func TestHookedRows(t *testing.T) {
+	for _, want := range []error{nil, errors.New("canceling statement")} {
+		var calls int
+		var got error
+		rows := &hookedRows{Rows: &fakeRows{n: 2, err: want}, after: func(err error) {
+			calls++
+			got = err
+		}}
+		dest := make([]driver.Value, 1)
+		for rows.Next(dest) == nil {
+		}
+		if calls != 0 {
+			t.Error("hook finished before the rows were closed")
+		}
+		rows.Close()
+		rows.Close()
+		if calls != 1 || !errors.Is(got, want) {
+			t.Errorf("hook called %d times with %v, want once with %v", calls, got, want)
+		}
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	var c driver.Connector = &hookedConnector{}
+	if WithHooks(c) != c {
+		t.Error("WithHooks without hooks wrapped the connector")
+	}
+	hook := func(ctx context.Context, query string) func(error) { return func(error) {} }
+	if _, ok := WithHooks(c, hook).(*hookedConnector); !ok {
+		t.Error("WithHooks did not wrap the connector")
+	}
+}
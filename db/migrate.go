@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationsDir is where `migrate create` writes new files, relative to the
+// repository root. Files there are compiled into the binary.
+const MigrationsDir = "db/migrations"
+
+// migrationLockKey is the pg_advisory_lock key held while migrating, so two
+// instances starting at once do not apply the same migration twice.
+const migrationLockKey = 727_001
+
+var (
+	migrationName  = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	migrationLabel = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Migration is one versioned schema change read from
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration together with when it was applied. Applied
+// rows with no matching file, e.g. after a rollback of the binary, have an
+// empty Up and Down.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, "migrations")
+}
+
+func readMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := migrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: unexpected file %q, want <version>_<name>.up.sql or .down.sql", e.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations, recording them in
+// schema_migrations.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the embedded migrations.
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrate: up %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns the ones it rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrate: down %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration and when it was applied, ordered by
+// version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := MigrationStatus{Migration: mig}
+			if row, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &row.AppliedAt
+				delete(applied, mig.Version)
+			}
+			statuses = append(statuses, s)
+		}
+		for _, row := range applied {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, MigrationStatus{
+				Migration: Migration{Version: row.Version, Name: row.Name},
+				AppliedAt: &appliedAt,
+			})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// locked runs fn on a single connection holding the migration lock, after
+// making sure schema_migrations exists.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint      PRIMARY KEY,
+			name       text        NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func appliedVersions(ctx context.Context, conn *sqlx.Conn) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := conn.SelectContext(ctx, &rows, `SELECT version, name, applied_at FROM schema_migrations`); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateMigration writes an empty up and down file for the next version in
+// dir and returns their paths.
+func CreateMigration(dir, name string) (up, down string, err error) {
+	if !migrationLabel.MatchString(name) {
+		return "", "", fmt.Errorf("migrate: name %q must be lower case letters, digits and underscores", name)
+	}
+	existing, err := readMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", version, name))
+	up, down = base+".up.sql", base+".down.sql"
+	header := fmt.Sprintf("-- %04d_%s\n", version, name)
+	if err := os.WriteFile(up, []byte(header), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte(header), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
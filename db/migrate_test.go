@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestReadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_b.up.sql":   {Data: []byte("up b")},
+		"m/0002_b.down.sql": {Data: []byte("down b")},
+		"m/0010_c.up.sql":   {Data: []byte("up c")},
+		"m/0010_c.down.sql": {Data: []byte("down c")},
+		"m/0001_a.up.sql":   {Data: []byte("up a")},
+		"m/0001_a.down.sql": {Data: []byte("down a")},
+	}
+	migrations, err := readMigrations(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range migrations {
+		got = append(got, m.Name+":"+m.Up+":"+m.Down)
+	}
+	if want := "a:up a:down a b:up b:down b c:up c:down c"; strings.Join(got, " ") != want {
+		t.Errorf("migrations = %v, want %s", got, want)
+	}
+}
+
+func TestReadMigrationsErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"unexpected file": {"m/0001_a.up.sql": {}, "m/0001_a.down.sql": {}, "m/notes.txt": {}},
+		"missing down":    {"m/0001_a.up.sql": {Data: []byte("up")}},
+		"shared version":  {"m/0001_a.up.sql": {Data: []byte("up")}, "m/0001_b.down.sql": {Data: []byte("down")}},
+		"upper case name": {"m/0001_A.up.sql": {Data: []byte("up")}, "m/0001_A.down.sql": {Data: []byte("down")}},
+	}
+	for name, fsys := range tests {
+		if _, err := readMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, want versions without gaps", i, m.Version)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	up, down, err := CreateMigration(dir, "add_tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0001_add_tags.up.sql" || filepath.Base(down) != "0001_add_tags.down.sql" {
+		t.Errorf("files = %s, %s, want version 1", up, down)
+	}
+	up, _, err = CreateMigration(dir, "drop_tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0002_drop_tags.up.sql" {
+		t.Errorf("second file = %s, want version 2", up)
+	}
+	if _, _, err := CreateMigration(dir, "Drop Tags"); err == nil {
+		t.Error("name with spaces accepted")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Errorf("%d files written, want 4", len(entries))
+	}
+}
+
+// TestMigrator runs against the database named by TEST_DB_URL. Other
+// packages migrate the same database, so it only checks that Up leaves
+// every migration applied.
+func TestMigrator(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	ctx := context.Background()
+	db, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("second Up applied %v, %v, want nothing", applied, err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %d_%s is not applied", s.Version, s.Name)
+		}
+	}
+}
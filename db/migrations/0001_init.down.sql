@@ -0,0 +1,8 @@
+-- The base schema adopts tables that predate migrations, so reverting it
+-- would drop the catalogue and the users, not just what 0001 created.
+-- Refuse instead; drop the tables by hand if that is really wanted.
+DO $$
+BEGIN
+    RAISE EXCEPTION 'the baseline schema (0001_init) cannot be reverted';
+END
+$$;
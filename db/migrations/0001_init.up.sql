@@ -0,0 +1,36 @@
+-- Base schema. Tables are created only when missing so databases that
+-- predate migrations keep their data.
+CREATE TABLE IF NOT EXISTS "Anime" (
+    id              serial           PRIMARY KEY,
+    name            text             NOT NULL UNIQUE,
+    img             text             NOT NULL DEFAULT '',
+    "imgHeader"     text             NOT NULL DEFAULT '',
+    describe        text             NOT NULL DEFAULT '',
+    genres          text[]           NOT NULL DEFAULT '{}',
+    author          text             NOT NULL DEFAULT '',
+    country         text             NOT NULL DEFAULT '',
+    published       integer          NOT NULL DEFAULT 0,
+    "averageRating" double precision NOT NULL DEFAULT 0,
+    "ratingCount"   integer          NOT NULL DEFAULT 0,
+    status          text             NOT NULL DEFAULT '',
+    popularity      integer          NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "Chapter" (
+    id          serial       PRIMARY KEY,
+    chapter     integer      NOT NULL,
+    img         text[]       NOT NULL DEFAULT '{}',
+    name        text         NOT NULL DEFAULT '',
+    "animeName" text         NOT NULL REFERENCES "Anime" (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    "createdAt" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("animeName", chapter)
+);
+
+CREATE TABLE IF NOT EXISTS "User" (
+    id          text         PRIMARY KEY,
+    email       text         NOT NULL UNIQUE,
+    name        text         NOT NULL DEFAULT '',
+    image       text         NOT NULL DEFAULT '',
+    favorite    text[]       NOT NULL DEFAULT '{}',
+    "createdAt" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
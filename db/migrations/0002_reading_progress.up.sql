@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS "ReadingProgress" (
+    "userId"    text         NOT NULL REFERENCES "User" (id) ON DELETE CASCADE,
+    "animeName" text         NOT NULL REFERENCES "Anime" (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    chapter     integer      NOT NULL,
+    page        integer      NOT NULL,
+    "updatedAt" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("userId", "animeName")
+);
+
+CREATE INDEX IF NOT EXISTS "ReadingProgress_userId_updatedAt_idx" ON "ReadingProgress" ("userId", "updatedAt" DESC);
+
+CREATE TABLE IF NOT EXISTS "ChapterRead" (
+    "userId"    text         NOT NULL REFERENCES "User" (id) ON DELETE CASCADE,
+    "animeName" text         NOT NULL REFERENCES "Anime" (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    chapter     integer      NOT NULL,
+    "readAt"    timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("userId", "animeName", chapter)
+);
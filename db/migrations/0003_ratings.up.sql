@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "Rating" (
+    "userId"    text         NOT NULL REFERENCES "User" (id) ON DELETE CASCADE,
+    "animeName" text         NOT NULL REFERENCES "Anime" (name) ON UPDATE CASCADE ON DELETE CASCADE,
+    score       smallint     NOT NULL CHECK (score BETWEEN 1 AND 10),
+    "updatedAt" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("userId", "animeName")
+);
+
+CREATE INDEX IF NOT EXISTS "Rating_animeName_idx" ON "Rating" ("animeName");
@@ -0,0 +1,9 @@
+UPDATE "User" u
+SET favorite = COALESCE((
+    SELECT array_agg(a.name ORDER BY f."createdAt")
+    FROM "Favorite" f
+    JOIN "Anime" a ON a.id = f."animeId"
+    WHERE f."userId" = u.id
+), '{}');
+
+DROP TABLE IF EXISTS "Favorite";
@@ -0,0 +1,18 @@
+-- Favorites move from the "User".favorite name array to a join table keyed
+-- by manga id. The array column is left in place so the down migration can
+-- restore it.
+CREATE TABLE IF NOT EXISTS "Favorite" (
+    "userId"    text         NOT NULL REFERENCES "User" (id) ON DELETE CASCADE,
+    "animeId"   integer      NOT NULL REFERENCES "Anime" (id) ON DELETE CASCADE,
+    "createdAt" timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("userId", "animeId")
+);
+
+CREATE INDEX IF NOT EXISTS "Favorite_animeId_idx" ON "Favorite" ("animeId");
+
+INSERT INTO "Favorite" ("userId", "animeId")
+SELECT u.id, a.id
+FROM "User" u
+CROSS JOIN LATERAL unnest(u.favorite) AS f(name)
+JOIN "Anime" a ON a.name = f.name
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- pg_trgm is left installed, other schemas may use it.
+DROP INDEX IF EXISTS "Anime_author_trgm_idx";
+DROP INDEX IF EXISTS "Anime_name_trgm_idx";
+DROP INDEX IF EXISTS "Anime_search_idx";
+ALTER TABLE "Anime" DROP COLUMN IF EXISTS search;
@@ -0,0 +1,14 @@
+-- Full-text and typo tolerant search over "Anime". The 'simple' text search
+-- configuration is used because titles and descriptions mix several
+-- languages; misspellings are handled by pg_trgm instead of stemming.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE "Anime" ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(author, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(describe, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS "Anime_search_idx" ON "Anime" USING gin (search);
+CREATE INDEX IF NOT EXISTS "Anime_name_trgm_idx" ON "Anime" USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "Anime_author_trgm_idx" ON "Anime" USING gin (author gin_trgm_ops);
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/chimas/GoProject/config"
+)
+
+// WaitFor calls connect until it succeeds, r.Attempts runs out or ctx is
+// done, backing off exponentially with jitter in between so replicas
+// restarting together do not hammer a recovering server in step. what
+// names the server in logs.
+func WaitFor(ctx context.Context, what string, r config.Retry, connect func(context.Context) error) error {
+	backoff := r.Backoff
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil || attempt >= r.Attempts {
+			return err
+		}
+		// Wait between half and all of the backoff.
+		wait := backoff/2 + rand.N(backoff/2+1)
+		slog.WarnContext(ctx, "Unable to connect, retrying", "to", what, "attempt", attempt, "wait", wait.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(2*backoff, r.MaxBackoff)
+	}
+}
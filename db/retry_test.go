@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chimas/GoProject/config"
+)
+
+var testRetry = config.Retry{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestWaitFor(t *testing.T) {
+	refused := errors.New("connection refused")
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantErr   error
+	}{
+		{"first try", 0, 1, nil},
+		{"recovers", 2, 3, nil},
+		{"gives up", 5, 3, refused},
+	}
+	for _, tc := range tests {
+		calls := 0
+		err := WaitFor(context.Background(), "test", testRetry, func(context.Context) error {
+			calls++
+			if calls <= tc.failures {
+				return refused
+			}
+			return nil
+		})
+		if calls != tc.wantCalls || !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: %d calls, error %v, want %d calls, error %v", tc.name, calls, err, tc.wantCalls, tc.wantErr)
+		}
+	}
+}
+
+func TestWaitForCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	refused := errors.New("connection refused")
+	calls := 0
+	start := time.Now()
+	err := WaitFor(ctx, "test", config.Retry{Attempts: 10, Backoff: time.Hour, MaxBackoff: time.Hour}, func(context.Context) error {
+		calls++
+		cancel()
+		return refused
+	})
+	if calls != 1 || !errors.Is(err, refused) {
+		t.Errorf("%d calls, error %v, want 1 call and the last error", calls, err)
+	}
+	if time.Since(start) > time.Minute {
+		t.Error("WaitFor kept waiting after the context was canceled")
+	}
+}
@@ -1,25 +1,41 @@
 package db
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"fmt"
 
 	"github.com/chimas/GoProject/config"
 	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func DBConnection() (*sqlx.DB, error) {
-	err := godotenv.Load()
+// Connect opens the Postgres pool described by cfg and pings it, retrying
+// as cfg.Connect allows while the server is unreachable.
+func Connect(ctx context.Context, cfg config.DB, hooks ...QueryHook) (*sqlx.DB, error) {
+	dsn, err := postgresDSN(cfg)
 	if err != nil {
-		log.Println("Error loading .env file")
-		// log.Fatal("Error loading .env file")
+		return nil, fmt.Errorf("connect to database: %w", err)
 	}
-	db, err := sqlx.ConnectContext(ctx, "postgres", config.LoadEnv().DB_URL)
-
+	connector, err := pq.NewConnector(dsn)
 	if err != nil {
-		log.Fatal("Unable to connect to database:", err)
-		return nil, err
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	if d := postgresDialer(cfg); d != nil {
+		connector.Dialer(d)
+	}
+	db := sqlx.NewDb(sql.OpenDB(WithHooks(connector, hooks...)), "postgres")
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if err := WaitFor(ctx, "postgres", cfg.Connect, db.PingContext); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to database: %w", err)
 	}
 
 	return db, nil
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chimas/GoProject/config"
+	"github.com/lib/pq"
+)
+
+// postgresDSN returns cfg.URL with the TLS settings appended as lib/pq
+// keywords, which win over the same keywords in the URL, and with
+// cfg.Connect.Timeout as connect_timeout unless the URL sets one. lib/pq
+// checks the certificate against the host it connects to, so a server name
+// replaces the host and postgresDialer connects to the URL's host instead.
+func postgresDSN(cfg config.DB) (string, error) {
+	dsn := cfg.URL
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", err
+		}
+	}
+	for _, kv := range [][2]string{
+		{"sslmode", cfg.TLS.Mode},
+		{"sslrootcert", cfg.TLS.CAFile},
+		{"sslcert", cfg.TLS.CertFile},
+		{"sslkey", cfg.TLS.KeyFile},
+		{"host", cfg.TLS.ServerName},
+	} {
+		if kv[1] != "" {
+			dsn += " " + kv[0] + "=" + quoteDSN(kv[1])
+		}
+	}
+	if t := cfg.Connect.Timeout; t > 0 && !strings.Contains(dsn, "connect_timeout=") {
+		dsn += " connect_timeout=" + strconv.Itoa(int(math.Ceil(t.Seconds())))
+	}
+	return dsn, nil
+}
+
+// postgresDialer returns the dialer for a DSN whose host was replaced by
+// cfg.TLS.ServerName, or nil to let lib/pq dial the host itself.
+func postgresDialer(cfg config.DB) pq.Dialer {
+	if cfg.TLS.ServerName == "" {
+		return nil
+	}
+	// Validate only accepts a server name with a URL.
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil
+	}
+	return hostDialer{host: u.Hostname()}
+}
+
+// hostDialer connects to host whatever host it is asked for, keeping the
+// port.
+type hostDialer struct {
+	host string
+	net.Dialer
+}
+
+func (d hostDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d hostDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+func (d hostDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	return d.Dialer.DialContext(ctx, network, net.JoinHostPort(d.host, port))
+}
+
+func quoteDSN(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// redisTLS returns the TLS configuration for a Redis server at host, or nil
+// for a plain connection. rediss says whether the URL asked for TLS.
+func redisTLS(t config.TLS, rediss bool, host string) (*tls.Config, error) {
+	mode := t.Mode
+	if mode == "" && rediss {
+		mode = "verify-full"
+	}
+	if mode == "" || mode == "disable" {
+		return nil, nil
+	}
+
+	conf := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+	if t.ServerName != "" {
+		conf.ServerName = t.ServerName
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls: %w", err)
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis tls: no certificates in %s", t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	switch mode {
+	case "require":
+		conf.InsecureSkipVerify = true
+	case "verify-ca":
+		// crypto/tls verifies the chain and the name together, so skip its
+		// check and verify the chain alone.
+		conf.InsecureSkipVerify = true
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("redis tls: server sent no certificate")
+			}
+			opts := x509.VerifyOptions{Roots: conf.RootCAs, Intermediates: x509.NewCertPool()}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	return conf, nil
+}
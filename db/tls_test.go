@@ -0,0 +1,213 @@
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chimas/GoProject/config"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.DB
+		want string
+	}{
+		{
+			name: "key value",
+			cfg:  config.DB{URL: "host=db dbname=manga"},
+			want: "host=db dbname=manga",
+		},
+		{
+			name: "url",
+			cfg:  config.DB{URL: "postgres://manga@db:5432/manga?sslmode=disable"},
+			want: "dbname='manga' host='db' port='5432' sslmode='disable' user='manga'",
+		},
+		{
+			name: "tls",
+			cfg: config.DB{URL: "host=db sslmode=disable", TLS: config.TLS{
+				Mode:     "verify-full",
+				CAFile:   "/etc/ca's.pem",
+				CertFile: `C:\client.pem`,
+				KeyFile:  "client.key",
+			}},
+			want: `host=db sslmode=disable sslmode='verify-full' sslrootcert='/etc/ca\'s.pem' sslcert='C:\\client.pem' sslkey='client.key'`,
+		},
+		{
+			name: "server name",
+			cfg:  config.DB{URL: "postgres://10.0.0.5/manga", TLS: config.TLS{Mode: "verify-full", ServerName: "db.internal"}},
+			want: "dbname='manga' host='10.0.0.5' sslmode='verify-full' host='db.internal'",
+		},
+		{
+			name: "timeout",
+			cfg:  config.DB{URL: "host=db", Connect: config.Retry{Timeout: 1500 * time.Millisecond}},
+			want: "host=db connect_timeout=2",
+		},
+		{
+			name: "timeout in url",
+			cfg:  config.DB{URL: "host=db connect_timeout=10", Connect: config.Retry{Timeout: time.Second}},
+			want: "host=db connect_timeout=10",
+		},
+	}
+	for _, tc := range tests {
+		got, err := postgresDSN(tc.cfg)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: postgresDSN = %q, %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestPostgresDialer(t *testing.T) {
+	if d := postgresDialer(config.DB{URL: "postgres://127.0.0.1/manga"}); d != nil {
+		t.Errorf("dialer without a server name = %v, want lib/pq's", d)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	d := postgresDialer(config.DB{URL: "postgres://127.0.0.1/manga", TLS: config.TLS{ServerName: "db.invalid"}})
+	if d == nil {
+		t.Fatal("no dialer for a server name")
+	}
+	// lib/pq asks for the server name, the dialer reaches the URL's host.
+	for _, dial := range []func() (net.Conn, error){
+		func() (net.Conn, error) { return d.Dial("tcp", "db.invalid:"+port) },
+		func() (net.Conn, error) { return d.DialTimeout("tcp", "db.invalid:"+port, time.Second) },
+	} {
+		conn, err := dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := conn.RemoteAddr().String(); got != ln.Addr().String() {
+			t.Errorf("connected to %s, want %s", got, ln.Addr())
+		}
+		conn.Close()
+	}
+}
+
+// tlsServer starts a TLS server, whose certificate is for example.com and
+// 127.0.0.1, and writes that certificate to a PEM file.
+func tlsServer(t *testing.T) (addr, caFile string) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	// Handshakes failing is what some tests are after.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return srv.Listener.Addr().String(), caFile
+}
+
+// selfSigned writes a certificate for example.com that did not sign the
+// test server's.
+func selfSigned(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		DNSNames:              []string{"example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "other.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRedisTLS(t *testing.T) {
+	addr, caFile := tlsServer(t)
+	otherCAFile := selfSigned(t)
+
+	tests := []struct {
+		name      string
+		tls       config.TLS
+		rediss    bool
+		host      string
+		connected bool
+	}{
+		{"verify-full", config.TLS{Mode: "verify-full", CAFile: caFile}, false, "example.com", true},
+		{"rediss defaults to verify-full", config.TLS{CAFile: caFile}, true, "cache.internal", false},
+		{"server name", config.TLS{CAFile: caFile, ServerName: "example.com"}, true, "cache.internal", true},
+		{"verify-ca ignores the name", config.TLS{Mode: "verify-ca", CAFile: caFile}, false, "cache.internal", true},
+		{"verify-ca checks the chain", config.TLS{Mode: "verify-ca", CAFile: otherCAFile}, false, "example.com", false},
+		{"require", config.TLS{Mode: "require"}, false, "cache.internal", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := redisTLS(tc.tls, tc.rediss, tc.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := tls.Dial("tcp", addr, conf)
+			if err == nil {
+				conn.Close()
+			}
+			if connected := err == nil; connected != tc.connected {
+				t.Errorf("connected = %v (%v), want %v", connected, err, tc.connected)
+			}
+		})
+	}
+}
+
+func TestRedisTLSPlain(t *testing.T) {
+	for _, tc := range []struct {
+		mode   string
+		rediss bool
+	}{{"", false}, {"disable", false}, {"disable", true}} {
+		conf, err := redisTLS(config.TLS{Mode: tc.mode}, tc.rediss, "cache")
+		if conf != nil || err != nil {
+			t.Errorf("mode %q, rediss %v: redisTLS = %v, %v, want no TLS", tc.mode, tc.rediss, conf, err)
+		}
+	}
+}
+
+func TestRedisTLSErrors(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []config.TLS{
+		{Mode: "verify-full", CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{Mode: "verify-full", CAFile: notPEM},
+		{Mode: "require", CertFile: notPEM, KeyFile: notPEM},
+	} {
+		if _, err := redisTLS(tc, true, "cache"); err == nil {
+			t.Errorf("redisTLS(%+v) succeeded", tc)
+		}
+	}
+}
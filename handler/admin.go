@@ -0,0 +1,500 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/store"
+	"github.com/lib/pq"
+)
+
+// Vocabulary lists the values the admin API accepts for Manga.Status and
+// Manga.Genres, it comes from config.Catalog. Manga.Country must be an
+// ISO 3166-1 alpha-2 code.
+type Vocabulary struct {
+	Statuses []string
+	Genres   []string
+}
+
+const (
+	maxNameLength = 200
+	maxGenres     = 10
+)
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func NewAdminHandler(catalog store.CatalogStore, index store.SearchIndex, c cache.Cache, vocab Vocabulary) *AdminHandler {
+	return &AdminHandler{catalog: catalog, index: index, cache: cache.WithNamespace(c, mangaNamespace), vocab: vocab}
+}
+
+// AdminHandler edits mangas and chapters. Every route must be wrapped in
+// middleware.Authenticator.RequireRole("admin", ...).
+type AdminHandler struct {
+	catalog store.CatalogStore
+	index   store.SearchIndex
+	cache   cache.Cache
+	vocab   Vocabulary
+}
+
+// MangaInput is the body of the manga create and update endpoints.
+type MangaInput struct {
+	Name      string   `json:"name"`
+	Img       string   `json:"img"`
+	ImgHeader string   `json:"imgHeader"`
+	Describe  string   `json:"describe"`
+	Genres    []string `json:"genres"`
+	Author    string   `json:"author"`
+	Country   string   `json:"country"`
+	Published int      `json:"published"`
+	Status    string   `json:"status"`
+}
+
+// ChapterInput is the body of the chapter create and update endpoints.
+// Chapter is ignored on update, numbers change through renumbering.
+type ChapterInput struct {
+	Chapter int      `json:"chapter"`
+	Name    string   `json:"name"`
+	Img     []string `json:"img"`
+}
+
+type Renumbering struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type RenumberRequest struct {
+	Chapters []Renumbering `json:"chapters"`
+}
+
+type ReorderRequest struct {
+	// Order lists the current page indexes, starting at 0, in their new order.
+	Order []int `json:"order"`
+}
+
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &APIError{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: "request body is too large"}
+		}
+		return badRequest("invalid JSON body", map[string]string{"body": err.Error()})
+	}
+	return nil
+}
+
+// toManga validates the input against h.vocab.
+func (h *AdminHandler) toManga(in MangaInput) (store.Manga, error) {
+	details := map[string]string{}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || utf8.RuneCountInString(in.Name) > maxNameLength {
+		details["name"] = "must be 1 to " + strconv.Itoa(maxNameLength) + " characters"
+	}
+	if !slices.Contains(h.vocab.Statuses, in.Status) {
+		details["status"] = "must be one of " + strings.Join(h.vocab.Statuses, ", ")
+	}
+	if !countryCode.MatchString(in.Country) {
+		details["country"] = "must be an ISO 3166-1 alpha-2 code such as JP"
+	}
+	if len(in.Genres) > maxGenres {
+		details["genres"] = "at most " + strconv.Itoa(maxGenres) + " genres"
+	}
+	seen := map[string]bool{}
+	for _, g := range in.Genres {
+		if !slices.Contains(h.vocab.Genres, g) {
+			details["genres"] = "unknown genre " + strconv.Quote(g)
+		} else if seen[g] {
+			details["genres"] = "duplicate genre " + strconv.Quote(g)
+		}
+		seen[g] = true
+	}
+	if in.Published != 0 && (in.Published < 1900 || in.Published > time.Now().Year()+1) {
+		details["published"] = "must be a year between 1900 and next year"
+	}
+	if len(details) > 0 {
+		return store.Manga{}, badRequest("invalid manga", details)
+	}
+
+	return store.Manga{
+		Name:      in.Name,
+		Img:       in.Img,
+		ImgHeader: in.ImgHeader,
+		Describe:  in.Describe,
+		Genres:    pq.StringArray(append([]string{}, in.Genres...)),
+		Author:    in.Author,
+		Country:   in.Country,
+		Published: in.Published,
+		Status:    in.Status,
+	}, nil
+}
+
+func toChapter(animeName string, in ChapterInput, checkNumber bool) (store.Chapter, error) {
+	details := map[string]string{}
+	if checkNumber && in.Chapter < 1 {
+		details["chapter"] = "must be a positive number"
+	}
+	if utf8.RuneCountInString(in.Name) > maxNameLength {
+		details["name"] = "at most " + strconv.Itoa(maxNameLength) + " characters"
+	}
+	for i, img := range in.Img {
+		if strings.TrimSpace(img) == "" {
+			details["img"] = "page " + strconv.Itoa(i) + " is empty"
+			break
+		}
+	}
+	if len(details) > 0 {
+		return store.Chapter{}, badRequest("invalid chapter", details)
+	}
+	return store.Chapter{Chapter: in.Chapter, Name: in.Name, Img: pq.StringArray(append([]string{}, in.Img...)), AnimeName: animeName}, nil
+}
+
+func chapterNumber(r *http.Request) (int, error) {
+	n, err := strconv.Atoi(r.PathValue("chapter"))
+	if err != nil || n < 1 {
+		return 0, badRequest("chapter must be a positive number", map[string]string{"chapter": r.PathValue("chapter")})
+	}
+	return n, nil
+}
+
+// changed drops every cached response that includes the named mangas.
+func (h *AdminHandler) changed(r *http.Request, names ...string) {
+	for _, name := range names {
+		invalidateManga(r.Context(), h.cache, name)
+	}
+}
+
+// reindex and unindex keep the search index in step with the catalog.
+// Failures are logged, the write itself already succeeded.
+func (h *AdminHandler) reindex(r *http.Request, manga store.Manga) {
+	if err := h.index.Index(r.Context(), manga); err != nil {
+		slog.ErrorContext(r.Context(), "search index", "manga", manga.Name, "error", err)
+	}
+}
+
+func (h *AdminHandler) unindex(r *http.Request, name string) {
+	if err := h.index.Remove(r.Context(), name); err != nil {
+		slog.ErrorContext(r.Context(), "search remove", "manga", name, "error", err)
+	}
+}
+
+// @Summary Create a manga
+// @Description Create a manga. Status and genres must come from the configured vocabulary and country is an ISO 3166-1 alpha-2 code.
+// @Tags Admin
+// @ID admin-create-manga
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  body body MangaInput true "Manga"
+// @Success 201 {object} MangaSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga [post]
+func (h *AdminHandler) CreateManga(w http.ResponseWriter, r *http.Request) {
+	var in MangaInput
+	if err := decodeBody(r, &in); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	manga, err := h.toManga(in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	manga, err = h.catalog.CreateManga(r.Context(), manga)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.reindex(r, manga)
+	h.changed(r, manga.Name)
+
+	writeJSON(w, http.StatusCreated, manga)
+}
+
+// @Summary Update a manga
+// @Description Replace the editable fields of a manga. Changing the name keeps its chapters, ratings and reading progress.
+// @Tags Admin
+// @ID admin-update-manga
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  body body MangaInput true "Manga"
+// @Success 200 {object} MangaSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name} [put]
+func (h *AdminHandler) UpdateManga(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var in MangaInput
+	if err := decodeBody(r, &in); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	manga, err := h.toManga(in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	manga, err = h.catalog.UpdateManga(r.Context(), name, manga)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if manga.Name != name {
+		h.unindex(r, name)
+	}
+	h.reindex(r, manga)
+	h.changed(r, name, manga.Name)
+
+	writeJSON(w, http.StatusOK, manga)
+}
+
+// @Summary Delete a manga
+// @Description Delete a manga with its chapters, ratings, favorites and reading progress
+// @Tags Admin
+// @ID admin-delete-manga
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name} [delete]
+func (h *AdminHandler) DeleteManga(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.catalog.DeleteManga(r.Context(), name); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.unindex(r, name)
+	h.changed(r, name)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: "Manga deleted"})
+}
+
+// @Summary Create a chapter
+// @Description Add a chapter to a manga
+// @Tags Admin
+// @ID admin-create-chapter
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  body body ChapterInput true "Chapter"
+// @Success 201 {object} ChapterSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters [post]
+func (h *AdminHandler) CreateChapter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var in ChapterInput
+	if err := decodeBody(r, &in); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	chapter, err := toChapter(name, in, true)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	chapter, err = h.catalog.CreateChapter(r.Context(), chapter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.changed(r, name)
+
+	writeJSON(w, http.StatusCreated, chapter)
+}
+
+// @Summary Update a chapter
+// @Description Replace the name and pages of a chapter
+// @Tags Admin
+// @ID admin-update-chapter
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  chapter path int true "Chapter number"
+// @Param  body body ChapterInput true "Chapter, the number is ignored"
+// @Success 200 {object} ChapterSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters/{chapter} [put]
+func (h *AdminHandler) UpdateChapter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	number, err := chapterNumber(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var in ChapterInput
+	if err := decodeBody(r, &in); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	chapter, err := toChapter(name, in, false)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	chapter, err = h.catalog.UpdateChapter(r.Context(), name, number, chapter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.changed(r, name)
+
+	writeJSON(w, http.StatusOK, chapter)
+}
+
+// @Summary Delete a chapter
+// @Description Delete a chapter and the read marks on it
+// @Tags Admin
+// @ID admin-delete-chapter
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  chapter path int true "Chapter number"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters/{chapter} [delete]
+func (h *AdminHandler) DeleteChapter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	number, err := chapterNumber(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.catalog.DeleteChapter(r.Context(), name, number); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.changed(r, name)
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: "Chapter deleted"})
+}
+
+// @Summary Renumber chapters
+// @Description Move chapters to new numbers in one step, numbers may be swapped. Read marks and reading progress follow their chapter.
+// @Tags Admin
+// @ID admin-renumber-chapters
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  body body RenumberRequest true "Old and new numbers"
+// @Success 200 {array} ChapterSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters/renumber [post]
+func (h *AdminHandler) RenumberChapters(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req RenumberRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	numbers := map[int]int{}
+	for i, c := range req.Chapters {
+		field := "chapters[" + strconv.Itoa(i) + "]"
+		if c.From < 1 || c.To < 1 {
+			writeError(w, r, badRequest("invalid renumbering", map[string]string{field: "numbers must be positive"}))
+			return
+		}
+		if _, ok := numbers[c.From]; ok {
+			writeError(w, r, badRequest("invalid renumbering", map[string]string{field: "chapter " + strconv.Itoa(c.From) + " is listed twice"}))
+			return
+		}
+		numbers[c.From] = c.To
+	}
+
+	chapters, err := h.catalog.RenumberChapters(r.Context(), name, numbers)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.changed(r, name)
+
+	writeJSON(w, http.StatusOK, chapters)
+}
+
+// @Summary Reorder chapter pages
+// @Description Rearrange the pages of a chapter. order lists every current page index, starting at 0, in the new order.
+// @Tags Admin
+// @ID admin-reorder-pages
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  chapter path int true "Chapter number"
+// @Param  body body ReorderRequest true "New page order"
+// @Success 200 {object} ChapterSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters/{chapter}/pages [put]
+func (h *AdminHandler) ReorderPages(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	number, err := chapterNumber(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req ReorderRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	chapter, err := h.catalog.ReorderPages(r.Context(), name, number, req.Order)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.changed(r, name)
+
+	writeJSON(w, http.StatusOK, chapter)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func TestCreateManga(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "GET", "/mangas", nil, nil, nil)
+
+	var resp ErrorResponse
+	api.expect(http.StatusBadRequest, "POST", "/admin/manga", &admin,
+		MangaInput{Name: " ", Status: "paused", Country: "jp", Genres: []string{"action", "action"}, Published: 1800}, &resp)
+	for _, field := range []string{"name", "status", "country", "genres", "published"} {
+		if resp.Details[field] == "" {
+			t.Errorf("details = %v, missing %s", resp.Details, field)
+		}
+	}
+	resp = ErrorResponse{}
+	api.expect(http.StatusBadRequest, "POST", "/admin/manga", &admin,
+		MangaInput{Name: "Vagabond", Status: "cancelled", Country: "JP", Genres: []string{"comedy"}}, &resp)
+	if resp.Details["status"] != "must be one of ongoing, finished, frozen" || resp.Details["genres"] == "" {
+		t.Errorf("details = %v, want the configured vocabulary only", resp.Details)
+	}
+
+	input := MangaInput{Name: "Vagabond", Author: "Takehiko Inoue", Status: "frozen", Country: "JP", Genres: []string{"historical", "seinen"}, Published: 1998}
+	var manga Manga
+	api.expect(http.StatusCreated, "POST", "/admin/manga", &admin, input, &manga)
+	if manga.Name != "Vagabond" || manga.Id == 0 {
+		t.Errorf("created = %+v, want Vagabond with an id", manga)
+	}
+	api.expect(http.StatusConflict, "POST", "/admin/manga", &admin, input, &resp)
+	if resp.Code != "conflict" {
+		t.Errorf("code = %q, want conflict", resp.Code)
+	}
+
+	// The new manga shows up in cached lists and in search.
+	var page store.Page[Manga]
+	api.expect(http.StatusOK, "GET", "/mangas", nil, nil, &page)
+	if page.Total != 3 {
+		t.Errorf("mangas total = %d, want 3", page.Total)
+	}
+	var hits store.Page[store.SearchHit]
+	api.expect(http.StatusOK, "GET", "/search?q=inoue", nil, nil, &hits)
+	if len(hits.Items) != 1 || hits.Items[0].Manga.Name != "Vagabond" {
+		t.Errorf("hits = %+v, want Vagabond", hits.Items)
+	}
+}
+
+func TestUpdateAndDeleteManga(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "GET", "/manga?name=Monster", nil, nil, nil)
+
+	input := MangaInput{Name: "Monster", Author: "Naoki Urasawa", Status: "finished", Country: "JP", Genres: []string{"mystery"}}
+	api.expect(http.StatusOK, "PUT", "/admin/manga/Monster", &admin, input, nil)
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Monster", nil, nil, &manga)
+	if len(manga.Genres) != 1 || manga.Genres[0] != "mystery" {
+		t.Errorf("genres = %v, want the updated [mystery]", manga.Genres)
+	}
+	api.expect(http.StatusNotFound, "PUT", "/admin/manga/Vagabond", &admin, input, nil)
+
+	api.expect(http.StatusOK, "DELETE", "/admin/manga/Monster", &admin, nil, nil)
+	api.expect(http.StatusNotFound, "GET", "/manga?name=Monster", nil, nil, nil)
+	api.expect(http.StatusNotFound, "DELETE", "/admin/manga/Monster", &admin, nil, nil)
+}
+
+func TestChapters(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, nil)
+
+	api.expect(http.StatusCreated, "POST", "/admin/manga/Berserk/chapters", &admin, ChapterInput{Chapter: 3, Img: []string{"p1"}}, nil)
+	api.expect(http.StatusConflict, "POST", "/admin/manga/Berserk/chapters", &admin, ChapterInput{Chapter: 3, Img: []string{"p1"}}, nil)
+	api.expect(http.StatusNotFound, "POST", "/admin/manga/Vagabond/chapters", &admin, ChapterInput{Chapter: 1, Img: []string{"p1"}}, nil)
+
+	var chapters []Chapter
+	api.expect(http.StatusOK, "POST", "/admin/manga/Berserk/chapters/renumber", &admin,
+		RenumberRequest{Chapters: []Renumbering{{From: 3, To: 1}, {From: 1, To: 3}}}, &chapters)
+	if got := len(chapters); got != 3 {
+		t.Fatalf("renumbered %d chapters, want 3", got)
+	}
+	var chapter Chapter
+	api.expect(http.StatusOK, "GET", "/manga/Berserk/1", nil, nil, &chapter)
+	if len(chapter.Img) != 1 {
+		t.Errorf("chapter 1 has %d pages, want the former chapter 3", len(chapter.Img))
+	}
+
+	api.expect(http.StatusBadRequest, "POST", "/admin/manga/Berserk/chapters/renumber", &admin,
+		RenumberRequest{Chapters: []Renumbering{{From: 1, To: 4}, {From: 1, To: 5}}}, nil)
+	api.expect(http.StatusBadRequest, "POST", "/admin/manga/Berserk/chapters/renumber", &admin,
+		RenumberRequest{Chapters: []Renumbering{{From: 0, To: 4}}}, nil)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/store"
+)
+
+// CacheTTLs holds how long each MangaHandler endpoint keeps its responses.
+// A zero TTL disables caching for that endpoint.
+type CacheTTLs struct {
+	Mangas  time.Duration
+	Manga   time.Duration
+	Popular time.Duration
+	Filter  time.Duration
+	Chapter time.Duration
+	Search  time.Duration
+	// Stale is how much longer a response is kept past its TTL, to be
+	// served while the store is unavailable. Zero keeps nothing stale.
+	Stale time.Duration
+}
+
+var DefaultCacheTTLs = CacheTTLs{
+	Mangas:  time.Minute,
+	Manga:   time.Minute,
+	Popular: 5 * time.Minute,
+	Filter:  30 * time.Second,
+	Chapter: 10 * time.Minute,
+	Search:  30 * time.Second,
+	Stale:   10 * time.Minute,
+}
+
+// mangaNamespace prefixes every key and tag written by the manga endpoints.
+const mangaNamespace = "manga"
+
+// Cache tags shared by the handlers. tagMangas covers every list response,
+// mangaTag(name) covers a single manga and its chapters.
+const tagMangas = "mangas"
+
+func mangaTag(name string) string {
+	return "manga:" + name
+}
+
+// cacheEntry is what cached stores: the response and when it goes stale.
+type cacheEntry struct {
+	FreshUntil time.Time       `json:"freshUntil"`
+	Value      json.RawMessage `json:"value"`
+}
+
+// staleLoadTimeout is how long cached waits for the store before serving a
+// stale entry. Loads are not interrupted, lib/pq may sit on a dead socket
+// past any context deadline, so the wait runs beside the load.
+const staleLoadTimeout = 3 * time.Second
+
+// cached returns the value stored under key, or calls load and stores its
+// result for ttl. The entry is kept for stale longer and returned when load
+// fails with store.ErrUnavailable or takes over staleLoadTimeout, so reads
+// survive a short database outage. Cache failures are logged and never
+// fail the request.
+func cached[T any](ctx context.Context, c cache.Cache, key string, ttl, stale time.Duration, tags []string, load func(ctx context.Context) (T, error)) (T, error) {
+	v, _, err := cachedOrStale(ctx, c, key, ttl, stale, tags, load)
+	return v, err
+}
+
+// cachedOrStale is cached that also reports whether the value is a stale
+// entry served because the store is unavailable, for callers that would
+// otherwise go on to query it.
+func cachedOrStale[T any](ctx context.Context, c cache.Cache, key string, ttl, stale time.Duration, tags []string, load func(ctx context.Context) (T, error)) (T, bool, error) {
+	if ttl <= 0 {
+		v, err := load(ctx)
+		return v, false, err
+	}
+
+	var entry cacheEntry
+	val, err := c.Get(ctx, key)
+	if err == nil {
+		if err := json.Unmarshal(val, &entry); err != nil || entry.Value == nil {
+			entry = cacheEntry{}
+		}
+	} else if !errors.Is(err, cache.ErrMiss) {
+		slog.WarnContext(ctx, "cache get", "key", key, "error", err)
+	}
+	var cachedV T
+	hasCached := entry.Value != nil && json.Unmarshal(entry.Value, &cachedV) == nil
+	if hasCached && time.Now().Before(entry.FreshUntil) {
+		return cachedV, false, nil
+	}
+
+	var v T
+	if hasCached {
+		v, err = loadWithin(ctx, staleLoadTimeout, load)
+		if errors.Is(err, store.ErrUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+			slog.WarnContext(ctx, "Serving stale cache entry", "key", key, "error", err)
+			return cachedV, true, nil
+		}
+	} else {
+		v, err = load(ctx)
+	}
+	if err != nil {
+		return v, false, err
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return v, false, nil
+	}
+	data, err := json.Marshal(cacheEntry{FreshUntil: time.Now().Add(ttl), Value: value})
+	if err != nil {
+		return v, false, nil
+	}
+	if err := c.Set(ctx, key, data, ttl+stale, tags...); err != nil {
+		slog.WarnContext(ctx, "cache set", "key", key, "error", err)
+	}
+	return v, false, nil
+}
+
+// loadWithin runs load with a deadline of timeout and gives up waiting for
+// it then, even if load itself does not return.
+func loadWithin[T any](ctx context.Context, timeout time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer cancel()
+		v, err := load(ctx)
+		done <- result{v, err}
+	}()
+	select {
+	case res := <-done:
+		return res.v, res.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// invalidateManga drops every cached response that includes the manga.
+func invalidateManga(ctx context.Context, c cache.Cache, name string) {
+	if err := c.InvalidateTags(ctx, mangaTag(name), tagMangas); err != nil {
+		slog.WarnContext(ctx, "cache invalidate", "manga", name, "error", err)
+	}
+}
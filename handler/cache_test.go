@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/store"
+)
+
+// loader counts its calls and returns value, or err when it is set.
+type loader struct {
+	calls int
+	value string
+	err   error
+}
+
+func (l *loader) load(ctx context.Context) (string, error) {
+	l.calls++
+	return l.value, l.err
+}
+
+func TestCached(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(10)
+	l := &loader{value: "a"}
+
+	for range 2 {
+		v, err := cached(ctx, c, "k", time.Minute, 0, nil, l.load)
+		if err != nil || v != "a" {
+			t.Fatalf("cached = %q, %v, want a", v, err)
+		}
+	}
+	if l.calls != 1 {
+		t.Errorf("load called %d times, want once", l.calls)
+	}
+
+	l = &loader{value: "b"}
+	for range 2 {
+		if _, err := cached(ctx, c, "uncached", 0, 0, nil, l.load); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.calls != 2 {
+		t.Errorf("with ttl 0 load called %d times, want every time", l.calls)
+	}
+
+	l = &loader{err: store.ErrNotFound}
+	for range 2 {
+		if _, err := cached(ctx, c, "missing", time.Minute, 0, nil, l.load); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+	}
+	if l.calls != 2 {
+		t.Errorf("errors were cached: load called %d times", l.calls)
+	}
+}
+
+func TestInvalidateManga(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(10)
+	tags := map[string][]string{
+		"manga:Berserk": {mangaTag("Berserk")},
+		"manga:Monster": {mangaTag("Monster")},
+		"popular":       {tagMangas},
+	}
+	for key, tag := range tags {
+		if _, err := cached(ctx, c, key, time.Minute, 0, tag, (&loader{value: key}).load); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	invalidateManga(ctx, c, "Berserk")
+
+	for key, want := range map[string]bool{"manga:Berserk": false, "manga:Monster": true, "popular": false} {
+		_, err := c.Get(ctx, key)
+		if got := err == nil; got != want {
+			t.Errorf("%s cached = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestCachedOrStale(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(10)
+
+	l := &loader{value: "old"}
+	if _, _, err := cachedOrStale(ctx, c, "k", time.Minute, time.Minute, nil, l.load); err != nil {
+		t.Fatal(err)
+	}
+	expireFreshness(t, c, "k")
+
+	l.value, l.err = "", store.ErrUnavailable
+	v, stale, err := cachedOrStale(ctx, c, "k", time.Minute, time.Minute, nil, l.load)
+	if err != nil || v != "old" || !stale {
+		t.Errorf("during an outage = %q, %v, %v, want the stale old", v, stale, err)
+	}
+
+	l.err = store.ErrNotFound
+	if _, _, err := cachedOrStale(ctx, c, "k", time.Minute, time.Minute, nil, l.load); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound: only outages serve stale entries", err)
+	}
+
+	l.value, l.err = "new", nil
+	v, stale, err = cachedOrStale(ctx, c, "k", time.Minute, time.Minute, nil, l.load)
+	if err != nil || v != "new" || stale {
+		t.Errorf("after the outage = %q, %v, %v, want a fresh new", v, stale, err)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/chimas/GoProject/store"
+)
+
+// ErrorResponse is the body of every non-2xx response.
+type ErrorResponse struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"requestId,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// APIError is an error that already knows its HTTP status and code.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func badRequest(message string, details map[string]string) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: "bad_request", Message: message, Details: details}
+}
+
+func notFound(message string) *APIError {
+	return &APIError{Status: http.StatusNotFound, Code: "not_found", Message: message}
+}
+
+// toAPIError maps store and driver errors to the response sent to clients.
+// Unknown errors are hidden behind a generic 500, writeError logs them.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, store.ErrNotFound):
+		return notFound("resource not found")
+	case errors.Is(err, store.ErrConflict):
+		return &APIError{Status: http.StatusConflict, Code: "conflict", Message: "resource already exists"}
+	case errors.Is(err, store.ErrInvalidOrder):
+		return badRequest("invalid page order", map[string]string{"order": "must list every page index exactly once"})
+	case errors.Is(err, store.ErrUnavailable):
+		return &APIError{Status: http.StatusServiceUnavailable, Code: "unavailable", Message: "database is unavailable"}
+	default:
+		return &APIError{Status: http.StatusInternalServerError, Code: "internal", Message: "internal server error"}
+	}
+}
+
+func requestID(r *http.Request) string {
+	return r.Header.Get("X-Request-ID")
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	if apiErr.Status == http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "internal error", "error", err)
+	}
+	writeJSON(w, apiErr.Status, ErrorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		RequestID: requestID(r),
+		Details:   apiErr.Details,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("encode response", "error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func TestToAPIError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{store.ErrNotFound, http.StatusNotFound, "not_found"},
+		{fmt.Errorf("create manga: %w", store.ErrConflict), http.StatusConflict, "conflict"},
+		{store.ErrInvalidOrder, http.StatusBadRequest, "bad_request"},
+		{store.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+		{badRequest("invalid", nil), http.StatusBadRequest, "bad_request"},
+		{errors.New("pq: syntax error"), http.StatusInternalServerError, "internal"},
+	}
+	for _, tt := range tests {
+		apiErr := toAPIError(tt.err)
+		if apiErr.Status != tt.status || apiErr.Code != tt.code {
+			t.Errorf("toAPIError(%v) = %d %s, want %d %s", tt.err, apiErr.Status, apiErr.Code, tt.status, tt.code)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	writeError(rec, req, errors.New("pq: password authentication failed"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != "internal" || resp.Message != "internal server error" || resp.RequestID != "req-1" {
+		t.Errorf("response = %+v, want a generic internal error", resp)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	api := newTestAPI(t)
+
+	var resp ErrorResponse
+	api.expect(http.StatusNotFound, "GET", "/manga?name=Vagabond", nil, nil, &resp)
+	if resp.Code != "not_found" {
+		t.Errorf("404 code = %q, want not_found", resp.Code)
+	}
+
+	api.store.down.Store(true)
+	resp = ErrorResponse{}
+	api.expect(http.StatusServiceUnavailable, "GET", "/manga?name=Berserk", nil, nil, &resp)
+	if resp.Code != "unavailable" {
+		t.Errorf("503 code = %q, want unavailable", resp.Code)
+	}
+
+	resp = ErrorResponse{}
+	api.expect(http.StatusUnauthorized, "GET", "/user/me", nil, nil, &resp)
+	if resp.Code != "unauthorized" {
+		t.Errorf("401 code = %q, want unauthorized", resp.Code)
+	}
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/chimas/GoProject/comic"
+	"github.com/chimas/GoProject/store"
+)
+
+// maxExportChapters bounds the chapter range of a single download.
+const maxExportChapters = 50
+
+type exportFormat struct {
+	contentType string
+	write       func(e *comic.Exporter, ctx context.Context, w io.Writer, x comic.Export) error
+}
+
+var exportFormats = map[string]exportFormat{
+	"cbz":  {"application/vnd.comicbook+zip", (*comic.Exporter).WriteCBZ},
+	"epub": {"application/epub+zip", (*comic.Exporter).WriteEPUB},
+}
+
+func NewExportHandler(mangas store.MangaStore, exporter *comic.Exporter) *ExportHandler {
+	return &ExportHandler{mangas: mangas, exporter: exporter}
+}
+
+// ExportHandler serves chapters as CBZ and EPUB downloads.
+type ExportHandler struct {
+	mangas   store.MangaStore
+	exporter *comic.Exporter
+}
+
+// @Summary Download a chapter
+// @Description Download a chapter as a CBZ with ComicInfo.xml or as a fixed-layout EPUB 3. The archive is streamed as it is built, so there is no Content-Length.
+// @Tags Manga
+// @ID download-chapter
+// @Produce  application/vnd.comicbook+zip,application/epub+zip
+// @Param  name path string true "Name of the Manga"
+// @Param  chapter path int true "Chapter number"
+// @Param  format query string false "cbz (default) or epub"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /manga/{name}/{chapter}/download [get]
+func (h *ExportHandler) Chapter(w http.ResponseWriter, r *http.Request) {
+	format, err := h.format(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	number, err := chapterNumber(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name := r.PathValue("name")
+	manga, err := h.mangas.ByName(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	chapter, err := h.mangas.Chapter(r.Context(), name, number)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.stream(w, r, format, comic.Export{Manga: manga, Chapters: []store.Chapter{chapter}})
+}
+
+// @Summary Download chapters
+// @Description Download the chapters from one number to another, at most 50, as a single CBZ or fixed-layout EPUB 3. Missing numbers in the range are skipped.
+// @Tags Manga
+// @ID download-chapters
+// @Produce  application/vnd.comicbook+zip,application/epub+zip
+// @Param  name path string true "Name of the Manga"
+// @Param  from query int true "First chapter number"
+// @Param  to query int true "Last chapter number"
+// @Param  format query string false "cbz (default) or epub"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /manga/{name}/download [get]
+func (h *ExportHandler) Chapters(w http.ResponseWriter, r *http.Request) {
+	format, err := h.format(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	from, errFrom := strconv.Atoi(query.Get("from"))
+	to, errTo := strconv.Atoi(query.Get("to"))
+	if errFrom != nil || errTo != nil || from < 1 || to < from {
+		writeError(w, r, badRequest("invalid chapter range", map[string]string{"from": "positive number", "to": "number not below from"}))
+		return
+	}
+	name := r.PathValue("name")
+	manga, err := h.mangas.ByName(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	all, err := h.mangas.Chapters(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var chapters []store.Chapter
+	for _, c := range all {
+		if c.Chapter >= from && c.Chapter <= to {
+			chapters = append(chapters, c)
+		}
+	}
+	if len(chapters) == 0 {
+		writeError(w, r, notFound("no chapters in range"))
+		return
+	}
+	if len(chapters) > maxExportChapters {
+		writeError(w, r, badRequest("too many chapters", map[string]string{"to": "at most " + strconv.Itoa(maxExportChapters) + " chapters per download"}))
+		return
+	}
+
+	h.stream(w, r, format, comic.Export{Manga: manga, Chapters: chapters})
+}
+
+func (h *ExportHandler) format(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "cbz"
+	}
+	if _, ok := exportFormats[format]; !ok {
+		return "", badRequest("invalid format", map[string]string{"format": "must be cbz or epub"})
+	}
+	return format, nil
+}
+
+// stream writes the archive. Until the first byte is out a failure is an
+// ordinary error response; after that the connection is aborted so the
+// client sees a broken download instead of a truncated archive.
+func (h *ExportHandler) stream(w http.ResponseWriter, r *http.Request, format string, x comic.Export) {
+	allowLongTransfer(w)
+	f := exportFormats[format]
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": x.Filename(format)}))
+
+	sw := &startedWriter{w: w}
+	err := f.write(h.exporter, r.Context(), sw, x)
+	if err == nil {
+		return
+	}
+	if !sw.started {
+		w.Header().Del("Content-Disposition")
+		writeError(w, r, &APIError{Status: http.StatusBadGateway, Code: "page_unavailable", Message: "a page could not be loaded"})
+		slog.WarnContext(r.Context(), "export failed", "file", x.Filename(format), "error", err)
+		return
+	}
+	slog.WarnContext(r.Context(), "export aborted", "file", x.Filename(format), "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+type startedWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (s *startedWriter) Write(p []byte) (int, error) {
+	s.started = true
+	return s.w.Write(p)
+}
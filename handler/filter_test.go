@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func TestDecodeFilterParams(t *testing.T) {
+	params, err := decodeFilterParams(url.Values{
+		"name":       {"ber"},
+		"genres[]":   {"action", "horror"},
+		"status":     {"ongoing", ""},
+		"perPage":    {"5"},
+		"unexpected": {"x"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FilterParams{Name: "ber", Genres: []string{"action", "horror"}, Status: []string{"ongoing"}, PerPage: 5}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("params = %+v, want %+v", params, want)
+	}
+
+	_, err = decodeFilterParams(url.Values{"page": {"two"}, "ratingMin": {"high"}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		t.Fatalf("err = %v, want a 400", err)
+	}
+	if len(apiErr.Details) != 2 || apiErr.Details["page"] == "" || apiErr.Details["ratingMin"] == "" {
+		t.Errorf("details = %v, want page and ratingMin", apiErr.Details)
+	}
+}
+
+func TestToFilter(t *testing.T) {
+	cursor := store.Cursor{Popularity: 10, Id: 1}
+	tests := []struct {
+		name    string
+		params  FilterParams
+		want    store.MangaFilter
+		invalid []string
+	}{
+		{
+			name:   "defaults",
+			params: FilterParams{},
+			want:   store.MangaFilter{GenreMatch: store.GenreMatchAll, Page: 1, PerPage: defaultPerPage},
+		},
+		{
+			name:   "order",
+			params: FilterParams{GenreMatch: "any", OrderField: "averageRating", OrderSort: "DESC", Page: 2, PerPage: 10},
+			want:   store.MangaFilter{GenreMatch: store.GenreMatchAny, Sort: store.SortAverageRating, Desc: true, Page: 2, PerPage: 10},
+		},
+		{
+			name:   "cursor",
+			params: FilterParams{Cursor: cursor.String()},
+			want:   store.MangaFilter{GenreMatch: store.GenreMatchAll, Sort: store.SortPopularity, After: &cursor, Page: 1, PerPage: defaultPerPage},
+		},
+		{
+			name:    "unknown order",
+			params:  FilterParams{GenreMatch: "some", OrderField: "id", OrderSort: "up"},
+			invalid: []string{"genreMatch", "orderField", "orderSort"},
+		},
+		{
+			name:    "cursor with another order",
+			params:  FilterParams{Cursor: cursor.String(), OrderField: "name"},
+			invalid: []string{"cursor"},
+		},
+		{
+			name:    "bad cursor",
+			params:  FilterParams{Cursor: "!"},
+			invalid: []string{"cursor"},
+		},
+		{
+			name:    "ranges",
+			params:  FilterParams{PublishedFrom: 2000, PublishedTo: 1990, RatingMin: -1, Page: -1, PerPage: maxPerPage + 1},
+			invalid: []string{"publishedFrom", "ratingMin", "page", "perPage"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.params.toFilter()
+			if tt.invalid == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(f, tt.want) {
+					t.Errorf("filter = %+v, want %+v", f, tt.want)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want an APIError", err)
+			}
+			for _, key := range tt.invalid {
+				if apiErr.Details[key] == "" {
+					t.Errorf("details = %v, missing %s", apiErr.Details, key)
+				}
+			}
+			if len(apiErr.Details) != len(tt.invalid) {
+				t.Errorf("details = %v, want only %v", apiErr.Details, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	api := newTestAPI(t)
+
+	var page store.Page[Manga]
+	api.expect(http.StatusOK, "GET", "/filter?genres[]=thriller", nil, nil, &page)
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].Name != "Monster" {
+		t.Errorf("page = %+v, want only Monster", page)
+	}
+
+	var resp ErrorResponse
+	api.expect(http.StatusBadRequest, "GET", "/filter?orderField=id&perPage=x", nil, nil, &resp)
+	if resp.Code != "bad_request" || resp.Details["perPage"] == "" {
+		t.Errorf("error = %+v, want bad_request on perPage", resp)
+	}
+}
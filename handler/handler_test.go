@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/middleware"
+	"github.com/chimas/GoProject/search"
+	"github.com/chimas/GoProject/store"
+)
+
+// flakyStore is a store.Memory whose manga lookups and read marks fail
+// with store.ErrUnavailable while down is set, as during a Postgres outage.
+// readMarks counts the calls to ReadChapters.
+type flakyStore struct {
+	*store.Memory
+	down      atomic.Bool
+	readMarks atomic.Int32
+}
+
+func (f *flakyStore) ByName(ctx context.Context, name string) (Manga, error) {
+	if f.down.Load() {
+		return Manga{}, store.ErrUnavailable
+	}
+	return f.Memory.ByName(ctx, name)
+}
+
+func (f *flakyStore) ReadChapters(ctx context.Context, userId, animeName string) ([]int, error) {
+	f.readMarks.Add(1)
+	if f.down.Load() {
+		return nil, store.ErrUnavailable
+	}
+	return f.Memory.ReadChapters(ctx, userId, animeName)
+}
+
+// testAPI routes requests to handlers backed by one store.Memory and one
+// LRU cache, the way main wires them.
+type testAPI struct {
+	t     *testing.T
+	store *flakyStore
+	cache *cache.LRU
+	mux   *http.ServeMux
+}
+
+var (
+	alice = middleware.AuthUser{Id: "alice", Email: "alice@example.com"}
+	admin = middleware.AuthUser{Id: "root", Email: "root@example.com", Roles: []string{"admin"}}
+)
+
+// testVocabulary is narrower than config.Default().Catalog so that the
+// tests show the admin API follows the configured one.
+var testVocabulary = Vocabulary{
+	Statuses: []string{"ongoing", "finished", "frozen"},
+	Genres:   []string{"action", "historical", "horror", "mystery", "seinen", "thriller"},
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	mem := &flakyStore{Memory: store.NewMemory()}
+	for _, m := range []Manga{
+		{Id: 1, Name: "Berserk", Author: "Kentaro Miura", Popularity: 10, Genres: []string{"action", "horror"}, Status: "ongoing", Country: "JP"},
+		{Id: 2, Name: "Monster", Author: "Naoki Urasawa", Popularity: 20, Genres: []string{"thriller"}, Status: "finished", Country: "JP"},
+	} {
+		mem.AddManga(m)
+	}
+	for _, c := range []Chapter{
+		{AnimeName: "Berserk", Chapter: 2, Img: []string{"p1", "p2"}},
+		{AnimeName: "Berserk", Chapter: 1, Img: []string{"p1", "p2", "p3"}},
+	} {
+		mem.AddChapter(c)
+	}
+	if err := mem.Create(context.Background(), User{Id: alice.Id, Email: alice.Email, Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := search.NewEngine(mem)
+	if err := index.Rebuild(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c := cache.NewLRU(100)
+	m := NewMangaHandler(mem, mem, index, c, DefaultCacheTTLs, nil)
+	u := NewUserHandler(mem, mem, mem, c, nil)
+	p := NewProgressHandler(mem, mem, nil)
+	rt := NewRatingHandler(mem, mem, c)
+	a := NewAdminHandler(mem, index, c, testVocabulary)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /mangas", m.Mangas)
+	mux.HandleFunc("GET /manga", m.Manga)
+	mux.HandleFunc("GET /manga/{name}/{chapter}", m.Chapter)
+	mux.HandleFunc("GET /manga/{name}/ratings", rt.Distribution)
+	mux.HandleFunc("GET /popular", m.Popular)
+	mux.HandleFunc("GET /filter", m.Filter)
+	mux.HandleFunc("GET /search", m.Search)
+	mux.HandleFunc("GET /user/me", u.GetUser)
+	mux.HandleFunc("DELETE /user/me", u.DeleteUser)
+	mux.HandleFunc("POST /user/create", u.CreateUserIfNotExists)
+	mux.HandleFunc("GET /user/me/favorite/one", u.IsUserFavorite)
+	mux.HandleFunc("POST /user/me/favorite/{id}", u.ToggleFavorite)
+	mux.HandleFunc("PUT /user/me/favorite/{id}", u.AddFavorite)
+	mux.HandleFunc("DELETE /user/me/favorite/{id}", u.RemoveFavorite)
+	mux.HandleFunc("GET /user/me/favorite/list", u.UserFavList)
+	mux.HandleFunc("PUT /user/me/progress/{name}", p.SaveProgress)
+	mux.HandleFunc("GET /user/me/progress", p.ContinueReading)
+	mux.HandleFunc("POST /user/me/read/{name}", p.MarkRead)
+	mux.HandleFunc("PUT /user/me/rating/{name}", rt.Rate)
+	mux.HandleFunc("DELETE /user/me/rating/{name}", rt.DeleteRating)
+	mux.HandleFunc("POST /admin/manga", a.CreateManga)
+	mux.HandleFunc("PUT /admin/manga/{name}", a.UpdateManga)
+	mux.HandleFunc("DELETE /admin/manga/{name}", a.DeleteManga)
+	mux.HandleFunc("POST /admin/manga/{name}/chapters", a.CreateChapter)
+	mux.HandleFunc("POST /admin/manga/{name}/chapters/renumber", a.RenumberChapters)
+	return &testAPI{t: t, store: mem, cache: c, mux: mux}
+}
+
+// do sends a request as user, anonymously when user is nil, through
+// Identify as main does, and decodes
+// the JSON response into out when out is not nil.
+func (api *testAPI) do(method, target string, user *middleware.AuthUser, body any, out any) int {
+	api.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			api.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if user != nil {
+		req = req.WithContext(middleware.WithUser(req.Context(), *user))
+	}
+	rec := httptest.NewRecorder()
+	Identify(api.store, api.mux).ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			api.t.Fatalf("%s %s: decode %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// expect is do that fails the test unless the response has status.
+func (api *testAPI) expect(status int, method, target string, user *middleware.AuthUser, body any, out any) {
+	api.t.Helper()
+	if got := api.do(method, target, user, body, out); got != status {
+		api.t.Fatalf("%s %s = %d, want %d", method, target, got, status)
+	}
+}
+
+// expireFreshness marks the cache entry under key as past its TTL but still
+// within the stale window, as if ttl had elapsed since it was stored.
+func expireFreshness(t *testing.T, c cache.Cache, key string) {
+	t.Helper()
+	ctx := context.Background()
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("cache get %s: %v", key, err)
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	entry.FreshUntil = time.Now().Add(-time.Second)
+	if data, err = json.Marshal(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, key, data, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+}
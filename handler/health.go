@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/chimas/GoProject/cache"
+)
+
+// checkTimeout bounds each dependency ping of /readyz and /status.
+const checkTimeout = 2 * time.Second
+
+// Check is a dependency of the service. Requests cannot be served without
+// it unless it is Optional, in which case the service only runs degraded.
+type Check struct {
+	Name     string
+	Ping     func(ctx context.Context) error
+	Optional bool
+}
+
+// NewHealthHandler reports on checks. dbStats and cacheStats feed /status
+// and may be nil when there is no pool or cache to describe.
+func NewHealthHandler(version string, checks []Check, dbStats func() sql.DBStats, cacheStats func() cache.Stats) *HealthHandler {
+	return &HealthHandler{version: version, started: time.Now(), checks: checks, dbStats: dbStats, cacheStats: cacheStats}
+}
+
+// HealthHandler serves the liveness, readiness and status endpoints. /status
+// shows dependency errors and must be wrapped in
+// middleware.Authenticator.RequireRole("admin", ...).
+type HealthHandler struct {
+	version    string
+	started    time.Time
+	checks     []Check
+	dbStats    func() sql.DBStats
+	cacheStats func() cache.Stats
+}
+
+type CheckResult struct {
+	// Status is "ok" or "down".
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	// Status is "ok", "degraded" when only optional checks are down, or
+	// "unavailable" when a required one is.
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type PoolStatus struct {
+	MaxOpen           int     `json:"maxOpen"`
+	Open              int     `json:"open"`
+	InUse             int     `json:"inUse"`
+	Idle              int     `json:"idle"`
+	WaitCount         int64   `json:"waitCount"`
+	WaitMs            float64 `json:"waitMs"`
+	MaxIdleClosed     int64   `json:"maxIdleClosed"`
+	MaxLifetimeClosed int64   `json:"maxLifetimeClosed"`
+	MaxIdleTimeClosed int64   `json:"maxIdleTimeClosed"`
+}
+
+type CacheStatus struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Errors   uint64  `json:"errors"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+type StatusResponse struct {
+	HealthResponse
+	Version       string       `json:"version"`
+	StartedAt     time.Time    `json:"startedAt"`
+	UptimeSeconds int64        `json:"uptimeSeconds"`
+	Goroutines    int          `json:"goroutines"`
+	Database      *PoolStatus  `json:"database,omitempty"`
+	Cache         *CacheStatus `json:"cache,omitempty"`
+}
+
+// @Summary Liveness
+// @Description Answers as long as the process serves HTTP, without looking at dependencies
+// @Tags Health
+// @ID healthz
+// @Produce  json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// @Summary Readiness
+// @Description Pings Postgres and Redis, when configured, with a timeout each. Postgres failing answers 503 so the instance is taken out of rotation, unless cache.ttl.stale is set: cached responses are then served stale and the instance only reports "degraded". Redis failing only reports "degraded", the cache then runs in process.
+// @Tags Health
+// @ID readyz
+// @Produce  json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	health := h.check(r.Context())
+	for name, result := range health.Checks {
+		result.Error = ""
+		health.Checks[name] = result
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, health.httpStatus(), health)
+}
+
+// @Summary Service status
+// @Description Dependency checks with their errors, connection pool stats, cache hit ratio, build version and uptime
+// @Tags Health
+// @ID status
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} StatusResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /status [get]
+func (h *HealthHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status := StatusResponse{
+		HealthResponse: h.check(r.Context()),
+		Version:        h.version,
+		StartedAt:      h.started.UTC(),
+		UptimeSeconds:  int64(time.Since(h.started).Seconds()),
+		Goroutines:     runtime.NumGoroutine(),
+	}
+	if h.dbStats != nil {
+		s := h.dbStats()
+		status.Database = &PoolStatus{
+			MaxOpen:           s.MaxOpenConnections,
+			Open:              s.OpenConnections,
+			InUse:             s.InUse,
+			Idle:              s.Idle,
+			WaitCount:         s.WaitCount,
+			WaitMs:            milliseconds(s.WaitDuration),
+			MaxIdleClosed:     s.MaxIdleClosed,
+			MaxLifetimeClosed: s.MaxLifetimeClosed,
+			MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		}
+	}
+	if h.cacheStats != nil {
+		s := h.cacheStats()
+		status.Cache = &CacheStatus{Hits: s.Hits, Misses: s.Misses, Errors: s.Errors, HitRatio: s.HitRatio()}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, status)
+}
+
+// check pings every dependency concurrently.
+func (h *HealthHandler) check(ctx context.Context) HealthResponse {
+	health := HealthResponse{Status: "ok", Checks: make(map[string]CheckResult, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.Ping(ctx)
+			result := CheckResult{Status: "ok", LatencyMs: milliseconds(time.Since(start))}
+			if err != nil {
+				result.Status = "down"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			health.Checks[c.Name] = result
+			switch {
+			case err == nil:
+			case !c.Optional:
+				health.Status = "unavailable"
+			case health.Status == "ok":
+				health.Status = "degraded"
+			}
+		}(c)
+	}
+	wg.Wait()
+	return health
+}
+
+func (h HealthResponse) httpStatus() int {
+	if h.Status == "unavailable" {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
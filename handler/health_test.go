@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name   string
+		checks []Check
+		status int
+		want   string
+	}{
+		{"all up", []Check{{Name: "postgres", Ping: up}, {Name: "redis", Ping: up, Optional: true}}, http.StatusOK, "ok"},
+		{"optional down", []Check{{Name: "postgres", Ping: up}, {Name: "redis", Ping: down, Optional: true}}, http.StatusOK, "degraded"},
+		{"required down", []Check{{Name: "postgres", Ping: down}, {Name: "redis", Ping: down, Optional: true}}, http.StatusServiceUnavailable, "unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler("test", tt.checks, nil, nil)
+			rec := httptest.NewRecorder()
+			h.Readyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var health HealthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+				t.Fatal(err)
+			}
+			if health.Status != tt.want {
+				t.Errorf("health = %q, want %q", health.Status, tt.want)
+			}
+			for name, result := range health.Checks {
+				if result.Error != "" {
+					t.Errorf("readyz leaks the %s error %q", name, result.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	h := NewHealthHandler("v1.2.3", []Check{{Name: "postgres", Ping: func(ctx context.Context) error { return errors.New("timeout") }}}, nil, nil)
+	rec := httptest.NewRecorder()
+	h.Status(rec, httptest.NewRequest("GET", "/status", nil))
+
+	var status StatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != "v1.2.3" || status.Status != "unavailable" || status.Checks["postgres"].Error != "timeout" {
+		t.Errorf("status = %+v, want the version and the postgres error", status)
+	}
+}
@@ -0,0 +1,384 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/store"
+	"github.com/lib/pq"
+)
+
+// maxPages bounds a single chapter page upload.
+const maxPages = 200
+
+func NewImageHandler(uploader *images.Uploader, resizer *images.Resizer, mangas store.MangaStore, catalog store.CatalogStore, c cache.Cache) *ImageHandler {
+	return &ImageHandler{uploader: uploader, resizer: resizer, mangas: mangas, catalog: catalog, cache: cache.WithNamespace(c, mangaNamespace)}
+}
+
+// ImageHandler uploads images and serves them, resized on request. The upload routes must be wrapped
+// in middleware.Authenticator.RequireRole("admin", ...).
+type ImageHandler struct {
+	uploader *images.Uploader
+	resizer  *images.Resizer
+	mangas   store.MangaStore
+	catalog  store.CatalogStore
+	cache    cache.Cache
+}
+
+// upload stores every file part of a multipart body, in order, and stops at
+// limit files. Non-file parts are skipped.
+func (h *ImageHandler) upload(r *http.Request, w http.ResponseWriter, limit int) ([]images.Image, error) {
+	allowLongTransfer(w)
+	r.Body = http.MaxBytesReader(w, r.Body, int64(limit)*(h.uploader.MaxSize()+64<<10))
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, badRequest("expected a multipart/form-data body", map[string]string{"body": err.Error()})
+	}
+
+	uploaded := []images.Image{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, uploadError(err, len(uploaded))
+		}
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		if len(uploaded) == limit {
+			part.Close()
+			return nil, badRequest("too many files", map[string]string{"files": "at most " + strconv.Itoa(limit)})
+		}
+		img, err := h.uploader.Upload(r.Context(), part)
+		part.Close()
+		if err != nil {
+			return nil, uploadError(err, len(uploaded))
+		}
+		uploaded = append(uploaded, img)
+	}
+	if len(uploaded) == 0 {
+		return nil, badRequest("no files uploaded", map[string]string{"files": "attach at least one file part"})
+	}
+	return uploaded, nil
+}
+
+// uploadError names the file that failed when the error is about its content.
+func uploadError(err error, index int) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, images.ErrTooLarge), errors.As(err, &tooLarge):
+		return &APIError{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: "image is too large",
+			Details: map[string]string{"files[" + strconv.Itoa(index) + "]": "larger than the size limit"}}
+	case errors.Is(err, images.ErrUnsupportedType):
+		return &APIError{Status: http.StatusUnsupportedMediaType, Code: "unsupported_type", Message: "unsupported image type",
+			Details: map[string]string{"files[" + strconv.Itoa(index) + "]": "must be JPEG, PNG, GIF, WebP or AVIF"}}
+	case errors.Is(err, multipart.ErrMessageTooLarge):
+		return badRequest("invalid multipart body", map[string]string{"body": err.Error()})
+	}
+	return err
+}
+
+// @Summary Upload images
+// @Description Store images without attaching them anywhere. Identical files are stored once. Each file is limited in size and must be JPEG, PNG, GIF, WebP or AVIF.
+// @Tags Admin
+// @ID admin-upload-images
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param  files formData file true "Images, any number of file parts"
+// @Success 201 {array} images.Image
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Router /admin/images [post]
+func (h *ImageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	uploaded, err := h.upload(r, w, maxPages)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, uploaded)
+}
+
+// @Summary Upload a manga cover or header
+// @Description Store an image and make it the cover (kind=cover) or header image (kind=header) of a manga
+// @Tags Admin
+// @ID admin-upload-manga-image
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  kind path string true "cover or header"
+// @Param  file formData file true "Image"
+// @Success 200 {object} MangaSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/images/{kind} [post]
+func (h *ImageHandler) MangaImage(w http.ResponseWriter, r *http.Request) {
+	name, kind := r.PathValue("name"), r.PathValue("kind")
+	if kind != "cover" && kind != "header" {
+		writeError(w, r, badRequest("invalid image kind", map[string]string{"kind": "must be cover or header"}))
+		return
+	}
+	manga, err := h.mangas.ByName(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uploaded, err := h.upload(r, w, 1)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if kind == "cover" {
+		manga.Img = uploaded[0].URL
+	} else {
+		manga.ImgHeader = uploaded[0].URL
+	}
+
+	manga, err = h.catalog.UpdateManga(r.Context(), name, manga)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	invalidateManga(r.Context(), h.cache, name)
+
+	writeJSON(w, http.StatusOK, manga)
+}
+
+// @Summary Upload chapter pages
+// @Description Store pages in the order of the file parts and append them to a chapter, or replace its pages with mode=replace
+// @Tags Admin
+// @ID admin-upload-pages
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  chapter path int true "Chapter number"
+// @Param  mode query string false "append (default) or replace"
+// @Param  files formData file true "Pages, any number of file parts"
+// @Success 200 {object} ChapterSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters/{chapter}/pages [post]
+func (h *ImageHandler) ChapterPages(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	number, err := chapterNumber(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "append" && mode != "replace" {
+		writeError(w, r, badRequest("invalid mode", map[string]string{"mode": "must be append or replace"}))
+		return
+	}
+	chapter, err := h.mangas.Chapter(r.Context(), name, number)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uploaded, err := h.upload(r, w, maxPages)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	pages := pq.StringArray{}
+	if mode != "replace" {
+		pages = append(pages, chapter.Img...)
+	}
+	for _, img := range uploaded {
+		pages = append(pages, img.URL)
+	}
+	chapter.Img = pages
+
+	chapter, err = h.catalog.UpdateChapter(r.Context(), name, number, chapter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	invalidateManga(r.Context(), h.cache, name)
+
+	writeJSON(w, http.StatusOK, chapter)
+}
+
+// @Summary Get an image
+// @Description Serve an uploaded image. With w the image is resized to that width, which must be 160, 320, 640, 960 or 1280, and re-encoded at quality q (1 to 100, default 80, rounded to the nearest of 50, 65, 80 and 95) as AVIF or WebP when the Accept header allows it and the server can encode it, else as JPEG or PNG. Keys are content hashes, so responses never change and are cached for a year.
+// @Tags Images
+// @ID get-image
+// @Produce  image/jpeg,image/png,image/gif,image/webp,image/avif
+// @Param  key path string true "Image key"
+// @Param  w query int false "Width to resize to"
+// @Param  q query int false "Quality of the resized image"
+// @Success 200 {file} file
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /images/{key} [get]
+func (h *ImageHandler) Image(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if !images.ValidKey(key) {
+		writeError(w, r, notFound("image not found"))
+		return
+	}
+	query := r.URL.Query()
+	if query.Has("w") || query.Has("q") {
+		if served := h.variant(w, r, key); served {
+			return
+		}
+	}
+
+	// The key already is the content hash.
+	etag := `"` + strings.TrimSuffix(path.Base(key), path.Ext(key)) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rc, obj, err := h.uploader.Open(r.Context(), key)
+	if errors.Is(err, images.ErrNotFound) {
+		writeError(w, r, notFound("image not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", images.ContentTypeOf(key))
+	w.Header().Set("Cache-Control", immutable)
+	w.Header().Set("ETag", etag)
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, rs)
+		return
+	}
+	if obj.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	io.Copy(w, rc)
+}
+
+const immutable = "public, max-age=31536000, immutable"
+
+// variant serves a resized image and reports whether it did. Images that
+// cannot be resized, such as AVIF, are left to the caller to serve as is.
+func (h *ImageHandler) variant(w http.ResponseWriter, r *http.Request, key string) bool {
+	query := r.URL.Query()
+	width, err := strconv.Atoi(query.Get("w"))
+	if err != nil || !slices.Contains(images.Widths, width) {
+		writeError(w, r, badRequest("invalid width", map[string]string{"w": "must be one of " + joinInts(images.Widths)}))
+		return true
+	}
+	quality := images.DefaultQuality
+	if v := query.Get("q"); v != "" {
+		quality, err = strconv.Atoi(v)
+		if err != nil || quality < 1 || quality > 100 {
+			writeError(w, r, badRequest("invalid quality", map[string]string{"q": "must be between 1 and 100"}))
+			return true
+		}
+		quality = images.SnapQuality(quality)
+	}
+
+	contentType := h.resizer.Negotiate(r.Header.Get("Accept"), images.ContentTypeOf(key))
+	// The format depends on Accept, shared caches must keep one copy per
+	// format.
+	w.Header().Add("Vary", "Accept")
+	v := h.resizer.VariantOf(key, width, quality, contentType)
+	if r.Header.Get("If-None-Match") == v.ETag {
+		w.Header().Set("Cache-Control", immutable)
+		w.Header().Set("ETag", v.ETag)
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	v, err = h.resizer.Variant(r.Context(), key, width, quality, contentType)
+	switch {
+	case errors.Is(err, images.ErrUnsupportedType):
+		return false
+	case errors.Is(err, images.ErrNotFound):
+		writeError(w, r, notFound("image not found"))
+		return true
+	case errors.Is(err, images.ErrTooLarge):
+		writeError(w, r, &APIError{Status: http.StatusUnprocessableEntity, Code: "too_large", Message: "image is too large to resize"})
+		return true
+	case err != nil:
+		writeError(w, r, err)
+		return true
+	}
+
+	f, err := os.Open(v.Path)
+	if err != nil {
+		writeError(w, r, err)
+		return true
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", v.ContentType)
+	w.Header().Set("Cache-Control", immutable)
+	w.Header().Set("ETag", v.ETag)
+	http.ServeContent(w, r, "", time.Time{}, f)
+	return true
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// withThumbnails fills in Manga.Thumbnail and the page thumbnails of the
+// chapters of every manga.
+func withThumbnails(t *images.Thumbnails, mangas []Manga) {
+	for i := range mangas {
+		withMangaThumbnails(t, &mangas[i])
+	}
+}
+
+func withMangaThumbnails(t *images.Thumbnails, manga *Manga) {
+	manga.Thumbnail = t.URL(manga.Img, images.ThumbnailWidth)
+	for i := range manga.Chapters {
+		withPageThumbnails(t, &manga.Chapters[i])
+	}
+}
+
+// withPageThumbnails fills in Chapter.Thumbnails, leaving it empty when no
+// page was uploaded.
+func withPageThumbnails(t *images.Thumbnails, chapter *Chapter) {
+	chapter.Thumbnails = nil
+	thumbs := make([]string, len(chapter.Img))
+	for i, img := range chapter.Img {
+		thumbs[i] = t.URL(img, images.ThumbnailWidth)
+		if thumbs[i] != "" {
+			chapter.Thumbnails = thumbs
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/comic"
+	"github.com/chimas/GoProject/images"
+)
+
+func NewImportHandler(importer *comic.Importer, c cache.Cache, maxArchiveSize int64) *ImportHandler {
+	return &ImportHandler{importer: importer, cache: cache.WithNamespace(c, mangaNamespace), maxArchiveSize: maxArchiveSize}
+}
+
+// ImportHandler creates chapters from uploaded CBZ and ZIP archives. Every
+// route must be wrapped in middleware.Authenticator.RequireRole("admin", ...).
+type ImportHandler struct {
+	importer       *comic.Importer
+	cache          cache.Cache
+	maxArchiveSize int64
+}
+
+// maxFieldSize bounds the plain form fields of an import.
+const maxFieldSize = 1 << 10
+
+// @Summary Import a chapter archive
+// @Description Create a chapter from a CBZ or ZIP archive of page images, ordered naturally by file name (2 before 10). The number and name come from the form fields, else from ComicInfo.xml (uploaded as comicinfo or inside the archive), else the number from the archive name. The release date in ComicInfo.xml becomes createdAt.
+// @Tags Admin
+// @ID admin-import-chapter
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  archive formData file true "CBZ or ZIP archive"
+// @Param  comicinfo formData file false "ComicInfo.xml, overrides the one in the archive"
+// @Param  chapter formData int false "Chapter number"
+// @Param  title formData string false "Chapter name"
+// @Param  replace formData bool false "Replace the pages and name of an existing chapter"
+// @Success 201 {object} ImportResultSwag
+// @Success 200 {object} ImportResultSwag "An existing chapter was replaced"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/manga/{name}/chapters/import [post]
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	opts := comic.Options{Manga: r.PathValue("name")}
+	allowLongTransfer(w)
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxArchiveSize+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, badRequest("expected a multipart/form-data body", map[string]string{"body": err.Error()}))
+		return
+	}
+
+	// zip needs random access, the archive is spooled to disk rather than
+	// held in memory.
+	var archive *os.File
+	var filename string
+	defer func() {
+		if archive != nil {
+			archive.Close()
+			os.Remove(archive.Name())
+		}
+	}()
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, r, importError(err))
+			return
+		}
+		switch part.FormName() {
+		case "archive":
+			if archive != nil {
+				err = badRequest("only one archive per request", map[string]string{"archive": "listed twice"})
+				break
+			}
+			filename = part.FileName()
+			archive, err = os.CreateTemp("", "import-*.zip")
+			if err == nil {
+				_, err = io.Copy(archive, part)
+			}
+		case "comicinfo":
+			var info comic.ComicInfo
+			info, err = comic.ParseComicInfo(part)
+			if err != nil {
+				err = badRequest("invalid ComicInfo.xml", map[string]string{"comicinfo": err.Error()})
+			}
+			opts.ComicInfo = &info
+		case "chapter", "title", "replace":
+			err = h.field(part, &opts)
+		}
+		part.Close()
+		if err != nil {
+			writeError(w, r, importError(err))
+			return
+		}
+	}
+	if archive == nil {
+		writeError(w, r, badRequest("archive is required", map[string]string{"archive": "attach a CBZ or ZIP file"}))
+		return
+	}
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.importer.Import(r.Context(), archive, size, filename, opts)
+	if err != nil {
+		writeError(w, r, importError(err))
+		return
+	}
+	invalidateManga(r.Context(), h.cache, opts.Manga)
+
+	status := http.StatusCreated
+	if result.Replaced {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, result)
+}
+
+// field reads one plain form field into opts.
+func (h *ImportHandler) field(part *multipart.Part, opts *comic.Options) error {
+	name := part.FormName()
+	data, err := io.ReadAll(io.LimitReader(part, maxFieldSize))
+	if err != nil {
+		return err
+	}
+	value := strings.TrimSpace(string(data))
+	switch name {
+	case "chapter":
+		opts.Chapter, err = strconv.Atoi(value)
+		if err != nil || opts.Chapter < 1 {
+			return badRequest("invalid chapter", map[string]string{"chapter": "must be a positive number"})
+		}
+	case "title":
+		opts.Name = value
+	case "replace":
+		opts.Replace, err = strconv.ParseBool(value)
+		if err != nil {
+			return badRequest("invalid replace", map[string]string{"replace": "must be true or false"})
+		}
+	}
+	return nil
+}
+
+// importError maps importer errors to client errors, anything else goes
+// through writeError as usual.
+func importError(err error) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return &APIError{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: "archive is too large"}
+	case errors.Is(err, images.ErrTooLarge):
+		return &APIError{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: err.Error()}
+	case errors.Is(err, comic.ErrNoPages), errors.Is(err, comic.ErrTooManyPages), errors.Is(err, comic.ErrNoChapterNumber):
+		return badRequest(err.Error(), nil)
+	case errors.Is(err, comic.ErrInvalidArchive):
+		return badRequest("archive is not a valid ZIP file", map[string]string{"archive": err.Error()})
+	}
+	return err
+}
@@ -2,52 +2,36 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/go-redis/redis/v9"
-	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/store"
+	"github.com/gorilla/schema"
 )
 
-func NewMangaHandler(db *sqlx.DB, rdb *redis.Client) *MangaHandler {
-	return &MangaHandler{db: db, rdb: rdb}
+func NewMangaHandler(mangas store.MangaStore, progress store.ProgressStore, search store.SearchIndex, c cache.Cache, ttl CacheTTLs, thumbs *images.Thumbnails) *MangaHandler {
+	return &MangaHandler{mangas: mangas, progress: progress, search: search, cache: cache.WithNamespace(c, mangaNamespace), ttl: ttl, thumbs: thumbs}
 }
 
+// MangaHandler serves the public manga endpoints. Manga and Chapter may be
+// wrapped in middleware.Authenticator.Optional and Identify to get per-user
+// read flags.
 type MangaHandler struct {
-	db  *sqlx.DB
-	rdb *redis.Client
+	mangas   store.MangaStore
+	progress store.ProgressStore
+	search   store.SearchIndex
+	cache    cache.Cache
+	ttl      CacheTTLs
+	thumbs   *images.Thumbnails
 }
 
-type Manga struct {
-	Name          string         `json:"name"`
-	Img           string         `json:"img"`
-	ImgHeader     string         `json:"imgHeader" db:"imgHeader"`
-	Describe      string         `json:"describe"`
-	Genres        pq.StringArray `json:"genres" db:"genres"`
-	Author        string         `json:"author"`
-	Country       string         `json:"country"`
-	Published     int            `json:"published"`
-	AverageRating float64        `json:"averageRating" db:"averageRating"`
-	RatingCount   int            `json:"ratingCount" db:"ratingCount"`
-	Status        string         `json:"status"`
-	Popularity    int            `json:"popularity"`
-	Id            int            `json:"id"`
-	Chapters      []Chapter      `json:"chapters"`
-}
+type Manga = store.Manga
 
-type Chapter struct {
-	Chapter   int            `json:"chapter"`
-	Img       pq.StringArray `json:"img" db:"img"`
-	Name      string         `json:"name"`
-	AnimeName string         `json:"animeName" db:"animeName"`
-	CreatedAt time.Time      `json:"createdAt" db:"createdAt"`
-}
+type Chapter = store.Chapter
 
 // @Summary Get all mangas
 // @Description Retrieve a list of all mangas
@@ -55,21 +39,35 @@ type Chapter struct {
 // @ID get-all-mangas
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} MangaSwag
+// @Param  page query int false "page, starting at 1"
+// @Param  perPage query int false "perPage"
+// @Param  cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} MangaPageSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /mangas [get]
 func (m *MangaHandler) Mangas(w http.ResponseWriter, r *http.Request) {
-
-	query := `SELECT * FROM "Anime"`
-	var mangas []Manga
-	err := m.db.Select(&mangas, query)
+	query := r.URL.Query()
+	filter, err := pageFilter(query, defaultPerPage)
 	if err != nil {
-		log.Fatal(err)
+		writeError(w, r, err)
+		return
 	}
+	filter.Sort = store.SortPopularity
+	filter.Desc = true
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(mangas); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	key := "mangas:" + query.Encode()
+	mangas, err := cached(r.Context(), m.cache, key, m.ttl.Mangas, m.ttl.Stale, []string{tagMangas}, func(ctx context.Context) (store.Page[Manga], error) {
+		return m.mangas.Filter(ctx, filter)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	withThumbnails(m.thumbs, mangas.Items)
+
+	writeJSON(w, http.StatusOK, mangas)
 }
 
 // @Summary Get a manga by name
@@ -80,57 +78,39 @@ func (m *MangaHandler) Mangas(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param  name query string true "Name of the Manga"
 // @Success 200 {object} MangaSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /manga [get]
 func (m *MangaHandler) Manga(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
 	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, r, badRequest("name is required", map[string]string{"name": "required"}))
+		return
+	}
 
-	val, err := m.rdb.Get(ctx, name).Result()
-	if err == redis.Nil {
-
-		query := `SELECT * FROM "Anime" WHERE name=$1`
-		chaptersQuery := `SELECT * FROM "Chapter" WHERE "animeName" =$1`
-		var manga Manga
-		err := m.db.Get(&manga, query, name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		var chapters []Chapter
-		err = m.db.Select(&chapters, chaptersQuery, name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-
-		manga.Chapters = chapters
-
-		mangaJSON, err := json.Marshal(manga)
+	manga, stale, err := cachedOrStale(r.Context(), m.cache, "manga:"+name, m.ttl.Manga, m.ttl.Stale, []string{mangaTag(name)}, func(ctx context.Context) (Manga, error) {
+		manga, err := m.mangas.ByName(ctx, name)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return manga, err
 		}
-
-		err = m.rdb.Set(ctx, name, mangaJSON, time.Minute).Err()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		manga.Chapters, err = m.mangas.Chapters(ctx, name)
+		return manga, err
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !stale {
+		if err := markRead(r, m.progress, name, manga.Chapters); err != nil {
+			writeError(w, r, err)
+			return
 		}
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(manga); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	} else if err != nil {
-		log.Fatal(err)
-	} else {
-		manga := Manga{}
-		err := json.Unmarshal([]byte(val), &manga)
-		if err != nil {
-			log.Fatal(err)
-		}
+	withMangaThumbnails(m.thumbs, &manga)
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(manga); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	}
+	writeJSON(w, http.StatusOK, manga)
 }
 
 // @Summary Get a chapter
@@ -142,157 +122,262 @@ func (m *MangaHandler) Manga(w http.ResponseWriter, r *http.Request) {
 // @Param  name path string true "Name of the Manga"
 // @Param  chapter path string true "Chapter of the Manga"
 // @Success 200 {object} ChapterSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /manga/{name}/{chapter} [get]
 func (m *MangaHandler) Chapter(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
-	chapt := r.PathValue("chapter")
-
-	var chapter Chapter
-
-	query := `SELECT * FROM "Chapter" WHERE "animeName" =$1 AND chapter=$2`
-
-	err := m.db.Get(&chapter, query, name, chapt)
+	chapt, err := strconv.Atoi(r.PathValue("chapter"))
 	if err != nil {
-		log.Fatal(err)
+		writeError(w, r, badRequest("chapter must be a number", map[string]string{"chapter": r.PathValue("chapter")}))
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(chapter); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	key := "chapter:" + name + ":" + strconv.Itoa(chapt)
+	chapter, stale, err := cachedOrStale(r.Context(), m.cache, key, m.ttl.Chapter, m.ttl.Stale, []string{mangaTag(name)}, func(ctx context.Context) (Chapter, error) {
+		return m.mangas.Chapter(ctx, name, chapt)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+	if !stale {
+		chapters := []Chapter{chapter}
+		if err := markRead(r, m.progress, name, chapters); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		chapter = chapters[0]
+	}
+
+	withPageThumbnails(m.thumbs, &chapter)
+
+	writeJSON(w, http.StatusOK, chapter)
 }
 
+const popularLimit = 14
+
 // @Summary Get popular mangas
 // @Description Retrieve a list of popular mangas
 // @Tags Manga
 // @ID get-popular-manga
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} MangaSwag
+// @Param  page query int false "page, starting at 1"
+// @Param  perPage query int false "perPage"
+// @Success 200 {object} MangaPageSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /popular [get]
 func (m *MangaHandler) Popular(w http.ResponseWriter, r *http.Request) {
-
-	query := `SELECT * FROM "Anime" ORDER BY "ratingCount" DESC LIMIT 14 `
-	var animes []Manga
-	err := m.db.Select(&animes, query)
+	query := r.URL.Query()
+	query.Del("cursor")
+	filter, err := pageFilter(query, popularLimit)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(animes); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
-}
-func (m *MangaHandler) Search(w http.ResponseWriter, r *http.Request) {
+	filter.Sort = store.SortRatingCount
+	filter.Desc = true
 
-	query := `SELECT * FROM "Anime"`
-	var animes []Manga
-	err := m.db.Select(&animes, query)
+	key := "popular:" + query.Encode()
+	animes, err := cached(r.Context(), m.cache, key, m.ttl.Popular, m.ttl.Stale, []string{tagMangas}, func(ctx context.Context) (store.Page[Manga], error) {
+		return m.mangas.Filter(ctx, filter)
+	})
 	if err != nil {
-		log.Fatal(err)
+		writeError(w, r, err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(animes); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	withThumbnails(m.thumbs, animes.Items)
+
+	writeJSON(w, http.StatusOK, animes)
 }
 
+// FilterParams are the query parameters of GET /filter. Array parameters
+// may be repeated, with or without a "[]" suffix.
 type FilterParams struct {
-	Name       string   `schema:"name"`
-	Genres     []string `schema:"genres"`
-	Status     string   `schema:"status"`
-	Country    string   `schema:"country"`
-	OrderField string   `schema:"orderField"`
-	OrderSort  string   `schema:"orderSort"`
-	Page       int      `schema:"page"`
-	PerPage    int      `schema:"perPage"`
+	Name          string   `schema:"name"`
+	Genres        []string `schema:"genres"`
+	GenreMatch    string   `schema:"genreMatch"`
+	ExcludeGenres []string `schema:"excludeGenres"`
+	Status        []string `schema:"status"`
+	Country       []string `schema:"country"`
+	PublishedFrom int      `schema:"publishedFrom"`
+	PublishedTo   int      `schema:"publishedTo"`
+	RatingMin     float64  `schema:"ratingMin"`
+	OrderField    string   `schema:"orderField"`
+	OrderSort     string   `schema:"orderSort"`
+	Page          int      `schema:"page"`
+	PerPage       int      `schema:"perPage"`
+	Cursor        string   `schema:"cursor"`
 }
 
-// @Summary Get a chapter
-// @Description Find Manga Chapter
-// @Tags Manga
-// @ID Filter-anime
-// @Accept  json
-// @Produce  json
-// @Param  name query string false "Name of the Manga"
-// @Param  genres query []string false "Chapter of the Manga"
-// @Param  status query string false "Name of the Manga"
-// @Param  country query string false "Chapter of the Manga"
-// @Param  orderField query string false "field of the Manga"
-// @Param  orderSort query string false "sort of the Manga"
-// @Param  page query int false "page not 0"
-// @Param  perPage query int false "perPage"
-// @Success 200 {array} MangaSwag
-// @Router /filter [get]
-func (m *MangaHandler) Filter(w http.ResponseWriter, r *http.Request) {
-	params := r.URL.Query()
-	name := params.Get("name")
-	genres := params["genres[]"]
-	status := params.Get("status")
-	country := params.Get("country")
-	orderField := params.Get("orderField")
-	orderSort := params.Get("orderSort")
-
-	page, err := strconv.Atoi(params.Get("page"))
-	if err != nil {
-		log.Println("not have page")
-	}
-	perPage, err := strconv.Atoi(params.Get("perPage"))
-	if err != nil {
-		log.Println("not have perPage")
+const maxPerPage = 100
+
+var filterDecoder = func() *schema.Decoder {
+	d := schema.NewDecoder()
+	d.IgnoreUnknownKeys(true)
+	return d
+}()
+
+// decodeFilterParams parses the query string into FilterParams, reporting
+// every malformed parameter at once.
+func decodeFilterParams(query url.Values) (FilterParams, error) {
+	src := map[string][]string{}
+	for key, values := range query {
+		key = strings.TrimSuffix(key, "[]")
+		for _, v := range values {
+			if v != "" {
+				src[key] = append(src[key], v)
+			}
+		}
 	}
 
-	var mangas []Manga
-	query := `SELECT * FROM "Anime"`
-	args := []interface{}{}
-	i := 1
+	var params FilterParams
+	details := map[string]string{}
+	if err := filterDecoder.Decode(&params, src); err != nil {
+		if multi, ok := err.(schema.MultiError); ok {
+			for key := range multi {
+				details[key] = "invalid value"
+			}
+		} else {
+			return params, badRequest(err.Error(), nil)
+		}
+	}
+	if len(details) > 0 {
+		return params, badRequest("invalid filter parameters", details)
+	}
+	return params, nil
+}
 
-	if name != "" || status != "" || country != "" || (len(genres) > 0 && genres[0] != "") {
-		query += " WHERE"
+// toFilter validates p against the whitelisted sort fields, directions and
+// ranges accepted by the store.
+func (p FilterParams) toFilter() (store.MangaFilter, error) {
+	details := map[string]string{}
+	f := store.MangaFilter{
+		Name:          p.Name,
+		Genres:        p.Genres,
+		GenreMatch:    store.GenreMatchAll,
+		ExcludeGenres: p.ExcludeGenres,
+		Statuses:      p.Status,
+		Countries:     p.Country,
+		PublishedFrom: p.PublishedFrom,
+		PublishedTo:   p.PublishedTo,
+		RatingMin:     p.RatingMin,
+		Page:          p.Page,
+		PerPage:       p.PerPage,
+	}
+	if f.Page == 0 {
+		f.Page = 1
+	}
+	if f.PerPage == 0 {
+		f.PerPage = defaultPerPage
 	}
 
-	if name != "" {
-		name = "%" + name + "%"
-		query += fmt.Sprintf(` "name" ILIKE $%d AND`, i)
-		args = append(args, name)
-		i++
+	switch p.GenreMatch {
+	case "", string(store.GenreMatchAll):
+	case string(store.GenreMatchAny):
+		f.GenreMatch = store.GenreMatchAny
+	default:
+		details["genreMatch"] = "must be all or any"
 	}
-	if status != "" {
-		query += fmt.Sprintf(` "status" = $%d AND`, i)
-		args = append(args, status)
-		i++
+
+	if p.OrderField != "" {
+		field, ok := store.ParseSortField(p.OrderField)
+		if !ok {
+			details["orderField"] = "must be one of popularity, averageRating, ratingCount, published, name, createdAt"
+		}
+		f.Sort = field
 	}
-	if country != "" {
-		query += fmt.Sprintf(` "country" = $%d AND`, i)
-		args = append(args, country)
-		i++
+	switch strings.ToLower(p.OrderSort) {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		details["orderSort"] = "must be asc or desc"
 	}
-	if len(genres) > 0 && genres[0] != "" {
-		for _, genre := range genres {
-			query += fmt.Sprintf(` "genres" @> ARRAY[$%d] AND`, i)
-			args = append(args, genre)
-			i++
+
+	if p.Cursor != "" {
+		cursor, err := store.ParseCursor(p.Cursor)
+		if err != nil {
+			details["cursor"] = "invalid cursor"
+		}
+		if f.Sort != "" && f.Sort != store.SortPopularity {
+			details["cursor"] = "only allowed when ordering by popularity"
 		}
+		f.After = &cursor
+		f.Sort = store.SortPopularity
 	}
 
-	query = strings.TrimSuffix(query, "AND")
-	if orderField != "" && orderSort != "" {
-		query += fmt.Sprintf(` ORDER BY "%s" %s`, orderField, orderSort)
+	if p.PublishedFrom > 0 && p.PublishedTo > 0 && p.PublishedFrom > p.PublishedTo {
+		details["publishedFrom"] = "must not be after publishedTo"
 	}
-	if page > 0 && perPage > 0 {
-		query += fmt.Sprintf(` LIMIT %d OFFSET %d`, perPage, (page-1)*perPage)
+	if p.RatingMin < 0 {
+		details["ratingMin"] = "must not be negative"
+	}
+	if p.Page < 0 {
+		details["page"] = "must be a positive number"
+	}
+	if p.PerPage < 0 || p.PerPage > maxPerPage {
+		details["perPage"] = "must be between 1 and " + strconv.Itoa(maxPerPage)
 	}
 
-	log.Println("q", query)
-	err = m.db.Select(&mangas, query, args...)
+	if len(details) > 0 {
+		return f, badRequest("invalid filter parameters", details)
+	}
+	return f, nil
+}
+
+// @Summary Filter mangas
+// @Description Filter, sort and paginate mangas
+// @Tags Manga
+// @ID Filter-anime
+// @Accept  json
+// @Produce  json
+// @Param  name query string false "Part of the Manga name"
+// @Param  genres query []string false "Genres of the Manga"
+// @Param  genreMatch query string false "all (default) or any"
+// @Param  excludeGenres query []string false "Genres the Manga must not have"
+// @Param  status query []string false "Statuses of the Manga"
+// @Param  country query []string false "Countries of the Manga"
+// @Param  publishedFrom query int false "Published in or after this year"
+// @Param  publishedTo query int false "Published in or before this year"
+// @Param  ratingMin query number false "Minimal average rating"
+// @Param  orderField query string false "popularity, averageRating, ratingCount, published, name or createdAt"
+// @Param  orderSort query string false "asc or desc"
+// @Param  page query int false "page not 0"
+// @Param  perPage query int false "perPage"
+// @Param  cursor query string false "nextCursor of the previous page, needs orderField=popularity or none"
+// @Success 200 {object} MangaPageSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /filter [get]
+func (m *MangaHandler) Filter(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, err := decodeFilterParams(query)
 	if err != nil {
-		log.Fatal(err)
+		writeError(w, r, err)
+		return
+	}
+	filter, err := params.toFilter()
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(mangas); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Encode sorts by key, so equal queries share one cache entry.
+	key := "filter:" + query.Encode()
+	mangas, err := cached(r.Context(), m.cache, key, m.ttl.Filter, m.ttl.Stale, []string{tagMangas}, func(ctx context.Context) (store.Page[Manga], error) {
+		return m.mangas.Filter(ctx, filter)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	withThumbnails(m.thumbs, mangas.Items)
+
+	writeJSON(w, http.StatusOK, mangas)
 }
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestManga(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.expect(http.StatusBadRequest, "GET", "/manga", nil, nil, nil)
+
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, &manga)
+	if manga.Name != "Berserk" || len(manga.Chapters) != 2 || manga.Chapters[0].Chapter != 1 || manga.Chapters[1].Chapter != 2 {
+		t.Fatalf("manga = %+v, want Berserk with chapters 1 and 2", manga)
+	}
+}
+
+func TestChapter(t *testing.T) {
+	api := newTestAPI(t)
+
+	var chapter Chapter
+	api.expect(http.StatusOK, "GET", "/manga/Berserk/2", nil, nil, &chapter)
+	if chapter.Chapter != 2 || len(chapter.Img) != 2 {
+		t.Errorf("chapter = %+v, want chapter 2 with 2 pages", chapter)
+	}
+	api.expect(http.StatusBadRequest, "GET", "/manga/Berserk/two", nil, nil, nil)
+	api.expect(http.StatusNotFound, "GET", "/manga/Berserk/9", nil, nil, nil)
+	api.expect(http.StatusNotFound, "GET", "/manga/Vagabond/1", nil, nil, nil)
+}
+
+func TestMangaWithoutReadFlags(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "POST", "/user/me/read/Berserk", &alice, ReadRequest{Chapters: []int{1}, Read: true}, nil)
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, nil)
+
+	// A fresh entry is served, but the read marks are out of reach.
+	api.store.down.Store(true)
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", &alice, nil, &manga)
+	if got := readFlags(manga.Chapters); !reflect.DeepEqual(got, []bool{false, false}) {
+		t.Errorf("read flags = %v, want none while the store is down", got)
+	}
+}
+
+func TestMangaStale(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, nil)
+	expireFreshness(t, api.cache, mangaNamespace+":manga:Berserk")
+
+	api.store.down.Store(true)
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", &alice, nil, &manga)
+	if manga.Name != "Berserk" || len(manga.Chapters) != 2 {
+		t.Errorf("stale manga = %+v, want Berserk with 2 chapters", manga)
+	}
+	if n := api.store.readMarks.Load(); n != 0 {
+		t.Errorf("read marks queried %d times for a stale entry, want none", n)
+	}
+
+	// Without a cached entry the outage reaches the client.
+	api.expect(http.StatusServiceUnavailable, "GET", "/manga?name=Monster", nil, nil, nil)
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/chimas/GoProject/store"
+)
+
+const defaultPerPage = 20
+
+// pageFilter reads page, perPage and cursor from a list endpoint's query
+// string. perPage defaults to defPerPage.
+func pageFilter(query url.Values, defPerPage int) (store.MangaFilter, error) {
+	f := store.MangaFilter{Page: 1, PerPage: defPerPage}
+	details := map[string]string{}
+	if v := query.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			details["page"] = "must be a positive number"
+		}
+		f.Page = page
+	}
+	if v := query.Get("perPage"); v != "" {
+		perPage, err := strconv.Atoi(v)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			details["perPage"] = "must be between 1 and " + strconv.Itoa(maxPerPage)
+		}
+		f.PerPage = perPage
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := store.ParseCursor(v)
+		if err != nil {
+			details["cursor"] = "invalid cursor"
+		}
+		f.After = &cursor
+	}
+	if len(details) > 0 {
+		return f, badRequest("invalid pagination parameters", details)
+	}
+	return f, nil
+}
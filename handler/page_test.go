@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func TestPageFilter(t *testing.T) {
+	cursor := store.Cursor{Popularity: 20, Id: 2}
+	f, err := pageFilter(url.Values{"page": {"3"}, "cursor": {cursor.String()}}, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Page != 3 || f.PerPage != 14 || f.After == nil || *f.After != cursor {
+		t.Errorf("filter = %+v, want page 3 of 14 after %v", f, cursor)
+	}
+
+	_, err = pageFilter(url.Values{"page": {"0"}, "perPage": {"101"}, "cursor": {"x"}}, 14)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Details) != 3 {
+		t.Errorf("err = %v, want page, perPage and cursor rejected", err)
+	}
+}
+
+func TestMangasPages(t *testing.T) {
+	api := newTestAPI(t)
+
+	var page store.Page[Manga]
+	api.expect(http.StatusOK, "GET", "/mangas?perPage=1", nil, nil, &page)
+	if page.Total != 2 || len(page.Items) != 1 || page.Page != 1 || page.PerPage != 1 {
+		t.Errorf("page = %+v, want 1 of 2 mangas", page)
+	}
+	api.expect(http.StatusBadRequest, "GET", "/mangas?perPage=0", nil, nil, nil)
+}
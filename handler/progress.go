@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/store"
+)
+
+func NewProgressHandler(progress store.ProgressStore, mangas store.MangaStore, thumbs *images.Thumbnails) *ProgressHandler {
+	return &ProgressHandler{progress: progress, mangas: mangas, thumbs: thumbs}
+}
+
+// ProgressHandler serves the reading progress of the current user. Every
+// route must be wrapped in middleware.Authenticator.Require and Identify.
+type ProgressHandler struct {
+	progress store.ProgressStore
+	mangas   store.MangaStore
+	thumbs   *images.Thumbnails
+}
+
+type ProgressRequest struct {
+	Chapter int `json:"chapter"`
+	Page    int `json:"page"`
+}
+
+type ReadRequest struct {
+	Chapters []int `json:"chapters"`
+	Read     bool  `json:"read"`
+}
+
+// ContinueReading is one entry of the "continue reading" list.
+type ContinueReading struct {
+	store.Progress
+	Manga Manga `json:"manga"`
+}
+
+const maxContinueReading = 50
+
+// @Summary Save reading progress
+// @Description Record the chapter and page the current user reached. Reaching the last page marks the chapter read.
+// @Tags Progress
+// @ID save-progress
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  body body ProgressRequest true "Position"
+// @Success 200 {object} store.Progress
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/progress/{name} [put]
+func (p *ProgressHandler) SaveProgress(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name := r.PathValue("name")
+
+	var req ProgressRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	chapter, err := p.mangas.Chapter(r.Context(), name, req.Chapter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.Page < 1 || req.Page > len(chapter.Img) {
+		writeError(w, r, badRequest("page out of range", map[string]string{"page": "must be between 1 and " + strconv.Itoa(len(chapter.Img))}))
+		return
+	}
+
+	progress := store.Progress{UserId: user.Id, AnimeName: name, Chapter: req.Chapter, Page: req.Page, UpdatedAt: time.Now()}
+	if err := p.progress.SaveProgress(r.Context(), progress); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.Page == len(chapter.Img) {
+		if err := p.progress.SetRead(r.Context(), user.Id, name, []int{req.Chapter}, true); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, progress)
+}
+
+// @Summary Continue reading
+// @Description Mangas the current user has started, most recently read first
+// @Tags Progress
+// @ID continue-reading
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  limit query int false "at most 50, default 20"
+// @Success 200 {array} ContinueReadingSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/progress [get]
+func (p *ProgressHandler) ContinueReading(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	limit := defaultPerPage
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxContinueReading {
+			writeError(w, r, badRequest("invalid limit", map[string]string{"limit": "must be between 1 and " + strconv.Itoa(maxContinueReading)}))
+			return
+		}
+	}
+
+	progress, err := p.progress.ContinueReading(r.Context(), user.Id, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(progress) == 0 {
+		writeJSON(w, http.StatusOK, []ContinueReading{})
+		return
+	}
+
+	names := make([]string, len(progress))
+	for i, pr := range progress {
+		names[i] = pr.AnimeName
+	}
+	mangas, err := p.mangas.Filter(r.Context(), store.MangaFilter{Names: names})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	withThumbnails(p.thumbs, mangas.Items)
+	byName := map[string]Manga{}
+	for _, manga := range mangas.Items {
+		byName[manga.Name] = manga
+	}
+
+	items := []ContinueReading{}
+	for _, pr := range progress {
+		if manga, ok := byName[pr.AnimeName]; ok {
+			items = append(items, ContinueReading{Progress: pr, Manga: manga})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// @Summary Mark chapters read or unread
+// @Description Mark several chapters of a manga read or unread at once. Every chapter must exist.
+// @Tags Progress
+// @ID mark-read
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  body body ReadRequest true "Chapters"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/read/{name} [post]
+func (p *ProgressHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name := r.PathValue("name")
+
+	var req ReadRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(req.Chapters) == 0 {
+		writeError(w, r, badRequest("no chapters given", map[string]string{"chapters": "required"}))
+		return
+	}
+
+	if err := p.checkChapters(r, name, req.Chapters); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := p.progress.SetRead(r.Context(), user.Id, name, req.Chapters, req.Read); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: "Chapters updated"})
+}
+
+// checkChapters fails with 404 when the manga does not exist and 422 when
+// any of chapters does not, so no read marks point at missing chapters.
+func (p *ProgressHandler) checkChapters(r *http.Request, name string, chapters []int) error {
+	if _, err := p.mangas.ByName(r.Context(), name); err != nil {
+		return err
+	}
+	existing, err := p.mangas.Chapters(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	known := make(map[int]bool, len(existing))
+	for _, c := range existing {
+		known[c.Chapter] = true
+	}
+	var missing []string
+	for _, c := range chapters {
+		if !known[c] {
+			missing = append(missing, strconv.Itoa(c))
+		}
+	}
+	if len(missing) > 0 {
+		return &APIError{Status: http.StatusUnprocessableEntity, Code: "unknown_chapters", Message: "chapters do not exist",
+			Details: map[string]string{"chapters": strings.Join(missing, ",")}}
+	}
+	return nil
+}
+
+// markRead sets Chapter.Read for the signed-in caller. Anonymous requests
+// and callers without a stored user are left untouched, and so are the
+// chapters when the database is unavailable: the read flags are not worth
+// failing the response over.
+func markRead(r *http.Request, progress store.ProgressStore, animeName string, chapters []Chapter) error {
+	if len(chapters) == 0 {
+		return nil
+	}
+	user, err := currentUser(r)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return nil
+	}
+	var read []int
+	if err == nil {
+		read, err = progress.ReadChapters(r.Context(), user.Id, animeName)
+	}
+	if errors.Is(err, store.ErrUnavailable) {
+		slog.WarnContext(r.Context(), "Serving chapters without read flags", "manga", animeName, "error", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	set := map[int]bool{}
+	for _, c := range read {
+		set[c] = true
+	}
+	for i := range chapters {
+		chapters[i].Read = set[chapters[i].Chapter]
+	}
+	return nil
+}
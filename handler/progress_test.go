@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+// readFlags returns the Read flag of each chapter, in order.
+func readFlags(chapters []Chapter) []bool {
+	out := make([]bool, len(chapters))
+	for i, c := range chapters {
+		out[i] = c.Read
+	}
+	return out
+}
+
+func TestSaveProgress(t *testing.T) {
+	api := newTestAPI(t)
+
+	var progress store.Progress
+	api.expect(http.StatusOK, "PUT", "/user/me/progress/Berserk", &alice, ProgressRequest{Chapter: 1, Page: 2}, &progress)
+	if progress.Chapter != 1 || progress.Page != 2 {
+		t.Errorf("progress = %+v, want chapter 1 page 2", progress)
+	}
+
+	var resp ErrorResponse
+	api.expect(http.StatusBadRequest, "PUT", "/user/me/progress/Berserk", &alice, ProgressRequest{Chapter: 1, Page: 4}, &resp)
+	if resp.Details["page"] == "" {
+		t.Errorf("error = %+v, want page out of range", resp)
+	}
+	api.expect(http.StatusNotFound, "PUT", "/user/me/progress/Berserk", &alice, ProgressRequest{Chapter: 3, Page: 1}, nil)
+
+	// Reaching the last page marks the chapter read.
+	api.expect(http.StatusOK, "PUT", "/user/me/progress/Berserk", &alice, ProgressRequest{Chapter: 2, Page: 2}, nil)
+	var chapter Chapter
+	api.expect(http.StatusOK, "GET", "/manga/Berserk/2", &alice, nil, &chapter)
+	if !chapter.Read {
+		t.Error("chapter 2 is not read after its last page")
+	}
+
+	var items []ContinueReading
+	api.expect(http.StatusOK, "GET", "/user/me/progress", &alice, nil, &items)
+	if len(items) != 1 || items[0].Manga.Name != "Berserk" || items[0].Chapter != 2 {
+		t.Errorf("continue reading = %+v, want Berserk at chapter 2", items)
+	}
+	api.expect(http.StatusBadRequest, "GET", "/user/me/progress?limit=51", &alice, nil, nil)
+}
+
+func TestMarkRead(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.expect(http.StatusBadRequest, "POST", "/user/me/read/Berserk", &alice, ReadRequest{Read: true}, nil)
+	api.expect(http.StatusNotFound, "POST", "/user/me/read/Vagabond", &alice, ReadRequest{Chapters: []int{1}, Read: true}, nil)
+
+	var resp ErrorResponse
+	api.expect(http.StatusUnprocessableEntity, "POST", "/user/me/read/Berserk", &alice, ReadRequest{Chapters: []int{1, 3, 4}, Read: true}, &resp)
+	if resp.Code != "unknown_chapters" || resp.Details["chapters"] != "3,4" {
+		t.Errorf("error = %+v, want unknown_chapters 3,4", resp)
+	}
+
+	api.expect(http.StatusOK, "POST", "/user/me/read/Berserk", &alice, ReadRequest{Chapters: []int{1, 2}, Read: true}, nil)
+	api.expect(http.StatusOK, "POST", "/user/me/read/Berserk", &alice, ReadRequest{Chapters: []int{2}, Read: false}, nil)
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", &alice, nil, &manga)
+	if got := readFlags(manga.Chapters); !reflect.DeepEqual(got, []bool{true, false}) {
+		t.Errorf("read flags = %v, want [true false]", got)
+	}
+}
+
+func TestReadFlags(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "POST", "/user/me/read/Berserk", &alice, ReadRequest{Chapters: []int{1}, Read: true}, nil)
+
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", &alice, nil, &manga)
+	if got := readFlags(manga.Chapters); !reflect.DeepEqual(got, []bool{true, false}) {
+		t.Errorf("alice's read flags = %v, want [true false]", got)
+	}
+
+	// The cached response must not carry alice's flags to anyone else.
+	manga = Manga{}
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, &manga)
+	if got := readFlags(manga.Chapters); !reflect.DeepEqual(got, []bool{false, false}) {
+		t.Errorf("anonymous read flags = %v, want [false false]", got)
+	}
+}
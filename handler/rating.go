@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/store"
+)
+
+func NewRatingHandler(ratings store.RatingStore, mangas store.MangaStore, c cache.Cache) *RatingHandler {
+	return &RatingHandler{ratings: ratings, mangas: mangas, cache: cache.WithNamespace(c, mangaNamespace)}
+}
+
+// RatingHandler serves user ratings. Every /user/me route must be wrapped
+// in middleware.Authenticator.Require and Identify.
+type RatingHandler struct {
+	ratings store.RatingStore
+	mangas  store.MangaStore
+	cache   cache.Cache
+}
+
+type RatingRequest struct {
+	Score int `json:"score"`
+}
+
+// @Summary Rate a manga
+// @Description Create or update the current user's rating of a manga
+// @Tags Rating
+// @ID rate-manga
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Param  body body RatingRequest true "Score from 1 to 10"
+// @Success 200 {object} store.RatingSummary
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/rating/{name} [put]
+func (h *RatingHandler) Rate(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name := r.PathValue("name")
+
+	var req RatingRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.Score < store.MinScore || req.Score > store.MaxScore {
+		writeError(w, r, badRequest("invalid score", map[string]string{
+			"score": "must be between " + strconv.Itoa(store.MinScore) + " and " + strconv.Itoa(store.MaxScore),
+		}))
+		return
+	}
+
+	summary, err := h.ratings.Rate(r.Context(), store.Rating{UserId: user.Id, AnimeName: name, Score: req.Score, UpdatedAt: time.Now()})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	invalidateManga(r.Context(), h.cache, name)
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// @Summary Get my rating
+// @Description The current user's rating of a manga
+// @Tags Rating
+// @ID get-my-rating
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Success 200 {object} store.Rating
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/rating/{name} [get]
+func (h *RatingHandler) MyRating(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	rating, err := h.ratings.UserRating(r.Context(), user.Id, r.PathValue("name"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rating)
+}
+
+// @Summary Delete my rating
+// @Description Remove the current user's rating of a manga
+// @Tags Rating
+// @ID delete-my-rating
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  name path string true "Name of the Manga"
+// @Success 200 {object} store.RatingSummary
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/rating/{name} [delete]
+func (h *RatingHandler) DeleteRating(w http.ResponseWriter, r *http.Request) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	name := r.PathValue("name")
+
+	summary, err := h.ratings.DeleteRating(r.Context(), user.Id, name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	invalidateManga(r.Context(), h.cache, name)
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// @Summary Rating distribution
+// @Description Number of ratings for every score of a manga
+// @Tags Rating
+// @ID rating-distribution
+// @Accept  json
+// @Produce  json
+// @Param  name path string true "Name of the Manga"
+// @Success 200 {array} store.RatingBucket
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /manga/{name}/ratings [get]
+func (h *RatingHandler) Distribution(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	// Unknown mangas have no ratings either, tell them apart.
+	if _, err := h.mangas.ByName(r.Context(), name); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	buckets, err := h.ratings.RatingDistribution(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, buckets)
+}
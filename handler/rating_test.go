@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func TestRate(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, nil)
+
+	var resp ErrorResponse
+	api.expect(http.StatusBadRequest, "PUT", "/user/me/rating/Berserk", &alice, RatingRequest{Score: 11}, &resp)
+	if resp.Details["score"] == "" {
+		t.Errorf("error = %+v, want an invalid score", resp)
+	}
+	api.expect(http.StatusNotFound, "PUT", "/user/me/rating/Vagabond", &alice, RatingRequest{Score: 5}, nil)
+
+	var summary store.RatingSummary
+	api.expect(http.StatusOK, "PUT", "/user/me/rating/Berserk", &alice, RatingRequest{Score: 8}, &summary)
+	if summary != (store.RatingSummary{AverageRating: 8, RatingCount: 1}) {
+		t.Errorf("summary = %+v, want 8 from one rating", summary)
+	}
+
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", nil, nil, &manga)
+	if manga.AverageRating != 8 || manga.RatingCount != 1 {
+		t.Errorf("cached manga rating = %v from %d, want the new rating", manga.AverageRating, manga.RatingCount)
+	}
+
+	var buckets []store.RatingBucket
+	api.expect(http.StatusOK, "GET", "/manga/Berserk/ratings", nil, nil, &buckets)
+	var rated []store.RatingBucket
+	for _, b := range buckets {
+		if b.Count > 0 {
+			rated = append(rated, b)
+		}
+	}
+	if want := []store.RatingBucket{{Score: 8, Count: 1}}; !reflect.DeepEqual(rated, want) {
+		t.Errorf("distribution = %v, want %v", rated, want)
+	}
+	resp = ErrorResponse{}
+	api.expect(http.StatusNotFound, "GET", "/manga/Vagabond/ratings", nil, nil, &resp)
+	if resp.Code != "not_found" {
+		t.Errorf("unknown manga error = %+v, want not_found", resp)
+	}
+
+	api.expect(http.StatusOK, "DELETE", "/user/me/rating/Berserk", &alice, nil, &summary)
+	if summary != (store.RatingSummary{}) {
+		t.Errorf("summary after delete = %+v, want none", summary)
+	}
+}
+
+func TestPopular(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "PUT", "/user/me/rating/Berserk", &alice, RatingRequest{Score: 9}, nil)
+
+	var page store.Page[Manga]
+	api.expect(http.StatusOK, "GET", "/popular", nil, nil, &page)
+	if len(page.Items) != 2 || page.Items[0].Name != "Berserk" {
+		t.Errorf("popular = %+v, want Berserk first", page.Items)
+	}
+	api.expect(http.StatusBadRequest, "GET", "/popular?page=0", nil, nil, nil)
+}
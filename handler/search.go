@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chimas/GoProject/store"
+)
+
+const (
+	maxQueryLength      = 200
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// SuggestResponse is returned by GET /search?mode=suggest.
+type SuggestResponse struct {
+	Suggestions []string `json:"suggestions"`
+}
+
+// @Summary Search mangas
+// @Description Full-text search over name, author and describe that tolerates misspellings. Snippets are HTML-escaped text with matched words wrapped in <mark></mark>. With mode=suggest it returns up to limit titles for an autocomplete prefix instead.
+// @Tags Manga
+// @ID search-manga
+// @Accept  json
+// @Produce  json
+// @Param  q query string true "Search text or prefix"
+// @Param  mode query string false "full (default) or suggest"
+// @Param  limit query int false "suggest mode: number of titles, at most 50"
+// @Param  page query int false "page, starting at 1"
+// @Param  perPage query int false "perPage"
+// @Success 200 {object} SearchPageSwag
+// @Success 200 {object} SuggestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /search [get]
+func (m *MangaHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		writeError(w, r, badRequest("q is required", map[string]string{"q": "required"}))
+		return
+	}
+	if utf8.RuneCountInString(q) > maxQueryLength {
+		writeError(w, r, badRequest("q is too long", map[string]string{"q": "at most " + strconv.Itoa(maxQueryLength) + " characters"}))
+		return
+	}
+
+	key := "search:" + query.Encode()
+	switch query.Get("mode") {
+	case "", "full":
+	case "suggest":
+		m.suggest(w, r, key, q)
+		return
+	default:
+		writeError(w, r, badRequest("invalid mode", map[string]string{"mode": "must be full or suggest"}))
+		return
+	}
+
+	query.Del("cursor")
+	filter, err := pageFilter(query, defaultPerPage)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	search := store.SearchQuery{Query: q, Page: filter.Page, PerPage: filter.PerPage}
+
+	hits, err := cached(r.Context(), m.cache, key, m.ttl.Search, m.ttl.Stale, []string{tagMangas}, func(ctx context.Context) (store.Page[store.SearchHit], error) {
+		return m.search.Search(ctx, search)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	for i := range hits.Items {
+		withMangaThumbnails(m.thumbs, &hits.Items[i].Manga)
+	}
+
+	writeJSON(w, http.StatusOK, hits)
+}
+
+func (m *MangaHandler) suggest(w http.ResponseWriter, r *http.Request, key, prefix string) {
+	limit := defaultSuggestLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSuggestLimit {
+			writeError(w, r, badRequest("invalid limit", map[string]string{"limit": "must be between 1 and " + strconv.Itoa(maxSuggestLimit)}))
+			return
+		}
+		limit = n
+	}
+
+	names, err := cached(r.Context(), m.cache, key, m.ttl.Search, m.ttl.Stale, []string{tagMangas}, func(ctx context.Context) ([]string, error) {
+		return m.search.Suggest(ctx, prefix, limit)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SuggestResponse{Suggestions: names})
+}
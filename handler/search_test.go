@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func TestSearch(t *testing.T) {
+	api := newTestAPI(t)
+
+	var hits store.Page[store.SearchHit]
+	api.expect(http.StatusOK, "GET", "/search?q=bersrek", nil, nil, &hits)
+	if len(hits.Items) == 0 || hits.Items[0].Manga.Name != "Berserk" {
+		t.Errorf("hits = %+v, want Berserk despite the typo", hits.Items)
+	}
+
+	var suggest SuggestResponse
+	api.expect(http.StatusOK, "GET", "/search?q=mon&mode=suggest", nil, nil, &suggest)
+	if !reflect.DeepEqual(suggest.Suggestions, []string{"Monster"}) {
+		t.Errorf("suggestions = %v, want [Monster]", suggest.Suggestions)
+	}
+
+	api.expect(http.StatusBadRequest, "GET", "/search", nil, nil, nil)
+	api.expect(http.StatusBadRequest, "GET", "/search?q=x&mode=fuzzy", nil, nil, nil)
+	api.expect(http.StatusBadRequest, "GET", "/search?q=x&mode=suggest&limit=0", nil, nil, nil)
+}
@@ -17,21 +17,62 @@ type MangaSwag struct {
 	Popularity    int           `json:"popularity"`
 	Id            int           `json:"id"`
 	Chapters      []ChapterSwag `json:"chapters"`
+	Thumbnail     string        `json:"thumbnail,omitempty"`
 }
 
 type ChapterSwag struct {
-	Chapter   int       `json:"chapter"`
-	Img       []string  `json:"genres" db:"img"`
-	Name      string    `json:"name"`
-	AnimeName string    `json:"animeName" db:"animeName"`
-	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+	Chapter    int       `json:"chapter"`
+	Img        []string  `json:"genres" db:"img"`
+	Name       string    `json:"name"`
+	AnimeName  string    `json:"animeName" db:"animeName"`
+	CreatedAt  time.Time `json:"createdAt" db:"createdAt"`
+	Read       bool      `json:"read"`
+	Thumbnails []string  `json:"thumbnails,omitempty"`
 }
 
 type UserSwag struct {
-	Id        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Image     string    `json:"image"`
-	Favorite  []string  `json:"favorite"`
-	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+	Id          string    `json:"id"`
+	Email       string    `json:"email"`
+	Name        string    `json:"name"`
+	Image       string    `json:"image"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+	FavoriteIds []int     `json:"favoriteIds"`
+}
+
+type MangaPageSwag struct {
+	Items      []MangaSwag `json:"items"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page"`
+	PerPage    int         `json:"perPage"`
+	NextCursor string      `json:"nextCursor"`
+}
+
+type ContinueReadingSwag struct {
+	UserId    string    `json:"userId"`
+	AnimeName string    `json:"animeName"`
+	Chapter   int       `json:"chapter"`
+	Page      int       `json:"page"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Manga     MangaSwag `json:"manga"`
+}
+
+type SearchHitSwag struct {
+	MangaSwag
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchPageSwag struct {
+	Items   []SearchHitSwag `json:"items"`
+	Total   int             `json:"total"`
+	Page    int             `json:"page"`
+	PerPage int             `json:"perPage"`
+}
+
+type ImportResultSwag struct {
+	File         string      `json:"file"`
+	Chapter      ChapterSwag `json:"chapter"`
+	Replaced     bool        `json:"replaced"`
+	Deduplicated int         `json:"deduplicated"`
+	Skipped      []string    `json:"skipped"`
 }
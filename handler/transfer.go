@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+)
+
+// transferTimeout replaces the server's read and write timeouts, which are
+// sized for JSON, on routes that move archives and batches of images.
+const transferTimeout = 30 * time.Minute
+
+// allowLongTransfer extends the connection deadlines of the request. Writers
+// that hide the connection make it a no-op.
+func allowLongTransfer(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(transferTimeout)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+}
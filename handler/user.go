@@ -1,60 +1,113 @@
 package handler
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
-	"time"
+	"strconv"
 
-	"github.com/go-redis/redis/v9"
-	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/middleware"
+	"github.com/chimas/GoProject/store"
 )
 
 type SuccessResponse struct {
 	Success string `json:"success"`
 }
-type User struct {
-	Id        string         `json:"id"`
-	Email     string         `json:"email"`
-	Name      string         `json:"name"`
-	Image     string         `json:"image"`
-	Favorite  pq.StringArray `json:"favorite"`
-	CreatedAt time.Time      `json:"createdAt" db:"createdAt"`
-}
+type User = store.User
 
-func NewUserHandler(db *sqlx.DB, rdb *redis.Client) *UserHandler {
-	return &UserHandler{db: db, rdb: rdb}
+func NewUserHandler(users store.UserStore, favorites store.FavoriteStore, mangas store.MangaStore, c cache.Cache, thumbs *images.Thumbnails) *UserHandler {
+	return &UserHandler{users: users, favorites: favorites, mangas: mangas, cache: cache.WithNamespace(c, mangaNamespace), thumbs: thumbs}
 }
 
+// UserHandler serves the /user/me endpoints. Every route must be wrapped in
+// middleware.Authenticator.Require and Identify, the user is always the
+// token's owner.
 type UserHandler struct {
-	db  *sqlx.DB
-	rdb *redis.Client
+	users     store.UserStore
+	favorites store.FavoriteStore
+	mangas    store.MangaStore
+	cache     cache.Cache
+	thumbs    *images.Thumbnails
+}
+
+// authUser returns the caller the bearer token was issued to, or a 401.
+func authUser(r *http.Request) (middleware.AuthUser, error) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		return user, &APIError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "authentication required"}
+	}
+	return user, nil
+}
+
+type identityKey struct{}
+
+// identity is the stored row of the authenticated caller, or why it could
+// not be loaded.
+type identity struct {
+	user User
+	err  error
+}
+
+// Identify loads the stored row of the authenticated caller once per
+// request, by the token's e-mail, for the handlers behind it. Tokens name
+// users by the identity provider's subject, which need not be the id their
+// row was created with, so handlers key everything by the row's id. It
+// must run behind middleware.Authenticator; anonymous requests pass
+// through untouched.
+func Identify(users store.UserStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, ok := middleware.UserFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := users.ByEmail(r.Context(), auth.Email)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity{user: user, err: err})))
+	})
+}
+
+// currentUser returns the stored row loaded by Identify: a 401 for
+// anonymous requests and a 404 for callers who have not created their user
+// yet.
+func currentUser(r *http.Request) (User, error) {
+	if _, err := authUser(r); err != nil {
+		return User{}, err
+	}
+	id, ok := r.Context().Value(identityKey{}).(identity)
+	if !ok {
+		return User{}, errors.New("handler: route is not wrapped in Identify")
+	}
+	if errors.Is(id.err, store.ErrNotFound) {
+		return User{}, notFound("user not found, create it with POST /user/create")
+	}
+	return id.user, id.err
 }
 
-// @Summary Get a user by email
-// @Description Retrieve a user its email
+// @Summary Get the current user
+// @Description Retrieve the user the bearer token belongs to
 // @Tags User
-// @ID get-user-by-email
+// @ID get-user-me
 // @Accept  json
 // @Produce  json
-// @Param  email path string true "User Email"
+// @Security BearerAuth
 // @Success 200 {object} UserSwag
-// @Router /user/{email} [get]
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me [get]
 func (u *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
-	email := r.PathValue("email")
-	var user User
-
-	err := u.db.Get(&user, `SELECT * FROM "User" WHERE "email" = $1`, email)
+	user, err := currentUser(r)
 	if err != nil {
-		log.Fatal(err)
+		writeError(w, r, err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	writeJSON(w, http.StatusOK, user)
 }
 
 type FavoriteResponse struct {
@@ -65,213 +118,282 @@ type MangasSwags struct {
 }
 
 // @Summary User favorite Mangas
-// @Description User Favorites
+// @Description Favorites of the current user
 // @Tags User
 // @ID get-user-list-manga
 // @Accept  json
 // @Produce  json
-// @Param  email query string true "email"
-// @Success 200 {array} MangaSwag
-// @Router /user/favorite/list [get]
+// @Security BearerAuth
+// @Param  page query int false "page, starting at 1"
+// @Param  perPage query int false "perPage"
+// @Success 200 {object} MangaPageSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/favorite/list [get]
 func (u *UserHandler) UserFavList(w http.ResponseWriter, r *http.Request) {
-	var user User
-	email := r.URL.Query().Get("email")
-
-	log.Println("emmm", email)
-	err := u.db.Get(&user, `SELECT "favorite" FROM "User" WHERE "email" = $1`, email)
+	query := r.URL.Query()
+	query.Del("cursor")
+	filter, err := pageFilter(query, defaultPerPage)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 
-	if len(user.Favorite) == 0 {
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode([]Manga{}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	query := `SELECT * FROM "Anime" WHERE "name" = ANY($1)`
-	var favoriteMangas []Manga
-	err = u.db.Select(&favoriteMangas, query, pq.Array(user.Favorite))
+	filter.FavoriteOf = user.Id
+	filter.Sort = store.SortName
+	favoriteMangas, err := u.mangas.Filter(r.Context(), filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
+	withThumbnails(u.thumbs, favoriteMangas.Items)
+
+	writeJSON(w, http.StatusOK, favoriteMangas)
+}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(favoriteMangas); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// animeId reads a manga id from the path or query string.
+func animeId(r *http.Request) (int, error) {
+	raw := r.PathValue("id")
+	if raw == "" {
+		raw = r.URL.Query().Get("id")
 	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id < 1 {
+		return 0, badRequest("invalid manga id", map[string]string{"id": "must be a positive number"})
+	}
+	return id, nil
 }
 
 // @Summary User favorite Manga
-// @Description User Favorite
+// @Description Whether a manga is a favorite of the current user
 // @Tags User
 // @ID get-user-favorite-manga
 // @Accept  json
 // @Produce  json
-// @Param  email query string true "email"
-// @Param  name query string true "name"
+// @Security BearerAuth
+// @Param  id query int true "manga id"
 // @Success 200 {object} FavoriteResponse
-// @Router /user/favorite/one [get]
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/favorite/one [get]
 func (u *UserHandler) IsUserFavorite(w http.ResponseWriter, r *http.Request) {
-	var user User
-	name := r.URL.Query().Get("name")
-	email := r.URL.Query().Get("email")
-
-	err := u.db.Get(&user, `SELECT * FROM "User" WHERE "email" = $1`, email)
+	user, err := currentUser(r)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
-
-	isAnimeInFavorites := false
-	for _, favorite := range user.Favorite {
-		if favorite == name {
-			isAnimeInFavorites = true
-			break
-		}
+	id, err := animeId(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
-	log.Println("Is Fav:", isAnimeInFavorites)
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(FavoriteResponse{IsFavorite: isAnimeInFavorites}); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+	favorite, err := u.favorites.IsFavorite(r.Context(), user.Id, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, FavoriteResponse{IsFavorite: favorite})
 }
 
-// @Summary delete user by email
+// @Summary Delete the current user
 // @Description Delete user
 // @Tags User
 // @ID delete-user
 // @Accept  json
 // @Produce  json
-// @Param  email query string true "email"
+// @Security BearerAuth
 // @Success 200 {object} SuccessResponse
-// @Router /user/delete [delete]
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me [delete]
 func (u *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	email := r.URL.Query().Get("email")
-	result, err := u.db.Exec(`DELETE FROM "User" WHERE "email" = $1`, email)
+	user, err := currentUser(r)
 	if err != nil {
-		log.Fatal(err)
+		writeError(w, r, err)
+		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		log.Fatal(err)
+	if err := u.users.DeleteByEmail(r.Context(), user.Email); err != nil {
+		writeError(w, r, err)
+		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if rowsAffected == 0 {
-		http.Error(w, "User not found", http.StatusNotFound)
-	} else {
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(SuccessResponse{Success: "User deleted"}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	// The user's favorites no longer count towards popularity.
+	for _, id := range user.FavoriteIds {
+		u.invalidateFavorite(r, id)
 	}
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: "User deleted"})
 }
 
 // @Summary Create or cheack user
-// @Description Create
+// @Description Create the current user on first sign in. Id and email come from the token, name and image from the body or the token.
 // @Tags User
 // @ID create-or-cheack-user
 // @Accept  json
 // @Produce  json
-// @Param  body body string true "Auth Body"
+// @Security BearerAuth
+// @Param  body body UserSwag false "Profile"
 // @Success 200 {object} UserSwag
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /user/create [post]
 func (u *UserHandler) CreateUserIfNotExists(w http.ResponseWriter, r *http.Request) {
-	var newUser User
-	err := json.NewDecoder(r.Body).Decode(&newUser)
+	auth, err := authUser(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	err = u.db.Get(&newUser, `SELECT * FROM "User" WHERE "email" = $1`, newUser.Email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			query := `INSERT INTO "User" (id, email, name, image ) VALUES (:id, :email, :name, :image)`
-			_, err = u.db.NamedExec(query, newUser)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	var newUser User
+	if err := json.NewDecoder(r.Body).Decode(&newUser); err != nil && err != io.EOF {
+		writeError(w, r, badRequest("invalid JSON body", map[string]string{"body": err.Error()}))
+		return
+	}
+	newUser.Id = auth.Id
+	newUser.Email = auth.Email
+	newUser.FavoriteIds = nil
+	if newUser.Name == "" {
+		newUser.Name = auth.Name
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(newUser); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+	existing, err := currentUser(r)
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		newUser = existing
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound:
+		if err := u.users.Create(r.Context(), newUser); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	default:
+		writeError(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, newUser)
 }
 
 // @Summary Toggle Favorite manga
-// @Description Toggle manga
+// @Description Toggle a manga in the current user's favorites
 // @Tags User
 // @ID toggle-favorite-manga
 // @Accept  json
 // @Produce  json
-// @Param  name path string true "manga name"
-// @Param  email path string true "email"
-// @Success 200 {object} SuccessResponse
-// @Router /user/favorite/{name}/{email} [post]
+// @Security BearerAuth
+// @Param  id path int true "manga id"
+// @Success 200 {object} FavoriteResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/favorite/{id} [post]
 func (u *UserHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
-	var user User
-	name := r.PathValue("name")
-	email := r.PathValue("email")
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	id, err := animeId(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	err := u.db.Get(&user, `SELECT * FROM "User" WHERE "email" = $1`, email)
+	favorite, err := u.favorites.ToggleFavorite(r.Context(), user.Id, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
+	u.invalidateFavorite(r, id)
 
-	isAnimeInFavorites := false
-	for _, favorite := range user.Favorite {
-		if favorite == name {
-			isAnimeInFavorites = true
-			break
-		}
+	writeJSON(w, http.StatusOK, FavoriteResponse{IsFavorite: favorite})
+}
+
+// @Summary Add Favorite manga
+// @Description Add a manga to the current user's favorites. Adding it again changes nothing.
+// @Tags User
+// @ID add-favorite-manga
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  id path int true "manga id"
+// @Success 200 {object} FavoriteResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/favorite/{id} [put]
+func (u *UserHandler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	u.setFavorite(w, r, true)
+}
+
+// @Summary Remove Favorite manga
+// @Description Remove a manga from the current user's favorites. Removing it again changes nothing.
+// @Tags User
+// @ID remove-favorite-manga
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param  id path int true "manga id"
+// @Success 200 {object} FavoriteResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /user/me/favorite/{id} [delete]
+func (u *UserHandler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	u.setFavorite(w, r, false)
+}
+
+func (u *UserHandler) setFavorite(w http.ResponseWriter, r *http.Request, favorite bool) {
+	user, err := currentUser(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	id, err := animeId(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	if !isAnimeInFavorites {
+	changed, err := u.favorites.SetFavorite(r.Context(), user.Id, id, favorite)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if changed {
+		u.invalidateFavorite(r, id)
+	}
 
-		_, err = u.db.Exec(`UPDATE "Anime" SET "popularity" = popularity + 1 WHERE "name" = $1`, name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	writeJSON(w, http.StatusOK, FavoriteResponse{IsFavorite: favorite})
+}
 
-		user.Favorite = append(user.Favorite, name)
-		_, err = u.db.NamedExec(`UPDATE "User" SET "favorite" = :favorite WHERE "email" = :email`, user)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(SuccessResponse{Success: "Manga added"}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	} else {
-		newFavorites := []string{}
-		for _, favorite := range user.Favorite {
-			if favorite != name {
-				newFavorites = append(newFavorites, favorite)
-			}
-		}
-		user.Favorite = newFavorites
-		_, err = u.db.NamedExec(`UPDATE "User" SET "favorite" = :favorite WHERE "email" = :email`, user)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(SuccessResponse{Success: "Manga delete"}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+// invalidateFavorite drops the cached responses showing the popularity of
+// manga id, which a favorite just changed. Lists sorted by popularity are
+// dropped even when the manga cannot be looked up.
+func (u *UserHandler) invalidateFavorite(r *http.Request, id int) {
+	page, err := u.mangas.Filter(r.Context(), store.MangaFilter{Ids: []int{id}, PerPage: 1})
+	if err != nil || len(page.Items) == 0 {
+		if err := u.cache.InvalidateTags(r.Context(), tagMangas); err != nil {
+			slog.WarnContext(r.Context(), "cache invalidate", "error", err)
 		}
+		return
 	}
+	invalidateManga(r.Context(), u.cache, page.Items[0].Name)
 }
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/chimas/GoProject/middleware"
+	"github.com/chimas/GoProject/store"
+)
+
+func TestGetUser(t *testing.T) {
+	api := newTestAPI(t)
+
+	var user User
+	api.expect(http.StatusOK, "GET", "/user/me", &alice, nil, &user)
+	if user.Id != alice.Id || user.Email != alice.Email {
+		t.Errorf("user = %+v, want alice", user)
+	}
+	api.expect(http.StatusUnauthorized, "GET", "/user/me", nil, nil, nil)
+}
+
+func TestFavorites(t *testing.T) {
+	api := newTestAPI(t)
+
+	popularity := func() int {
+		t.Helper()
+		var manga Manga
+		api.expect(http.StatusOK, "GET", "/manga?name=Monster", nil, nil, &manga)
+		return manga.Popularity
+	}
+	if got := popularity(); got != 20 {
+		t.Fatalf("popularity = %d, want 20", got)
+	}
+
+	var resp FavoriteResponse
+	api.expect(http.StatusOK, "PUT", "/user/me/favorite/2", &alice, nil, &resp)
+	if !resp.IsFavorite {
+		t.Error("PUT did not add the favorite")
+	}
+	api.expect(http.StatusOK, "PUT", "/user/me/favorite/2", &alice, nil, nil)
+	// The cached manga was dropped along with the change.
+	if got := popularity(); got != 21 {
+		t.Errorf("popularity after adding twice = %d, want 21", got)
+	}
+
+	var page store.Page[Manga]
+	api.expect(http.StatusOK, "GET", "/user/me/favorite/list", &alice, nil, &page)
+	if len(page.Items) != 1 || page.Items[0].Name != "Monster" {
+		t.Errorf("favorites = %+v, want Monster", page.Items)
+	}
+
+	api.expect(http.StatusOK, "POST", "/user/me/favorite/2", &alice, nil, &resp)
+	if resp.IsFavorite {
+		t.Error("toggling a favorite kept it")
+	}
+	if got := popularity(); got != 20 {
+		t.Errorf("popularity after toggling off = %d, want 20", got)
+	}
+	api.expect(http.StatusOK, "DELETE", "/user/me/favorite/2", &alice, nil, nil)
+
+	api.expect(http.StatusBadRequest, "PUT", "/user/me/favorite/abc", &alice, nil, nil)
+	api.expect(http.StatusNotFound, "PUT", "/user/me/favorite/99", &alice, nil, nil)
+	api.expect(http.StatusUnauthorized, "PUT", "/user/me/favorite/2", nil, nil, nil)
+}
+
+func TestIdentity(t *testing.T) {
+	api := newTestAPI(t)
+
+	// A token from another provider names alice by a different subject, her
+	// favorites still go to her row.
+	relinked := middleware.AuthUser{Id: "google-oauth2|42", Email: alice.Email}
+	api.expect(http.StatusOK, "PUT", "/user/me/favorite/2", &relinked, nil, nil)
+	var resp FavoriteResponse
+	api.expect(http.StatusOK, "GET", "/user/me/favorite/one?id=2", &alice, nil, &resp)
+	if !resp.IsFavorite {
+		t.Error("favorite added under the token's subject instead of the user's id")
+	}
+
+	// Signed in but never created: every /user/me route is a 404 rather
+	// than a write the database refuses.
+	bob := middleware.AuthUser{Id: "bob", Email: "bob@example.com", Name: "Bob"}
+	api.expect(http.StatusNotFound, "GET", "/user/me", &bob, nil, nil)
+	api.expect(http.StatusNotFound, "PUT", "/user/me/favorite/2", &bob, nil, nil)
+	api.expect(http.StatusNotFound, "PUT", "/user/me/progress/Berserk", &bob, ProgressRequest{Chapter: 1, Page: 1}, nil)
+	api.expect(http.StatusNotFound, "PUT", "/user/me/rating/Berserk", &bob, RatingRequest{Score: 7}, nil)
+	api.expect(http.StatusOK, "GET", "/manga?name=Berserk", &bob, nil, nil)
+
+	var user User
+	api.expect(http.StatusOK, "POST", "/user/create", &bob, nil, &user)
+	if user.Id != bob.Id || user.Name != "Bob" {
+		t.Errorf("created user = %+v, want bob", user)
+	}
+	api.expect(http.StatusOK, "POST", "/user/create", &bob, User{Name: "Robert"}, &user)
+	if user.Name != "Bob" {
+		t.Errorf("second create = %+v, want the existing user", user)
+	}
+	api.expect(http.StatusOK, "PUT", "/user/me/favorite/2", &bob, nil, nil)
+
+	api.expect(http.StatusOK, "DELETE", "/user/me", &bob, nil, nil)
+	api.expect(http.StatusNotFound, "GET", "/user/me", &bob, nil, nil)
+}
+
+func TestDeleteUser(t *testing.T) {
+	api := newTestAPI(t)
+	api.expect(http.StatusOK, "PUT", "/user/me/favorite/2", &alice, nil, nil)
+	var manga Manga
+	api.expect(http.StatusOK, "GET", "/manga?name=Monster", nil, nil, &manga)
+	if manga.Popularity != 21 {
+		t.Fatalf("popularity = %d, want 21", manga.Popularity)
+	}
+
+	api.expect(http.StatusOK, "DELETE", "/user/me", &alice, nil, nil)
+	api.expect(http.StatusOK, "GET", "/manga?name=Monster", nil, nil, &manga)
+	if manga.Popularity != 20 {
+		t.Errorf("cached popularity after deleting the user = %d, want 20", manga.Popularity)
+	}
+	api.expect(http.StatusNotFound, "DELETE", "/user/me", &alice, nil, nil)
+}
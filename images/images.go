@@ -0,0 +1,176 @@
+// Package images stores uploaded covers and chapter pages.
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrNotFound        = errors.New("images: not found")
+	ErrTooLarge        = errors.New("images: image is too large")
+	ErrUnsupportedType = errors.New("images: unsupported image type")
+)
+
+// Object describes a stored image.
+type Object struct {
+	ContentType string
+	Size        int64
+}
+
+// ImageStore keeps images under content-addressed keys. Keys come from
+// KeyFor, so storing the same key twice stores the same bytes.
+type ImageStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the image and its metadata, or ErrNotFound. The caller
+	// closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// extensions maps every accepted content type to the key extension.
+var extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+	"image/avif": "avif",
+}
+
+var contentTypes = func() map[string]string {
+	m := map[string]string{}
+	for ct, ext := range extensions {
+		m[ext] = ct
+	}
+	return m
+}()
+
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{2}/[0-9a-f]{64}\.(jpg|png|gif|webp|avif)$`)
+
+// ValidKey reports whether key has the form produced by KeyFor. Backends
+// and handlers reject anything else, which also rules out path traversal.
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// KeyFor returns the key of data: its SHA-256, fanned out by the first
+// byte, with the extension of its content type.
+func KeyFor(data []byte, contentType string) string {
+	sum := sha256.Sum256(data)
+	h := hex.EncodeToString(sum[:])
+	return h[:2] + "/" + h + "." + Extension(contentType)
+}
+
+// Extension returns the file extension, without the dot, of an accepted
+// content type.
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// ContentTypeOf returns the content type of a key made by KeyFor.
+func ContentTypeOf(key string) string {
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] == '.' {
+			return contentTypes[key[i+1:]]
+		}
+	}
+	return ""
+}
+
+// Sniff detects the content type from the leading bytes, ignoring whatever
+// the client claimed. It returns ErrUnsupportedType for anything but JPEG,
+// PNG, GIF, WebP and AVIF.
+func Sniff(data []byte) (string, error) {
+	// net/http does not know AVIF: an ISO BMFF "ftyp" box with an avif or
+	// avis brand.
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" && (string(data[8:12]) == "avif" || string(data[8:12]) == "avis") {
+		return "image/avif", nil
+	}
+	ct := http.DetectContentType(data)
+	if _, ok := extensions[ct]; !ok {
+		return "", ErrUnsupportedType
+	}
+	return ct, nil
+}
+
+// Image is an uploaded image as reported to clients.
+type Image struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	// Deduplicated is true when the same bytes were already stored.
+	Deduplicated bool `json:"deduplicated"`
+}
+
+// Uploader validates images and stores each distinct one once.
+type Uploader struct {
+	store   ImageStore
+	maxSize int64
+	baseURL string
+}
+
+// NewUploader returns an Uploader rejecting images over maxSize bytes. URLs
+// of stored images are baseURL followed by the key.
+func NewUploader(store ImageStore, maxSize int64, baseURL string) *Uploader {
+	return &Uploader{store: store, maxSize: maxSize, baseURL: baseURL}
+}
+
+func (u *Uploader) MaxSize() int64 {
+	return u.maxSize
+}
+
+// URL returns the public URL of key.
+func (u *Uploader) URL(key string) string {
+	return u.baseURL + key
+}
+
+// KeyOf returns the key of an image URL made by URL, false for images
+// stored elsewhere.
+func (u *Uploader) KeyOf(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, u.baseURL)
+	return key, ok && ValidKey(key)
+}
+
+// Upload reads one image from r and stores it unless it is already stored.
+func (u *Uploader) Upload(ctx context.Context, r io.Reader) (Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.maxSize+1))
+	if err != nil {
+		return Image{}, err
+	}
+	if int64(len(data)) > u.maxSize {
+		return Image{}, ErrTooLarge
+	}
+	ct, err := Sniff(data)
+	if err != nil {
+		return Image{}, err
+	}
+
+	key := KeyFor(data, ct)
+	img := Image{Key: key, URL: u.URL(key), ContentType: ct, Size: len(data)}
+	exists, err := u.store.Exists(ctx, key)
+	if err != nil {
+		return Image{}, err
+	}
+	if exists {
+		img.Deduplicated = true
+		return img, nil
+	}
+	return img, u.store.Put(ctx, key, data, ct)
+}
+
+// Open returns a stored image. It is a shortcut for serving images through
+// the API when the backend is not publicly reachable.
+func (u *Uploader) Open(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	if !ValidKey(key) {
+		return nil, Object{}, ErrNotFound
+	}
+	return u.store.Get(ctx, key)
+}
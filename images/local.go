@@ -0,0 +1,81 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Local keeps images as files below a root directory.
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{root: root}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Object{}, err
+	}
+	return f, Object{ContentType: ContentTypeOf(key), Size: info.Size()}, nil
+}
+
+func (l *Local) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,400 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Widths are the widths variants may be resized to. Anything in between is
+// refused so the variant cache stays bounded.
+var Widths = []int{160, 320, 640, 960, 1280}
+
+// Qualities are the qualities variants are encoded at, for the same
+// reason. SnapQuality maps any requested quality to one of them.
+var Qualities = []int{50, 65, 80, 95}
+
+const (
+	DefaultQuality = 80
+
+	// generateTimeout bounds a shared resize, which no single request can
+	// cancel.
+	generateTimeout = 2 * time.Minute
+
+	// ThumbnailWidth is the width of the thumbnail URLs added to mangas and
+	// chapters.
+	ThumbnailWidth = 320
+
+	// maxPixels guards against decompression bombs: a few kilobytes of PNG
+	// can claim to be gigapixels.
+	maxPixels = 50_000_000
+)
+
+// Encoder writes an image in one format. Quality runs from 1 to 100 and may
+// be ignored by lossless formats. Encoders that run for long should stop
+// when ctx is done.
+type Encoder interface {
+	Encode(ctx context.Context, w io.Writer, img image.Image, quality int) error
+}
+
+type EncoderFunc func(ctx context.Context, w io.Writer, img image.Image, quality int) error
+
+func (f EncoderFunc) Encode(ctx context.Context, w io.Writer, img image.Image, quality int) error {
+	return f(ctx, w, img, quality)
+}
+
+var (
+	jpegEncoder = EncoderFunc(func(ctx context.Context, w io.Writer, img image.Image, quality int) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	})
+	pngEncoder = EncoderFunc(func(ctx context.Context, w io.Writer, img image.Image, quality int) error {
+		return png.Encode(w, img)
+	})
+)
+
+// CommandEncoder encodes with an external tool, for formats the standard
+// library cannot write. The image is handed over as a PNG file; in Args
+// "{in}", "{out}" and "{quality}" are replaced by the input file, the
+// output file and the quality. The tool is killed when ctx is done.
+type CommandEncoder struct {
+	Path string
+	Args []string
+}
+
+func (c CommandEncoder) Encode(ctx context.Context, w io.Writer, img image.Image, quality int) error {
+	dir, err := os.MkdirTemp("", "encode-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out")
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	if err := os.WriteFile(in, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = strings.NewReplacer("{in}", in, "{out}", out, "{quality}", strconv.Itoa(quality)).Replace(a)
+	}
+	cmd := exec.CommandContext(ctx, c.Path, args...)
+	// Children of a killed tool may hold its output open, stop waiting.
+	cmd.WaitDelay = time.Second
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("images: %s: %w: %s", filepath.Base(c.Path), err, bytes.TrimSpace(msg))
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// tools are the external encoders picked up by RegisterTools.
+var tools = []struct {
+	contentType string
+	name        string
+	args        []string
+}{
+	{"image/webp", "cwebp", []string{"-quiet", "-q", "{quality}", "{in}", "-o", "{out}"}},
+	{"image/avif", "avifenc", []string{"-q", "{quality}", "{in}", "{out}"}},
+}
+
+// Variant is a resized image in the variant cache.
+type Variant struct {
+	Path        string
+	ContentType string
+	// ETag is the quoted entity tag, unique per key, width, quality and
+	// format.
+	ETag string
+}
+
+// Resizer produces resized and re-encoded variants of stored images and
+// keeps them on disk. Variants are derived from immutable images, so the
+// cache directory never needs invalidating and may be wiped at any time.
+type Resizer struct {
+	store ImageStore
+	dir   string
+
+	encoders map[string]Encoder
+	// sem bounds concurrent decodes and encodes to the CPU count.
+	sem chan struct{}
+
+	mu       sync.Mutex
+	inflight map[string]*inflight
+}
+
+type inflight struct {
+	done chan struct{}
+	err  error
+}
+
+// NewResizer returns a Resizer caching variants below dir. It encodes JPEG
+// and PNG; Register adds other formats.
+func NewResizer(store ImageStore, dir string) (*Resizer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Resizer{
+		store:    store,
+		dir:      dir,
+		encoders: map[string]Encoder{"image/jpeg": jpegEncoder, "image/png": pngEncoder},
+		sem:      make(chan struct{}, runtime.NumCPU()),
+		inflight: map[string]*inflight{},
+	}, nil
+}
+
+// Register makes the Resizer produce contentType with enc. It must be
+// called before serving.
+func (r *Resizer) Register(contentType string, enc Encoder) {
+	r.encoders[contentType] = enc
+}
+
+// RegisterTools registers cwebp and avifenc when they are on the PATH. It
+// returns the content types they added and the ones left out because their
+// tool is missing, as in the scratch image, which serves JPEG and PNG only.
+func (r *Resizer) RegisterTools() (added, missing []string) {
+	for _, t := range tools {
+		if path, err := exec.LookPath(t.name); err == nil {
+			r.Register(t.contentType, CommandEncoder{Path: path, Args: t.args})
+			added = append(added, t.contentType)
+		} else {
+			missing = append(missing, t.contentType)
+		}
+	}
+	return added, missing
+}
+
+// Negotiate picks the format of a variant of an image of type source for a
+// request with the given Accept header: AVIF, then WebP when both the client
+// and an encoder support it, otherwise JPEG for JPEG sources and PNG for
+// the rest, which may have transparency.
+func (r *Resizer) Negotiate(accept, source string) string {
+	for _, ct := range []string{"image/avif", "image/webp"} {
+		if _, ok := r.encoders[ct]; ok && accepts(accept, ct) {
+			return ct
+		}
+	}
+	if source == "image/jpeg" {
+		return source
+	}
+	return "image/png"
+}
+
+// accepts reports whether the Accept header lists contentType explicitly
+// with a non-zero q. Wildcards do not count: browsers that decode AVIF or
+// WebP say so.
+func accepts(accept, contentType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != contentType {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// VariantOf returns where the variant of key at width and quality in
+// contentType is cached, without creating it.
+func (r *Resizer) VariantOf(key string, width, quality int, contentType string) Variant {
+	name := strings.TrimSuffix(filepath.Base(key), filepath.Ext(key)) +
+		"-w" + strconv.Itoa(width) + "-q" + strconv.Itoa(quality) + "." + extensions[contentType]
+	return Variant{
+		Path:        filepath.Join(r.dir, filepath.Dir(filepath.FromSlash(key)), name),
+		ContentType: contentType,
+		ETag:        `"` + name + `"`,
+	}
+}
+
+// SnapQuality returns the entry of Qualities closest to quality, the
+// higher one on ties.
+func SnapQuality(quality int) int {
+	best := Qualities[0]
+	for _, q := range Qualities[1:] {
+		if abs(q-quality) <= abs(best-quality) {
+			best = q
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Variant returns the variant of key resized to width, which must be one of
+// Widths, at quality, which must be one of Qualities, in contentType,
+// generating it on first use. Images narrower than width are re-encoded but
+// not enlarged. It returns ErrNotFound for unknown keys and
+// ErrUnsupportedType when the image cannot be decoded, as for AVIF, or
+// contentType has no encoder.
+func (r *Resizer) Variant(ctx context.Context, key string, width, quality int, contentType string) (Variant, error) {
+	if !ValidKey(key) {
+		return Variant{}, ErrNotFound
+	}
+	if !slices.Contains(Widths, width) || !slices.Contains(Qualities, quality) {
+		return Variant{}, fmt.Errorf("images: invalid variant w=%d q=%d", width, quality)
+	}
+	enc, ok := r.encoders[contentType]
+	if !ok {
+		return Variant{}, ErrUnsupportedType
+	}
+	v := r.VariantOf(key, width, quality, contentType)
+	if _, err := os.Stat(v.Path); err == nil {
+		return v, nil
+	}
+
+	// Concurrent requests for the same variant share one generation. It
+	// runs detached from the request that started it, so that request
+	// going away does not fail the others.
+	r.mu.Lock()
+	call, busy := r.inflight[v.Path]
+	if !busy {
+		call = &inflight{done: make(chan struct{})}
+		r.inflight[v.Path] = call
+		go func() {
+			genCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), generateTimeout)
+			defer cancel()
+			call.err = r.generate(genCtx, key, width, quality, enc, v.Path)
+			r.mu.Lock()
+			delete(r.inflight, v.Path)
+			r.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return v, call.err
+	case <-ctx.Done():
+		return Variant{}, ctx.Err()
+	}
+}
+
+func (r *Resizer) generate(ctx context.Context, key string, width, quality int, enc Encoder, path string) error {
+	rc, _, err := r.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case r.sem <- struct{}{}:
+		defer func() { <-r.sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ErrUnsupportedType
+	}
+
+	dst := src
+	if b := src.Bounds(); b.Dx() > width {
+		height := max(b.Dy()*width/b.Dx(), 1)
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, b, draw.Src, nil)
+		dst = scaled
+	}
+
+	var buf bytes.Buffer
+	if err := enc.Encode(ctx, &buf, dst, quality); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// writeFileAtomic writes to a temporary file first so readers never see a
+// partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp makes the file private, variants are served to anyone.
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Thumbnails turns the URLs of uploaded images into URLs of their resized
+// variants. URLs of images stored elsewhere have no thumbnails.
+type Thumbnails struct {
+	publicURL string
+	proxyURL  string
+}
+
+// NewThumbnails returns Thumbnails for images published under publicURL
+// and resized by the proxy at proxyURL.
+func NewThumbnails(publicURL, proxyURL string) *Thumbnails {
+	return &Thumbnails{publicURL: publicURL, proxyURL: proxyURL}
+}
+
+// URL returns the URL of img resized to width, or "" when img was not
+// uploaded here. A nil Thumbnails returns "".
+func (t *Thumbnails) URL(img string, width int) string {
+	if t == nil {
+		return ""
+	}
+	key, ok := strings.CutPrefix(img, t.publicURL)
+	if !ok || !ValidKey(key) {
+		return ""
+	}
+	return t.proxyURL + key + "?w=" + strconv.Itoa(width)
+}
@@ -0,0 +1,243 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"image/avif,image/webp,*/*", true},
+		{"text/html, image/webp;q=0.9", true},
+		{"image/webp;q=0", false},
+		{"image/*,*/*;q=0.8", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := accepts(tt.accept, "image/webp"); got != tt.want {
+			t.Errorf("accepts(%q, webp) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r, err := NewResizer(nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	chrome := "image/avif,image/webp,image/apng,*/*;q=0.8"
+	if got := r.Negotiate(chrome, "image/png"); got != "image/png" {
+		t.Errorf("without encoders = %s, want the PNG fallback", got)
+	}
+	if got := r.Negotiate(chrome, "image/jpeg"); got != "image/jpeg" {
+		t.Errorf("JPEG source without encoders = %s, want image/jpeg", got)
+	}
+	if got := r.Negotiate(chrome, "image/gif"); got != "image/png" {
+		t.Errorf("GIF source = %s, want image/png", got)
+	}
+
+	r.Register("image/webp", pngEncoder)
+	if got := r.Negotiate(chrome, "image/jpeg"); got != "image/webp" {
+		t.Errorf("with a WebP encoder = %s, want image/webp", got)
+	}
+	r.Register("image/avif", pngEncoder)
+	if got := r.Negotiate(chrome, "image/jpeg"); got != "image/avif" {
+		t.Errorf("with an AVIF encoder = %s, want image/avif", got)
+	}
+	if got := r.Negotiate("image/webp", "image/jpeg"); got != "image/webp" {
+		t.Errorf("client without AVIF = %s, want image/webp", got)
+	}
+}
+
+func TestSnapQuality(t *testing.T) {
+	for quality, want := range map[int]int{-5: 50, 1: 50, 57: 50, 58: 65, 72: 65, 73: 80, 80: 80, 88: 95, 100: 95, 1000: 95} {
+		if got := SnapQuality(quality); got != want {
+			t.Errorf("SnapQuality(%d) = %d, want %d", quality, got, want)
+		}
+	}
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: uint8(x), A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newTestResizer returns a Resizer over a Local store holding data.
+func newTestResizer(t *testing.T, data []byte) (*Resizer, string) {
+	t.Helper()
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := KeyFor(data, "image/png")
+	if err := store.Put(context.Background(), key, data, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewResizer(store, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, key
+}
+
+func variantSize(t *testing.T, v Variant) image.Point {
+	t.Helper()
+	f, err := os.Open(v.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image.Pt(cfg.Width, cfg.Height)
+}
+
+func TestVariant(t *testing.T) {
+	ctx := context.Background()
+	r, key := newTestResizer(t, encodePNG(t, 800, 400))
+
+	v, err := r.Variant(ctx, key, 320, 80, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := variantSize(t, v); got != image.Pt(320, 160) {
+		t.Errorf("variant is %v, want 320x160", got)
+	}
+	if v.ContentType != "image/jpeg" || v != r.VariantOf(key, 320, 80, "image/jpeg") {
+		t.Errorf("variant = %+v, want the cached JPEG", v)
+	}
+
+	v, err = r.Variant(ctx, key, 1280, 80, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := variantSize(t, v); got != image.Pt(800, 400) {
+		t.Errorf("wide variant is %v, want the image not enlarged", got)
+	}
+
+	if _, err := r.Variant(ctx, key, 321, 80, "image/png"); err == nil {
+		t.Error("width outside Widths accepted")
+	}
+	if _, err := r.Variant(ctx, key, 320, 80, "image/avif"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("format without an encoder error = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := r.Variant(ctx, "../etc/passwd", 320, 80, "image/png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("invalid key error = %v, want ErrNotFound", err)
+	}
+}
+
+// withSize rewrites the IHDR chunk of a PNG to claim width x height.
+func withSize(data []byte, width, height uint32) []byte {
+	out := bytes.Clone(data)
+	// The signature is 8 bytes, then IHDR: length, type, width, height...
+	ihdr := out[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	binary.BigEndian.PutUint32(out[8+8+13:], crc32.ChecksumIEEE(out[8+4:8+8+13]))
+	return out
+}
+
+func TestVariantTooManyPixels(t *testing.T) {
+	bomb := withSize(encodePNG(t, 1, 1), 100_000, 100_000)
+	r, key := newTestResizer(t, bomb)
+
+	var encoded bool
+	r.Register("image/png", EncoderFunc(func(ctx context.Context, w io.Writer, img image.Image, quality int) error {
+		encoded = true
+		return nil
+	}))
+	if _, err := r.Variant(context.Background(), key, 320, 80, "image/png"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("error = %v, want ErrTooLarge", err)
+	}
+	if encoded {
+		t.Error("image past maxPixels was decoded and encoded")
+	}
+}
+
+func TestCommandEncoder(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+
+	var buf bytes.Buffer
+	copyEnc := CommandEncoder{Path: "/bin/sh", Args: []string{"-c", `test "$2" = 65 && cp "$0" "$1"`, "{in}", "{out}", "{quality}"}}
+	if err := copyEnc.Encode(context.Background(), &buf, img, 65); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := png.DecodeConfig(&buf); err != nil || cfg.Width != 4 {
+		t.Errorf("output = %+v, %v, want the 4px wide input", cfg, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	slow := CommandEncoder{Path: "/bin/sh", Args: []string{"-c", "exec sleep 10"}}
+	if err := slow.Encode(ctx, io.Discard, img, 80); err == nil {
+		t.Error("slow encoder succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("encoder ran %v past its context", elapsed)
+	}
+}
+
+func TestRegisterTools(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	r, _ := newTestResizer(t, encodePNG(t, 10, 10))
+
+	added, missing := r.RegisterTools()
+	if len(added) != 0 || len(missing) != 2 {
+		t.Fatalf("RegisterTools = %v, %v, want both formats missing", added, missing)
+	}
+	if got := r.Negotiate("image/avif,image/webp", "image/png"); got != "image/png" {
+		t.Errorf("Negotiate = %s without encoders, want image/png", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "cwebp"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	added, missing = r.RegisterTools()
+	if !slices.Equal(added, []string{"image/webp"}) || !slices.Equal(missing, []string{"image/avif"}) {
+		t.Errorf("RegisterTools = %v, %v, want webp added and avif missing", added, missing)
+	}
+}
+
+func TestThumbnails(t *testing.T) {
+	key := KeyFor([]byte("x"), "image/png")
+	thumbs := NewThumbnails("https://cdn.example.com/", "https://img.example.com/")
+	if got, want := thumbs.URL("https://cdn.example.com/"+key, 320), "https://img.example.com/"+key+"?w=320"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+	if got := thumbs.URL("https://elsewhere.example.com/"+key, 320); got != "" {
+		t.Errorf("URL of a foreign image = %q, want none", got)
+	}
+	if got := (*Thumbnails)(nil).URL("https://cdn.example.com/"+key, 320); got != "" {
+		t.Errorf("nil Thumbnails URL = %q, want none", got)
+	}
+}
@@ -0,0 +1,242 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config points at an S3-compatible bucket. Objects are addressed
+// path-style, Endpoint/Bucket/key, which AWS, MinIO and most other
+// implementations accept. The keys need s3:GetObject, s3:PutObject and
+// s3:DeleteObject on the objects and s3:ListBucket on the bucket: without
+// it S3 answers 403 rather than 404 for missing objects, and Exists fails.
+type S3Config struct {
+	// Endpoint is the base URL, e.g. https://s3.eu-central-1.amazonaws.com
+	// or http://localhost:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// errForbidden is a 403 from S3: bad credentials, a policy denying the
+// request, or, for HEAD without s3:ListBucket, a missing object.
+var errForbidden = errors.New("images: S3 access denied")
+
+// S3 stores images in a bucket, signing requests with AWS Signature V4.
+type S3 struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewS3(cfg S3Config, client *http.Client) (*S3, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme == "" || base.Host == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("images: S3 needs an endpoint URL and a bucket")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3{cfg: cfg, base: base, client: client, now: time.Now}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data, map[string]string{
+		"Content-Type":  contentType,
+		"Cache-Control": "public, max-age=31536000, immutable",
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	return resp.Body, Object{ContentType: resp.Header.Get("Content-Type"), Size: resp.ContentLength}, nil
+}
+
+func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if errors.Is(err, errForbidden) {
+		return false, fmt.Errorf("%w (missing objects look forbidden without s3:ListBucket)", err)
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for key and turns 404 into ErrNotFound, 403
+// into errForbidden and other non-2xx statuses into errors. On success the caller closes the body.
+func (s *S3) do(ctx context.Context, method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+	if !ValidKey(key) {
+		return nil, ErrNotFound
+	}
+	u := *s.base
+	u.Path = u.Path + "/" + s.cfg.Bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%w: %s %s: %s", errForbidden, method, key, bytes.TrimSpace(msg))
+		}
+		return nil, fmt.Errorf("images: S3 %s %s: %s: %s", method, key, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+// sign adds the AWS Signature Version 4 Authorization header, signing the
+// host, every x-amz-* header and the headers already set on req.
+func (s *S3) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonical := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		canonical[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(canonical))
+	for name := range canonical {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var headerLines strings.Builder
+	for _, name := range names {
+		headerLines.WriteString(name + ":" + canonical[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		headerLines.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalPath percent-encodes each segment of the decoded path, which
+// can differ from the escaping net/url picks.
+func canonicalPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters, as SigV4
+// requires.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/chimas/GoProject/comic"
+	"github.com/chimas/GoProject/db"
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/store"
+)
+
+const importUsage = `usage: import -manga <name> [-chapter n] [-title s] [-comicinfo file] [-replace] <archive.cbz>
+       import -manga <name> [-replace] -dir <directory>`
+
+// runImport implements the `import` subcommand and returns the exit code.
+// Pages go to the configured image storage and chapters to DB_URL. Running
+// servers pick the chapters up once their cached responses expire.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, importUsage) }
+	manga := fs.String("manga", "", "name of the manga")
+	chapter := fs.Int("chapter", 0, "chapter number, default from ComicInfo.xml or the archive name")
+	title := fs.String("title", "", "chapter name, default from ComicInfo.xml")
+	comicInfo := fs.String("comicinfo", "", "ComicInfo.xml to use instead of the archive's")
+	replace := fs.Bool("replace", false, "replace existing chapters")
+	dir := fs.String("dir", "", "import every .cbz and .zip file in this directory")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	single := *dir == "" && fs.NArg() == 1
+	batch := *dir != "" && fs.NArg() == 0
+	if *manga == "" || !(single || batch) || *chapter < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	opts := comic.Options{Manga: *manga, Chapter: *chapter, Name: *title, Replace: *replace}
+	if *comicInfo != "" {
+		f, err := os.Open(*comicInfo)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		info, err := comic.ParseComicInfo(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import:", *comicInfo+":", err)
+			return exitFailure
+		}
+		opts.ComicInfo = &info
+	}
+
+	cfg, err := loadConfig("import", nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return configExit(err)
+	}
+	if cfg.DB.URL == "" {
+		fmt.Fprintln(os.Stderr, "import: db.url (DB_URL) is empty")
+		return exitConfig
+	}
+	conn, err := db.Connect(context.Background(), cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUnavailable
+	}
+	defer conn.Close()
+	imageStore, err := openImageStore(cfg.Storage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfig
+	}
+	pg := store.NewPostgres(conn)
+	importer := comic.NewImporter(images.NewUploader(imageStore, cfg.Storage.MaxBytes, cfg.Storage.PublicURL), pg, pg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *dir == "" {
+		result, err := importer.ImportFile(ctx, fs.Arg(0), opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import:", err)
+			return exitFailure
+		}
+		printImport(result)
+		return exitOK
+	}
+
+	results, err := importer.ImportDir(ctx, *dir, opts)
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintln(os.Stderr, "import:", result.File+":", result.Err)
+			continue
+		}
+		printImport(result)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return exitFailure
+	}
+	fmt.Printf("%d imported, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func printImport(result comic.Result) {
+	verb := "created"
+	if result.Replaced {
+		verb = "replaced"
+	}
+	fmt.Printf("%s: %s chapter %d, %d pages (%d already stored)\n",
+		result.File, verb, result.Chapter.Chapter, len(result.Chapter.Img), result.Deduplicated)
+	if len(result.Skipped) > 0 {
+		skipped, _ := json.Marshal(result.Skipped)
+		fmt.Printf("%s: skipped %s\n", result.File, skipped)
+	}
+}
@@ -0,0 +1,107 @@
+// Package logging builds the service's slog logger: JSON lines with levels,
+// the request ID and trace ID of the context, and e-mail addresses and
+// tokens redacted wherever they show up.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a logger writing to w. format is "json" or "text", level one
+// of debug, info, warn or error.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("logging: level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+	var h slog.Handler
+	switch format {
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request ID for every record logged with ctx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID stored by WithRequestID, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds request_id and trace_id from the context the record
+// was logged with, so call sites only pass r.Context().
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+const redacted = "[redacted]"
+
+var (
+	// secretKey matches attribute keys whose values are never logged.
+	secretKey = regexp.MustCompile(`(?i)(email|token|authorization|password|secret|cookie)`)
+	email     = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// bearer matches JWTs and anything following "Bearer".
+	bearer = regexp.MustCompile(`(?i)bearer\s+\S+|eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// redact blanks secret attributes and masks e-mails and tokens inside
+// strings and errors, which often quote the values they failed on.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+		return a
+	}
+	if secretKey.MatchString(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// Redact masks e-mail addresses and bearer tokens in s.
+func Redact(s string) string {
+	s = bearer.ReplaceAllString(s, redacted)
+	return email.ReplaceAllString(s, redacted)
+}
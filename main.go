@@ -1,18 +1,32 @@
 package main
 
 import (
-	"crypto/tls"
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/chimas/GoProject/cache"
+	"github.com/chimas/GoProject/comic"
 	"github.com/chimas/GoProject/config"
 	"github.com/chimas/GoProject/db"
 	_ "github.com/chimas/GoProject/docs"
 	"github.com/chimas/GoProject/handler"
+	"github.com/chimas/GoProject/images"
+	"github.com/chimas/GoProject/logging"
+	"github.com/chimas/GoProject/metrics"
 	"github.com/chimas/GoProject/middleware"
-	"github.com/go-redis/redis/v9"
-	"github.com/joho/godotenv"
+	"github.com/chimas/GoProject/search"
+	"github.com/chimas/GoProject/store"
+	"github.com/chimas/GoProject/tracing"
 	_ "github.com/lib/pq"
 	"github.com/rs/cors"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -22,69 +36,267 @@ import (
 //		@version		1.0
 //		@description	Manga search
 //	 @BasePath	/
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				"Bearer " followed by a JWT signed with HS256 or RS256
 func main() {
-	err := godotenv.Load()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+	os.Exit(run(os.Args[1:]))
+}
+
+// run serves the API until SIGINT or SIGTERM and returns the exit code.
+// args are configuration flags, see config.Load.
+func run(args []string) int {
+	cfg, err := loadConfig("manka", args)
 	if err != nil {
-		log.Println("Error loading .env file")
-		// log.Fatal("Error loading .env file")
+		fmt.Fprintln(os.Stderr, err)
+		return configExit(err)
 	}
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitConfig
+	}
+	slog.SetDefault(logger)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var open closers
+	defer open.closeAll()
+
+	flushTraces, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.File, buildVersion())
+	if err != nil {
+		slog.Error("Unable to set up tracing", "error", err)
+		return exitConfig
+	}
+	open.add("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return flushTraces(ctx)
+	})
+
+	stats := metrics.New()
 	router := http.NewServeMux()
 	c := cors.New(cors.Options{
-             AllowedOrigins: []string{"http://localhost:4000", "http://localhost:3000", "https://golang-on-koyeb-mankago.koyeb.app/", "https://manka-next.vercel.app"},
+		AllowedOrigins: cfg.CORS.AllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
 	})
 
-	db, err := db.DBConnection()
-	if err != nil {
-		log.Fatal("Unable to connect to database:", err)
-		return
+	var mangas store.MangaStore
+	var users store.UserStore
+	var progress store.ProgressStore
+	var ratings store.RatingStore
+	var favorites store.FavoriteStore
+	var searchIndex store.SearchIndex
+	var catalog store.CatalogStore
+	var checks []handler.Check
+	var dbStats func() sql.DBStats
+	if cfg.DB.URL == "" {
+		slog.Info("db.url is empty, using in-memory store")
+		mem := store.NewMemory()
+		if seed := cfg.Server.SeedFile; seed != "" {
+			f, err := os.Open(seed)
+			if err != nil {
+				slog.Error("Unable to open seed file", "error", err)
+				return exitConfig
+			}
+			err = mem.LoadSeed(f)
+			f.Close()
+			if err != nil {
+				slog.Error("Unable to load seed file", "error", err)
+				return exitConfig
+			}
+		}
+		mangas, users, progress, ratings, favorites, catalog = mem, mem, mem, mem, mem, mem
+	} else {
+		conn, err := db.Connect(ctx, cfg.DB, stats.QueryHook, tracing.QueryHook)
+		if err != nil {
+			slog.Error("Unable to connect to database", "error", err)
+			return exitUnavailable
+		}
+		open.add("database", conn.Close)
+		// Only deployments that opted in keep an instance without Postgres
+		// in rotation, answering reads from stale cache entries.
+		checks = append(checks, handler.Check{Name: "postgres", Ping: conn.PingContext, Optional: cfg.DB.ReadyWhileDown})
+		dbStats = conn.Stats
+		stats.RegisterDB("postgres", conn.DB)
+		if cfg.DB.MigrateOnStart {
+			if err := migrateOnStart(conn); err != nil {
+				slog.Error("Unable to migrate database", "error", err)
+				return exitUnavailable
+			}
+		}
+		pg := store.NewPostgres(conn)
+		mangas, users, progress, ratings, favorites, catalog, searchIndex = pg, pg, pg, pg, pg, pg, pg
 	}
-	defer db.Close()
 
-	// opt, err := redis.ParseURL(config.LoadEnv().REDIS_URL)
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// rdb := redis.NewClient(opt)
-	opt, err := redis.ParseURL(config.LoadEnv().REDIS_URL)
-	if err != nil {
-		panic(err)
+	if searchIndex == nil || cfg.Search.Backend == "memory" {
+		slog.Info("Using in-process search index")
+		engine := search.NewEngine(mangas)
+		if err := engine.Rebuild(ctx); err != nil {
+			slog.Error("Unable to build search index", "error", err)
+			return exitUnavailable
+		}
+		searchIndex = engine
 	}
 
-	opt.TLSConfig = &tls.Config{
-		InsecureSkipVerify: true,
+	var mangaCache cache.Cache
+	switch backend := cfg.Cache.Backend; {
+	case backend == "none":
+		mangaCache = cache.NewNoop()
+	case backend == "lru" || cfg.Redis.URL == "":
+		slog.Info("Using in-process LRU cache")
+		mangaCache = cache.NewLRU(cfg.Cache.LRUSize)
+	default:
+		rdb, err := db.NewRedis(cfg.Redis, tracing.RedisHook{})
+		if err != nil {
+			slog.Error("Invalid redis settings", "error", err)
+			return exitConfig
+		}
+		open.add("redis", rdb.Close)
+		ping := func(ctx context.Context) error { return rdb.Ping(ctx).Err() }
+		// Without Redis the cache falls back to the process, so the
+		// service starts and stays ready while Redis is down.
+		if err := db.WaitFor(ctx, "redis", cfg.Redis.Connect, ping); err != nil {
+			slog.Warn("Redis is unreachable, caching in process until it answers", "error", err)
+		}
+		checks = append(checks, handler.Check{Name: "redis", Ping: ping, Optional: true})
+		mangaCache = cache.NewFallback(cache.NewRedis(rdb), cache.NewLRU(cfg.Cache.LRUSize), cfg.Redis.RetryAfter)
 	}
 
-	rdb := redis.NewClient(opt)
+	cacheStats := cache.NewCounting(mangaCache)
+	mangaCache = cacheStats
+	stats.RegisterCache("manga", cacheStats.Stats)
+
+	imageStore, err := openImageStore(cfg.Storage)
+	if err != nil {
+		slog.Error("Unable to configure image storage", "error", err)
+		return exitConfig
+	}
+	uploader := images.NewUploader(imageStore, cfg.Storage.MaxBytes, cfg.Storage.PublicURL)
+	resizer, err := images.NewResizer(imageStore, cfg.Storage.CacheDir)
+	if err != nil {
+		slog.Error("Unable to create image cache", "error", err)
+		return exitConfig
+	}
+	added, missing := resizer.RegisterTools()
+	if len(added) > 0 {
+		slog.Info("Resized images may also be encoded as", "formats", added)
+	}
+	if len(missing) > 0 {
+		slog.Warn("Encoders not on the PATH, resized images are never encoded as", "formats", missing)
+	}
+	thumbs := images.NewThumbnails(cfg.Storage.PublicURL, cfg.Storage.ProxyURL)
+
+	handlerM := handler.NewMangaHandler(mangas, progress, searchIndex, mangaCache, handler.CacheTTLs(cfg.Cache.TTL), thumbs)
+	handlerU := handler.NewUserHandler(users, favorites, mangas, mangaCache, thumbs)
+	handlerP := handler.NewProgressHandler(progress, mangas, thumbs)
+	handlerR := handler.NewRatingHandler(ratings, mangas, mangaCache)
+	handlerA := handler.NewAdminHandler(catalog, searchIndex, mangaCache, handler.Vocabulary(cfg.Catalog))
+	handlerI := handler.NewImageHandler(uploader, resizer, mangas, catalog, mangaCache)
+	handlerE := handler.NewExportHandler(mangas, comic.NewExporter(comic.NewPages(uploader, nil)))
+	handlerH := handler.NewHealthHandler(buildVersion(), checks, dbStats, cacheStats.Stats)
+	handlerImport := handler.NewImportHandler(comic.NewImporter(uploader, mangas, catalog), mangaCache, cfg.Storage.ArchiveMaxBytes)
+
+	auth, err := middleware.NewAuthenticator(middleware.AuthConfig(cfg.Auth))
+	if errors.Is(err, middleware.ErrNoKeys) {
+		slog.Warn("No auth key is configured, /user and /admin endpoints answer 503")
+	} else if err != nil {
+		slog.Error("Unable to configure authentication", "error", err)
+		return exitConfig
+	}
+	// Routes that take a JSON body go through authed or admin, which limit
+	// it. Uploads go through adminUpload and enforce their own limits.
+	authed := func(h http.HandlerFunc) http.Handler {
+		return middleware.MaxBytes(cfg.Server.MaxBodyBytes, auth.Require(handler.Identify(users, h)))
+	}
+	maybeAuthed := func(h http.HandlerFunc) http.Handler {
+		return auth.Optional(handler.Identify(users, h))
+	}
+	admin := func(h http.HandlerFunc) http.Handler {
+		return middleware.MaxBytes(cfg.Server.MaxBodyBytes, auth.RequireRole("admin", h))
+	}
+	adminUpload := func(h http.HandlerFunc) http.Handler {
+		return auth.RequireRole("admin", h)
+	}
 
-	handlerM := handler.NewMangaHandler(db, rdb)
-	handlerU := handler.NewUserHandler(db, rdb)
 	router.HandleFunc("GET /yaml", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "docs/swagger.yaml")
 	})
 	router.HandleFunc("GET /swagger/", httpSwagger.WrapHandler)
+	router.Handle("GET /metrics", stats.Handler())
+	router.HandleFunc("GET /healthz", handlerH.Healthz)
+	router.HandleFunc("GET /readyz", handlerH.Readyz)
+	router.Handle("GET /status", admin(handlerH.Status))
 	router.HandleFunc("GET /mangas", handlerM.Mangas)
-	router.HandleFunc("GET /manga", handlerM.Manga)
-	router.HandleFunc("GET /manga/{name}/{chapter}", handlerM.Chapter)
+	router.Handle("GET /manga", maybeAuthed(handlerM.Manga))
+	router.Handle("GET /manga/{name}/{chapter}", maybeAuthed(handlerM.Chapter))
+	router.HandleFunc("GET /manga/{name}/ratings", handlerR.Distribution)
+	router.HandleFunc("GET /manga/{name}/download", handlerE.Chapters)
+	router.HandleFunc("GET /manga/{name}/{chapter}/download", handlerE.Chapter)
 	router.HandleFunc("GET /popular", handlerM.Popular)
 	router.HandleFunc("GET /filter", handlerM.Filter)
-	router.HandleFunc("GET /user/{email}", handlerU.GetUser)
-	router.HandleFunc("POST /user/create", handlerU.CreateUserIfNotExists)
-	router.HandleFunc("POST /user/favorite/{name}/{email}", handlerU.ToggleFavorite)
-	router.HandleFunc("GET /user/favorite/one", handlerU.IsUserFavorite)
-	router.HandleFunc("GET /user/favorite/list", handlerU.UserFavList)
-	router.HandleFunc("DELETE /user/delete", handlerU.DeleteUser)
-
-	// router.HandleFunc("DELETE /user",handler)
+	router.HandleFunc("GET /search", handlerM.Search)
+	router.HandleFunc("GET /images/{key...}", handlerI.Image)
+	router.Handle("GET /user/me", authed(handlerU.GetUser))
+	router.Handle("POST /user/create", authed(handlerU.CreateUserIfNotExists))
+	router.Handle("POST /user/me/favorite/{id}", authed(handlerU.ToggleFavorite))
+	router.Handle("PUT /user/me/favorite/{id}", authed(handlerU.AddFavorite))
+	router.Handle("DELETE /user/me/favorite/{id}", authed(handlerU.RemoveFavorite))
+	router.Handle("GET /user/me/favorite/one", authed(handlerU.IsUserFavorite))
+	router.Handle("GET /user/me/favorite/list", authed(handlerU.UserFavList))
+	router.Handle("DELETE /user/me", authed(handlerU.DeleteUser))
+	router.Handle("PUT /user/me/progress/{name}", authed(handlerP.SaveProgress))
+	router.Handle("GET /user/me/progress", authed(handlerP.ContinueReading))
+	router.Handle("POST /user/me/read/{name}", authed(handlerP.MarkRead))
+	router.Handle("PUT /user/me/rating/{name}", authed(handlerR.Rate))
+	router.Handle("GET /user/me/rating/{name}", authed(handlerR.MyRating))
+	router.Handle("DELETE /user/me/rating/{name}", authed(handlerR.DeleteRating))
+	router.Handle("POST /admin/manga", admin(handlerA.CreateManga))
+	router.Handle("PUT /admin/manga/{name}", admin(handlerA.UpdateManga))
+	router.Handle("DELETE /admin/manga/{name}", admin(handlerA.DeleteManga))
+	router.Handle("POST /admin/manga/{name}/chapters", admin(handlerA.CreateChapter))
+	router.Handle("POST /admin/manga/{name}/chapters/renumber", admin(handlerA.RenumberChapters))
+	router.Handle("POST /admin/manga/{name}/chapters/import", adminUpload(handlerImport.Import))
+	router.Handle("PUT /admin/manga/{name}/chapters/{chapter}", admin(handlerA.UpdateChapter))
+	router.Handle("DELETE /admin/manga/{name}/chapters/{chapter}", admin(handlerA.DeleteChapter))
+	router.Handle("PUT /admin/manga/{name}/chapters/{chapter}/pages", admin(handlerA.ReorderPages))
+	router.Handle("POST /admin/manga/{name}/chapters/{chapter}/pages", adminUpload(handlerI.ChapterPages))
+	router.Handle("POST /admin/manga/{name}/images/{kind}", adminUpload(handlerI.MangaImage))
+	router.Handle("POST /admin/images", adminUpload(handlerI.Upload))
 
-	var PORT string
-	if PORT = os.Getenv("PORT"); PORT == "" {
-		PORT = "4000"
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           middleware.RequestID(tracing.Middleware(router, middleware.Logging(router, stats.Middleware(router, c.Handler(router))))),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
-	server := http.Server{
-		Addr:    ":" + PORT,
-		Handler: middleware.Logging(c.Handler(router)),
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		slog.Error("Unable to listen", "error", err)
+		return exitListen
+	}
+	slog.Info("Listening", "addr", ln.Addr().String())
+	return serve(ctx, server, ln, cfg.Server.ShutdownGrace)
+}
+
+// openImageStore returns the image backend picked by storage.backend.
+func openImageStore(cfg config.Storage) (images.ImageStore, error) {
+	if cfg.Backend == "s3" {
+		return images.NewS3(images.S3Config(cfg.S3), nil)
 	}
-	log.Println("Listening...")
-	server.ListenAndServe()
+	return images.NewLocal(cfg.Dir)
 }
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatched labels requests no route pattern matched, their raw paths
+// would make a label value per probe and typo.
+const unmatched = "unmatched"
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.ResponseWriter.WriteHeader(status)
+	w.status = status
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware counts and times requests labelled by the mux pattern that
+// matches them, such as "GET /manga/{name}/{chapter}", never by raw path,
+// so names and e-mails in URLs stay out of the label values.
+func (m *Metrics) Middleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = unmatched
+		}
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// Counted even when the handler aborts the response.
+			m.duration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+			m.requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Inc()
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
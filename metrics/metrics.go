@@ -0,0 +1,87 @@
+// Package metrics exports Prometheus metrics for HTTP requests, the
+// Postgres pool and its queries, and the manga cache.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/chimas/GoProject/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns the registry served on /metrics. Its own registry, rather
+// than the global default, keeps metrics of libraries out unless asked for.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	queries  *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time to serve HTTP requests by route pattern and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served.",
+		}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time spent in Postgres statements by operation and table.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"query", "result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.inFlight, m.queries,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB exports the stats of a connection pool.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterCache exports the lookups counted by a cache.Counting.
+func (m *Metrics) RegisterCache(name string, stats func() cache.Stats) {
+	m.registry.MustRegister(&cacheCollector{name: name, stats: stats})
+}
+
+var cacheLookups = prometheus.NewDesc("cache_lookups_total",
+	"Cache lookups by result: hit, miss or error.", []string{"cache", "result"}, nil)
+
+type cacheCollector struct {
+	name  string
+	stats func() cache.Stats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheLookups
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(cacheLookups, prometheus.CounterValue, float64(s.Hits), c.name, "hit")
+	ch <- prometheus.MustNewConstMetric(cacheLookups, prometheus.CounterValue, float64(s.Misses), c.name, "miss")
+	ch <- prometheus.MustNewConstMetric(cacheLookups, prometheus.CounterValue, float64(s.Errors), c.name, "error")
+}
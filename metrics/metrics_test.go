@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chimas/GoProject/cache"
+)
+
+// scrape returns the metrics m serves, in the Prometheus text format.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func expectLines(t *testing.T, metrics string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("metrics are missing %q", line)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /manga/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") == "missing" {
+			http.NotFound(w, r)
+		}
+	})
+	h := m.Middleware(mux, mux)
+	for _, path := range []string{"/manga/Berserk", "/manga/Monster", "/manga/missing", "/user/alice@example.com"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	metrics := scrape(t, m)
+	expectLines(t, metrics,
+		`http_requests_total{code="200",method="GET",route="GET /manga/{name}"} 2`,
+		`http_requests_total{code="404",method="GET",route="GET /manga/{name}"} 1`,
+		`http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="GET /manga/{name}"} 3`,
+		`http_requests_in_flight 0`,
+	)
+	// Raw paths never become label values.
+	if strings.Contains(metrics, "Berserk") || strings.Contains(metrics, "alice") {
+		t.Errorf("metrics leak request paths:\n%s", metrics)
+	}
+}
+
+func TestQueryHook(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	m.QueryHook(ctx, `SELECT * FROM "Anime" WHERE name = $1`)(nil)
+	m.QueryHook(ctx, `INSERT INTO "Rating" VALUES ($1)`)(errors.New("duplicate key"))
+	m.QueryHook(ctx, `SELECT 1 FROM "Anime"`)(driver.ErrSkip)
+
+	expectLines(t, scrape(t, m),
+		`db_query_duration_seconds_count{query="select Anime",result="ok"} 1`,
+		`db_query_duration_seconds_count{query="insert Rating",result="error"} 1`,
+	)
+}
+
+func TestRegisterCache(t *testing.T) {
+	m := New()
+	m.RegisterCache("manga", func() cache.Stats { return cache.Stats{Hits: 3, Misses: 2, Errors: 1} })
+
+	expectLines(t, scrape(t, m),
+		`cache_lookups_total{cache="manga",result="hit"} 3`,
+		`cache_lookups_total{cache="manga",result="miss"} 2`,
+		`cache_lookups_total{cache="manga",result="error"} 1`,
+	)
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/chimas/GoProject/db"
+)
+
+// QueryHook times statements, labelled by db.StatementName. Pass it to
+// db.Connect.
+func (m *Metrics) QueryHook(ctx context.Context, query string) func(error) {
+	start := time.Now()
+	return func(err error) {
+		if errors.Is(err, driver.ErrSkip) {
+			// database/sql retries through a prepared statement.
+			return
+		}
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		m.queries.WithLabelValues(db.StatementName(query), result).Observe(time.Since(start).Seconds())
+	}
+}
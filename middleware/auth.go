@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AuthUser is the caller identified by a verified bearer token.
+type AuthUser struct {
+	Id    string
+	Email string
+	Name  string
+	Roles []string
+}
+
+func (u AuthUser) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type authUserKey struct{}
+
+// WithUser stores u for UserFromContext and names it in the access log.
+func WithUser(ctx context.Context, u AuthUser) context.Context {
+	noteUser(ctx, u.Id)
+	return context.WithValue(ctx, authUserKey{}, u)
+}
+
+// UserFromContext returns the user stored by Authenticator, if any.
+func UserFromContext(ctx context.Context) (AuthUser, bool) {
+	u, ok := ctx.Value(authUserKey{}).(AuthUser)
+	return u, ok
+}
+
+type AuthConfig struct {
+	// HS256Secret verifies HS256 tokens. Empty disables HS256.
+	HS256Secret string
+	// JWKSFile is a JSON Web Key Set with the RSA keys for RS256 tokens.
+	// Empty disables RS256.
+	JWKSFile string
+	Issuer   string
+	Audience string
+}
+
+// ErrNoKeys is returned by NewAuthenticator when no key is configured. The
+// server then runs without an Authenticator and only serves public routes.
+var ErrNoKeys = errors.New("auth: neither an HS256 secret nor a JWKS file is configured")
+
+// Authenticator verifies bearer tokens issued by the frontend. A nil
+// Authenticator verifies none: routes requiring a user answer 503 and
+// optional ones treat every request as anonymous.
+type Authenticator struct {
+	hmacKey []byte
+	rsaKeys map[string]*rsa.PublicKey
+	parser  *jwt.Parser
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Email string   `json:"email"`
+	Name  string   `json:"name"`
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+}
+
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{rsaKeys: map[string]*rsa.PublicKey{}}
+	var methods []string
+	if cfg.HS256Secret != "" {
+		a.hmacKey = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.hmacKey, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		if kid == "" && len(a.rsaKeys) == 1 {
+			for _, key := range a.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("auth: unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("auth: unexpected signing method %s", token.Method.Alg())
+}
+
+// Verify parses a raw token and returns the user it was issued to.
+func (a *Authenticator) Verify(raw string) (AuthUser, error) {
+	var c claims
+	if _, err := a.parser.ParseWithClaims(raw, &c, a.key); err != nil {
+		return AuthUser{}, err
+	}
+	if c.Subject == "" || c.Email == "" {
+		return AuthUser{}, errors.New("auth: token has no subject or email")
+	}
+	roles := c.Roles
+	if c.Role != "" {
+		roles = append(roles, c.Role)
+	}
+	return AuthUser{Id: c.Subject, Email: c.Email, Name: c.Name, Roles: roles}, nil
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// Require rejects requests without a valid bearer token with 401 and puts
+// the authenticated user in the request context otherwise.
+func (a *Authenticator) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a == nil {
+			writeErrorBody(w, r, http.StatusServiceUnavailable, "unavailable", "authentication is not configured")
+			return
+		}
+		raw := bearerToken(r)
+		if raw == "" {
+			unauthorized(w, r, "missing bearer token")
+			return
+		}
+		user, err := a.Verify(raw)
+		if err != nil {
+			unauthorized(w, r, "invalid token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// Optional is like Require but lets anonymous requests through. Requests
+// carrying an invalid token are still rejected.
+func (a *Authenticator) Optional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a == nil || bearerToken(r) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		a.Require(next).ServeHTTP(w, r)
+	})
+}
+
+// RequireRole is like Require but also rejects users without role with 403.
+func (a *Authenticator) RequireRole(role string, next http.Handler) http.Handler {
+	return a.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _ := UserFromContext(r.Context()); !user.HasRole(role) {
+			writeErrorBody(w, r, http.StatusForbidden, "forbidden", "requires the "+role+" role")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer`)
+	writeErrorBody(w, r, http.StatusUnauthorized, "unauthorized", message)
+}
+
+func writeErrorBody(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Code: code, Message: message, RequestID: r.Header.Get("X-Request-ID")})
+}
+
+// errorBody mirrors handler.ErrorResponse for errors raised before a
+// request reaches a handler.
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read JWKS: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth: parse JWKS: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %q: bad modulus", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %q: bad exponent", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("auth: JWKS has no RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func validClaims() claims {
+	return claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Issuer:    "https://id.example.com",
+			Audience:  jwt.ClaimStrings{"manga"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Email: "alice@example.com",
+		Name:  "Alice",
+		Role:  "admin",
+	}
+}
+
+func signHS256(t *testing.T, c claims) string {
+	t.Helper()
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// writeJWKS writes key as the only entry of a JWKS file and returns its path.
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+	set := map[string][]jwk{"keys": {{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyHS256(t *testing.T) {
+	a, err := NewAuthenticator(AuthConfig{HS256Secret: testSecret, Issuer: "https://id.example.com", Audience: "manga"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := a.Verify(signHS256(t, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != "alice" || user.Email != "alice@example.com" || user.Name != "Alice" || !user.HasRole("admin") {
+		t.Errorf("user = %+v, want alice with the admin role", user)
+	}
+
+	tests := map[string]func(c *claims){
+		"expired":        func(c *claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) },
+		"no expiry":      func(c *claims) { c.ExpiresAt = nil },
+		"other issuer":   func(c *claims) { c.Issuer = "https://evil.example.com" },
+		"other audience": func(c *claims) { c.Audience = jwt.ClaimStrings{"admin"} },
+		"no email":       func(c *claims) { c.Email = "" },
+		"no subject":     func(c *claims) { c.Subject = "" },
+	}
+	for name, change := range tests {
+		c := validClaims()
+		change(&c)
+		if _, err := a.Verify(signHS256(t, c)); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("another secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Verify(forged); err == nil {
+		t.Error("token signed with another secret accepted")
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuthenticator(AuthConfig{JWKSFile: writeJWKS(t, "k1", &key.PublicKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+	token.Header["kid"] = "k1"
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Verify(raw); err != nil {
+		t.Errorf("RS256 token rejected: %v", err)
+	}
+
+	// HS256 is not enabled, so a token signed with it is refused whatever
+	// the key.
+	if _, err := a.Verify(signHS256(t, validClaims())); err == nil {
+		t.Error("HS256 token accepted without an HS256 secret")
+	}
+
+	token.Header["kid"] = "k2"
+	if raw, err = token.SignedString(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Verify(raw); err == nil {
+		t.Error("token with an unknown key id accepted")
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(`{"keys":[{"kty":"EC","kid":"e1"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadJWKS(path); err == nil {
+		t.Error("JWKS without RSA keys accepted")
+	}
+	if _, err := loadJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing JWKS file accepted")
+	}
+}
+
+// serve runs h with the Authorization header set to auth when it is not
+// empty and returns the response with the user h saw, if any.
+func serve(h func(http.Handler) http.Handler, auth string) (*httptest.ResponseRecorder, *AuthUser) {
+	var seen *AuthUser
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := UserFromContext(r.Context()); ok {
+			seen = &user
+		}
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	h(next).ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := NewAuthenticator(AuthConfig{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := "Bearer " + signHS256(t, validClaims())
+	c := validClaims()
+	c.Role = ""
+	reader := "bearer " + signHS256(t, c)
+	requireAdmin := func(next http.Handler) http.Handler { return a.RequireRole("admin", next) }
+
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		auth       string
+		status     int
+		user       bool
+	}{
+		{"require without token", a.Require, "", http.StatusUnauthorized, false},
+		{"require with bad token", a.Require, "Bearer nope", http.StatusUnauthorized, false},
+		{"require with basic auth", a.Require, "Basic YWxpY2U6cHc=", http.StatusUnauthorized, false},
+		{"require", a.Require, valid, http.StatusOK, true},
+		{"optional without token", a.Optional, "", http.StatusOK, false},
+		{"optional with bad token", a.Optional, "Bearer nope", http.StatusUnauthorized, false},
+		{"optional", a.Optional, reader, http.StatusOK, true},
+		{"role missing", requireAdmin, reader, http.StatusForbidden, false},
+		{"role", requireAdmin, valid, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		rec, user := serve(tt.middleware, tt.auth)
+		if rec.Code != tt.status || (user != nil) != tt.user {
+			t.Errorf("%s: status %d, user %v, want %d, user %v", tt.name, rec.Code, user != nil, tt.status, tt.user)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s: no WWW-Authenticate challenge", tt.name)
+		}
+	}
+}
+
+func TestWithoutKeys(t *testing.T) {
+	a, err := NewAuthenticator(AuthConfig{Issuer: "https://id.example.com"})
+	if !errors.Is(err, ErrNoKeys) || a != nil {
+		t.Fatalf("NewAuthenticator = %v, %v, want ErrNoKeys", a, err)
+	}
+
+	token := "Bearer " + signHS256(t, validClaims())
+	requireAdmin := func(next http.Handler) http.Handler { return a.RequireRole("admin", next) }
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		auth       string
+		status     int
+	}{
+		{"require", a.Require, token, http.StatusServiceUnavailable},
+		{"role", requireAdmin, token, http.StatusServiceUnavailable},
+		{"optional without token", a.Optional, "", http.StatusOK},
+		{"optional with token", a.Optional, token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec, user := serve(tt.middleware, tt.auth)
+		if rec.Code != tt.status || user != nil {
+			t.Errorf("%s: status %d, user %v, want %d and no user", tt.name, rec.Code, user != nil, tt.status)
+		}
+	}
+}
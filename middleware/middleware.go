@@ -1,14 +1,21 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"regexp"
 	"time"
+
+	"github.com/chimas/GoProject/logging"
 )
 
 type wrappedWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (w *wrappedWriter) WriteHeader(statusCode int) {
@@ -16,14 +23,98 @@ func (w *wrappedWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 }
 
-func Logging(next http.Handler) http.Handler {
+func (w *wrappedWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection, handlers use
+// it to extend their deadlines.
+func (w *wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MaxBytes limits request bodies to limit bytes. Reading past it fails with
+// *http.MaxBytesError and the connection is closed after the response.
+func MaxBytes(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID keeps IDs from clients short and printable, anything else
+// is replaced rather than echoed into logs and headers.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID takes the X-Request-ID of the request, or generates one, and
+// sets it on the request, the response and the context for logging. Error
+// responses carry it in their requestId field.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+			r.Header.Set("X-Request-ID", id)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// accessEntry collects what inner handlers learn about a request, the
+// authenticated user so far, for the access log line.
+type accessEntry struct {
+	userID string
+}
+
+type accessEntryKey struct{}
+
+func noteUser(ctx context.Context, id string) {
+	if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.userID = id
+	}
+}
+
+// Logging writes an access log line per request with the route pattern
+// rather than the raw path, which may hold names and e-mails, and the
+// user the request authenticated as.
+func Logging(mux *http.ServeMux, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		wrapped := &wrappedWriter{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
-		next.ServeHTTP(wrapped, r)
-		log.Println(wrapped.statusCode, r.Method, r.URL.Path, time.Since(start))
+		entry := &accessEntry{}
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		defer func() {
+			level := slog.LevelInfo
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", wrapped.statusCode),
+				slog.Int64("bytes", wrapped.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			}
+			if entry.userID != "" {
+				attrs = append(attrs, slog.String("user_id", entry.userID))
+			}
+			slog.LogAttrs(r.Context(), level, "request", attrs...)
+		}()
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
 	})
 }
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chimas/GoProject/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"missing", "", false},
+		{"valid", "trace-42:a.b_c", true},
+		{"too long", strings.Repeat("a", 129), false},
+		{"newline", "abc\nforged: 1", false},
+		{"space", "a b", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seenHeader, seenContext string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seenHeader = r.Header.Get("X-Request-ID")
+				seenContext = logging.RequestID(r.Context())
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.header != "" {
+				req.Header["X-Request-Id"] = []string{tc.header}
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-ID")
+			if tc.keep && id != tc.header {
+				t.Errorf("id = %q, want the client's %q", id, tc.header)
+			}
+			if !tc.keep && (id == tc.header || !validRequestID.MatchString(id)) {
+				t.Errorf("id = %q, want a fresh one", id)
+			}
+			if seenHeader != id || seenContext != id {
+				t.Errorf("handler saw %q in the header and %q in the context, want %q", seenHeader, seenContext, id)
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /user/{email}", func(w http.ResponseWriter, r *http.Request) {
+		noteUser(r.Context(), "7")
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h := Logging(mux, mux)
+	for _, target := range []string{"/user/alice@example.com", "/boom", "/nowhere"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+
+	if strings.Contains(buf.String(), "alice") {
+		t.Errorf("access log leaks the path: %s", buf.String())
+	}
+	var lines []map[string]any
+	for _, raw := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var line map[string]any
+		if err := json.Unmarshal(raw, &line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, want 3", len(lines))
+	}
+	want := []struct {
+		route, level, user string
+		status, bytes      float64
+	}{
+		{"GET /user/{email}", "INFO", "7", 200, 5},
+		{"GET /boom", "ERROR", "", 500, 0},
+		{"unmatched", "INFO", "", 404, 19},
+	}
+	for i, w := range want {
+		line := lines[i]
+		user, _ := line["user_id"].(string)
+		if line["route"] != w.route || line["level"] != w.level || user != w.user || line["status"] != w.status || line["bytes"] != w.bytes {
+			t.Errorf("line %d = %v, want %+v", i, line, w)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/chimas/GoProject/db"
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = `usage: migrate up | down [steps] | status | create <name>`
+
+// runMigrate implements the `migrate` subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return exitUsage
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return exitUsage
+		}
+		up, down, err := db.CreateMigration(db.MigrationsDir, args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		fmt.Println("created", up)
+		fmt.Println("created", down)
+		return exitOK
+	}
+
+	// Check the arguments before touching the database, so a typo is a
+	// usage error rather than a connection attempt.
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return exitUsage
+		}
+	case "down":
+		if len(args) > 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return exitUsage
+		}
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "migrate: steps must be a positive number")
+				return exitUsage
+			}
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig("migrate", nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		return configExit(err)
+	}
+	if cfg.DB.URL == "" {
+		fmt.Fprintln(os.Stderr, "migrate: db.url (DB_URL) is empty")
+		return exitConfig
+	}
+	conn, err := db.Connect(context.Background(), cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUnavailable
+	}
+	defer conn.Close()
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			switch {
+			case s.AppliedAt != nil && s.Up == "":
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05") + " (no file)"
+			case s.AppliedAt != nil:
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		tw.Flush()
+	}
+	return exitOK
+}
+
+// migrateOnStart applies pending migrations before the server starts.
+func migrateOnStart(conn *sqlx.DB) error {
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+var stopWords = map[string]struct{}{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		a an and are as at be but by for from has he her his in is it its of on or
+		she that the their they this to was were which who will with
+		а без бы в во вот вы да для до его ее если есть же за и из или их к как
+		ко ли мне мы на над не нет но о об он она они от по под при с со так то
+		ты у уже что это я`) {
+		stopWords[w] = struct{}{}
+	}
+}
+
+// words splits text into lower case words of letters and digits, with ё
+// folded to е.
+func words(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// analyze turns text into index terms: words without stop words, stemmed
+// with the Russian stemmer when Cyrillic and the English one when ASCII.
+func analyze(text string) []string {
+	var terms []string
+	for _, w := range words(text) {
+		if _, ok := stopWords[w]; ok {
+			continue
+		}
+		terms = append(terms, stem(w))
+	}
+	return terms
+}
+
+// stem stems a single lower case word, leaving words in other scripts and
+// words mixing scripts or digits untouched.
+func stem(word string) string {
+	latin, cyrillic := true, true
+	for _, r := range word {
+		latin = latin && r >= 'a' && r <= 'z'
+		cyrillic = cyrillic && unicode.Is(unicode.Cyrillic, r)
+	}
+	switch {
+	case latin:
+		return stemEnglish(word)
+	case cyrillic:
+		return stemRussian(word)
+	}
+	return word
+}
+
+// editDistance is the optimal string alignment distance between a and b in
+// runes: insertions, deletions, substitutions and swaps of adjacent runes
+// each count as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// maxTypos is how many edits a query term of n runes may be away from an
+// indexed term and still match it.
+func maxTypos(n int) int {
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStemEnglish(t *testing.T) {
+	// From the sample vocabulary of Martin Porter's reference
+	// implementation.
+	tests := map[string]string{
+		"caresses": "caress", "ponies": "poni", "ties": "ti", "caress": "caress", "cats": "cat",
+		"feed": "feed", "agreed": "agre", "plastered": "plaster", "motoring": "motor", "sing": "sing",
+		"conflated": "conflat", "troubled": "troubl", "sized": "size", "hopping": "hop", "tanned": "tan",
+		"falling": "fall", "hissing": "hiss", "fizzed": "fizz", "failing": "fail", "filing": "file",
+		"happy": "happi", "sky": "sky", "relational": "relat", "conditional": "condit", "rational": "ration",
+		"valenci": "valenc", "digitizer": "digit", "operator": "oper", "generalization": "gener",
+		"triplicate": "triplic", "formative": "form", "electriciti": "electr", "hopeful": "hope",
+		"goodness": "good", "revival": "reviv", "allowance": "allow", "inference": "infer",
+		"adjustable": "adjust", "effective": "effect", "probate": "probat", "rate": "rate",
+		"cease": "ceas", "controll": "control", "roll": "roll", "is": "is",
+	}
+	for word, want := range tests {
+		if got := stemEnglish(word); got != want {
+			t.Errorf("stemEnglish(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestStemRussian(t *testing.T) {
+	tests := map[string]string{
+		"важнейшие":     "важн",
+		"важными":       "важн",
+		"взволнованный": "взволнова",
+		"вечерами":      "вечер",
+		"годами":        "год",
+		"грустно":       "грустн",
+		"подумали":      "подума",
+		"книги":         "книг",
+		"красивая":      "красив",
+		"ложится":       "лож",
+		"бегающий":      "бега",
+		"гулявшими":     "гуля",
+		"человечество":  "человечеств",
+		"приключения":   "приключен",
+		"я":             "я",
+	}
+	for word, want := range tests {
+		if got := stemRussian(word); got != want {
+			t.Errorf("stemRussian(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The Hunters of the Night", []string{"hunter", "night"}},
+		{"Ёжик в тумане", []string{"ежик", "туман"}},
+		{"Tokyo 2020, R2D2 и Ёж", []string{"tokyo", "2020", "r2d2", "еж"}},
+		{"アキラ", []string{"アキラ"}},
+	}
+	for _, tt := range tests {
+		if got := analyze(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("analyze(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"berserk", "berserk", 0},
+		{"berserk", "bersek", 1},
+		{"berserk", "bresrek", 2},
+		{"monster", "mosnter", 1},
+		{"", "abc", 3},
+		{"наруто", "нарута", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestMaxTypos(t *testing.T) {
+	for n, want := range map[int]int{1: 0, 3: 0, 4: 1, 7: 1, 8: 2, 20: 2} {
+		if got := maxTypos(n); got != want {
+			t.Errorf("maxTypos(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Package search holds the in-process search engine used when Postgres
+// full-text search is not available.
+package search
+
+import (
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/chimas/GoProject/store"
+)
+
+// Field weights applied to term frequencies, mirroring the A, B and C
+// weights of the Postgres search column.
+const (
+	nameWeight     = 3
+	authorWeight   = 2
+	describeWeight = 1
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// fuzzyWeight scales the score of index terms matched with typos.
+	fuzzyWeight = 0.5
+
+	// snippetWords is the length of a SearchHit.Snippet.
+	snippetWords = 30
+)
+
+// Engine is an inverted index over manga name, author and describe ranked
+// with BM25. It implements store.SearchIndex. Only text is indexed; hits
+// are loaded from the MangaStore so ratings and popularity are current.
+type Engine struct {
+	mangas store.MangaStore
+
+	mu   sync.RWMutex
+	docs map[string]*document
+	// postings maps a term to the weighted frequency in every document
+	// containing it, by manga name.
+	postings map[string]map[string]float64
+	totalLen float64
+}
+
+type document struct {
+	id         int
+	popularity int
+	name       string
+	describe   string
+	length     float64
+	terms      map[string]float64
+}
+
+func NewEngine(mangas store.MangaStore) *Engine {
+	return &Engine{
+		mangas:   mangas,
+		docs:     map[string]*document{},
+		postings: map[string]map[string]float64{},
+	}
+}
+
+func newDocument(manga store.Manga) *document {
+	d := &document{
+		id:         manga.Id,
+		popularity: manga.Popularity,
+		name:       manga.Name,
+		describe:   manga.Describe,
+		terms:      map[string]float64{},
+	}
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{
+		{manga.Name, nameWeight},
+		{manga.Author, authorWeight},
+		{manga.Describe, describeWeight},
+	} {
+		for _, t := range analyze(field.text) {
+			d.terms[t] += field.weight
+			d.length += field.weight
+		}
+	}
+	return d
+}
+
+// Rebuild replaces the index with every manga in the store.
+func (e *Engine) Rebuild(ctx context.Context) error {
+	mangas, err := e.mangas.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	fresh := NewEngine(e.mangas)
+	for _, manga := range mangas {
+		fresh.add(newDocument(manga))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.docs, e.postings, e.totalLen = fresh.docs, fresh.postings, fresh.totalLen
+	return nil
+}
+
+func (e *Engine) Index(ctx context.Context, manga store.Manga) error {
+	d := newDocument(manga)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(manga.Name)
+	e.add(d)
+	return nil
+}
+
+func (e *Engine) Remove(ctx context.Context, name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(name)
+	return nil
+}
+
+// add and remove must be called with e.mu held.
+func (e *Engine) add(d *document) {
+	e.docs[d.name] = d
+	e.totalLen += d.length
+	for t, tf := range d.terms {
+		posting, ok := e.postings[t]
+		if !ok {
+			posting = map[string]float64{}
+			e.postings[t] = posting
+		}
+		posting[d.name] = tf
+	}
+}
+
+func (e *Engine) remove(name string) {
+	d, ok := e.docs[name]
+	if !ok {
+		return
+	}
+	delete(e.docs, name)
+	e.totalLen -= d.length
+	for t := range d.terms {
+		delete(e.postings[t], name)
+		if len(e.postings[t]) == 0 {
+			delete(e.postings, t)
+		}
+	}
+}
+
+// queryTerms maps the analyzed query to index terms and their weights.
+// Terms missing from the index match indexed terms within maxTypos edits.
+// It must be called with e.mu held.
+func (e *Engine) queryTerms(query string) map[string]float64 {
+	terms := map[string]float64{}
+	for _, t := range analyze(query) {
+		if _, ok := e.postings[t]; ok {
+			terms[t] = 1
+			continue
+		}
+		typos := maxTypos(utf8.RuneCountInString(t))
+		if typos == 0 {
+			continue
+		}
+		for indexed := range e.postings {
+			if abs(utf8.RuneCountInString(indexed)-utf8.RuneCountInString(t)) > typos {
+				continue
+			}
+			if editDistance(t, indexed) <= typos && terms[indexed] < fuzzyWeight {
+				terms[indexed] = fuzzyWeight
+			}
+		}
+	}
+	return terms
+}
+
+func (e *Engine) Search(ctx context.Context, q store.SearchQuery) (store.Page[store.SearchHit], error) {
+	type scored struct {
+		doc   *document
+		score float64
+	}
+
+	e.mu.RLock()
+	terms := e.queryTerms(q.Query)
+	scores := map[string]float64{}
+	if n := float64(len(e.docs)); n > 0 {
+		avgLen := e.totalLen / n
+		for t, weight := range terms {
+			posting := e.postings[t]
+			df := float64(len(posting))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for name, tf := range posting {
+				norm := bm25K1 * (1 - bm25B + bm25B*e.docs[name].length/avgLen)
+				scores[name] += weight * idf * tf * (bm25K1 + 1) / (tf + norm)
+			}
+		}
+	}
+	ranked := make([]scored, 0, len(scores))
+	for name, score := range scores {
+		ranked = append(ranked, scored{doc: e.docs[name], score: score})
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].doc.id < ranked[j].doc.id
+	})
+	total := len(ranked)
+	if q.PerPage > 0 {
+		start := min((q.Page-1)*q.PerPage, total)
+		ranked = ranked[start:min(start+q.PerPage, total)]
+	}
+	if len(ranked) == 0 {
+		return store.NewSearchPage(nil, total, q), nil
+	}
+
+	names := make([]string, len(ranked))
+	for i, r := range ranked {
+		names[i] = r.doc.name
+	}
+	page, err := e.mangas.Filter(ctx, store.MangaFilter{Names: names})
+	if err != nil {
+		return store.Page[store.SearchHit]{}, err
+	}
+	byName := make(map[string]store.Manga, len(page.Items))
+	for _, manga := range page.Items {
+		byName[manga.Name] = manga
+	}
+
+	hits := make([]store.SearchHit, 0, len(ranked))
+	for _, r := range ranked {
+		manga, ok := byName[r.doc.name]
+		if !ok {
+			// Deleted since it was indexed.
+			continue
+		}
+		hits = append(hits, store.SearchHit{Manga: manga, Rank: r.score, Snippet: snippet(r.doc.describe, terms)})
+	}
+	return store.NewSearchPage(hits, total, q), nil
+}
+
+// snippet returns up to snippetWords words of text around the first word
+// matching terms, HTML-escaped, with matching words highlighted.
+func snippet(text string, terms map[string]float64) string {
+	fields := strings.Fields(text)
+	matches := func(word string) bool {
+		for _, t := range analyze(word) {
+			if _, ok := terms[t]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := 0
+	for i, w := range fields {
+		if matches(w) {
+			start = max(i-snippetWords/4, 0)
+			break
+		}
+	}
+	end := min(start+snippetWords, len(fields))
+
+	out := make([]string, 0, end-start)
+	for _, w := range fields[start:end] {
+		if matches(w) {
+			out = append(out, store.HighlightStart+html.EscapeString(w)+store.HighlightStop)
+		} else {
+			out = append(out, html.EscapeString(w))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// Suggest ranks names starting with prefix first, then names with a word
+// starting with it, then names with a word within maxTypos of it; ties go
+// to the more popular manga.
+func (e *Engine) Suggest(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.Join(words(prefix), " ")
+	prefixLen := utf8.RuneCountInString(prefix)
+	typos := maxTypos(prefixLen)
+	type suggestion struct {
+		doc   *document
+		class int
+	}
+
+	e.mu.RLock()
+	var found []suggestion
+	for _, d := range e.docs {
+		nameWords := words(d.name)
+		name := strings.Join(nameWords, " ")
+		switch {
+		case prefix == "":
+		case strings.HasPrefix(name, prefix):
+			found = append(found, suggestion{doc: d, class: 0})
+		case strings.Contains(name, " "+prefix):
+			found = append(found, suggestion{doc: d, class: 1})
+		case typos > 0:
+			for _, w := range nameWords {
+				if r := []rune(w); len(r) >= prefixLen && editDistance(string(r[:prefixLen]), prefix) <= typos {
+					found = append(found, suggestion{doc: d, class: 2})
+					break
+				}
+			}
+		}
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.class != b.class {
+			return a.class < b.class
+		}
+		if a.doc.popularity != b.doc.popularity {
+			return a.doc.popularity > b.doc.popularity
+		}
+		return a.doc.id < b.doc.id
+	})
+	names := []string{}
+	for i := 0; i < len(found) && i < limit; i++ {
+		names = append(names, found[i].doc.name)
+	}
+	return names, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
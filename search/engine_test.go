@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chimas/GoProject/store"
+)
+
+func newTestEngine(t *testing.T, mangas ...store.Manga) (*Engine, *store.Memory) {
+	t.Helper()
+	mem := store.NewMemory()
+	for _, m := range mangas {
+		mem.AddManga(m)
+	}
+	e := NewEngine(mem)
+	if err := e.Rebuild(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return e, mem
+}
+
+func hitNames(page store.Page[store.SearchHit]) []string {
+	var names []string
+	for _, h := range page.Items {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+var testMangas = []store.Manga{
+	{Id: 1, Name: "Berserk", Author: "Kentaro Miura", Describe: "Guts, a lone mercenary, hunts demons.", Popularity: 50},
+	{Id: 2, Name: "Monster", Author: "Naoki Urasawa", Describe: "A surgeon hunts the monster he once saved.", Popularity: 40},
+	{Id: 3, Name: "Vagabond", Author: "Takehiko Inoue", Describe: "A swordsman wanders Japan.", Popularity: 30},
+	{Id: 4, Name: "Monster Musume", Author: "Okayado", Describe: "Everyday life with monster girls.", Popularity: 60},
+}
+
+func TestSearch(t *testing.T) {
+	e, _ := newTestEngine(t, testMangas...)
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Matches in the name outweigh matches in describe.
+		{"monster", []string{"Monster", "Monster Musume"}},
+		{"hunting", []string{"Berserk", "Monster"}},
+		{"urasawa", []string{"Monster"}},
+		// Misspelled terms still match, at a lower weight.
+		{"vagabnd", []string{"Vagabond"}},
+		{"the of", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		page, err := e.Search(ctx, store.SearchQuery{Query: tt.query, Page: 1, PerPage: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := hitNames(page)
+		if tt.query == "hunting" {
+			slices.Sort(got)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		if page.Total != len(tt.want) {
+			t.Errorf("Search(%q).Total = %d, want %d", tt.query, page.Total, len(tt.want))
+		}
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	e, _ := newTestEngine(t, testMangas...)
+	page, err := e.Search(context.Background(), store.SearchQuery{Query: "monster", Page: 2, PerPage: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hitNames(page); !slices.Equal(got, []string{"Monster Musume"}) || page.Total != 2 {
+		t.Errorf("page 2 = %v of %d, want [Monster Musume] of 2", got, page.Total)
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	e, _ := newTestEngine(t, store.Manga{Id: 1, Name: "Tag", Describe: `A <script>alert(1)</script> story of hunters & "prey".`})
+	page, err := e.Search(context.Background(), store.SearchQuery{Query: "hunter", Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("got %d hits, want 1", len(page.Items))
+	}
+	want := `A &lt;script&gt;alert(1)&lt;/script&gt; story of <mark>hunters</mark> &amp; &#34;prey&#34;.`
+	if got := page.Items[0].Snippet; got != want {
+		t.Errorf("Snippet = %q, want %q", got, want)
+	}
+}
+
+func TestSnippetWindow(t *testing.T) {
+	text := strings.Repeat("filler ", 50) + "target " + strings.Repeat("tail ", 50)
+	got := strings.Fields(snippet(text, map[string]float64{"target": 1}))
+	if len(got) != snippetWords {
+		t.Fatalf("snippet has %d words, want %d", len(got), snippetWords)
+	}
+	if i := slices.Index(got, "<mark>target</mark>"); i != snippetWords/4 {
+		t.Errorf("match is word %d of the snippet, want %d", i, snippetWords/4)
+	}
+}
+
+func TestIndexAndRemove(t *testing.T) {
+	e, mem := newTestEngine(t, testMangas...)
+	ctx := context.Background()
+
+	added := store.Manga{Id: 5, Name: "Blame!", Describe: "A silent wanderer climbs the megastructure."}
+	mem.AddManga(added)
+	if err := e.Index(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	page, err := e.Search(ctx, store.SearchQuery{Query: "megastructure", Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hitNames(page); !slices.Equal(got, []string{"Blame!"}) {
+		t.Errorf("after Index: %v, want [Blame!]", got)
+	}
+
+	// Reindexing replaces the old terms.
+	added.Describe = "A silent wanderer."
+	if err := e.Index(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := e.Search(ctx, store.SearchQuery{Query: "megastructure", Page: 1, PerPage: 10}); page.Total != 0 {
+		t.Errorf("after reindexing: %v, want no hits", hitNames(page))
+	}
+
+	if err := e.Remove(ctx, "Monster"); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := e.Search(ctx, store.SearchQuery{Query: "monster", Page: 1, PerPage: 10}); !slices.Equal(hitNames(page), []string{"Monster Musume"}) {
+		t.Errorf("after Remove: %v, want [Monster Musume]", hitNames(page))
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	e, _ := newTestEngine(t, testMangas...)
+	ctx := context.Background()
+
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		// Prefix matches by popularity.
+		{"mon", 10, []string{"Monster Musume", "Monster"}},
+		{"MONSTER m", 10, []string{"Monster Musume"}},
+		// Then names with a word starting with the prefix.
+		{"mus", 10, []string{"Monster Musume"}},
+		// Then names with a word close to it.
+		{"vaga", 10, []string{"Vagabond"}},
+		{"vega", 10, []string{"Vagabond"}},
+		{"mon", 1, []string{"Monster Musume"}},
+		{"", 10, []string{}},
+		{"xyz", 10, []string{}},
+	}
+	for _, tt := range tests {
+		got, err := e.Suggest(ctx, tt.prefix, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Suggest(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package search
+
+// stemEnglish implements the original Porter stemming algorithm for lower
+// case ASCII words, following Martin Porter's reference C implementation.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	z := &porter{b: []byte(word), k: len(word) - 1}
+	z.step1ab()
+	if z.k > 0 {
+		z.step1c()
+		z.step2()
+		z.step3()
+		z.step4()
+		z.step5()
+	}
+	return string(z.b[:z.k+1])
+}
+
+// porter holds the word being stemmed in b[0..k], j marks the end of the
+// stem found by the last call to ends.
+type porter struct {
+	b    []byte
+	k, j int
+}
+
+// cons reports whether b[i] is a consonant.
+func (z *porter) cons(i int) bool {
+	switch z.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !z.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of vowel-consonant sequences in b[0..j].
+func (z *porter) m() int {
+	n, i := 0, 0
+	for {
+		if i > z.j {
+			return n
+		}
+		if !z.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > z.j {
+				return n
+			}
+			if z.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > z.j {
+				return n
+			}
+			if !z.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+func (z *porter) vowelInStem() bool {
+	for i := 0; i <= z.j; i++ {
+		if !z.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC reports whether b[j-1..j] is a double consonant.
+func (z *porter) doubleC(j int) bool {
+	return j >= 1 && z.b[j] == z.b[j-1] && z.cons(j)
+}
+
+// cvc reports whether b[i-2..i] is consonant-vowel-consonant and the last
+// consonant is not w, x or y.
+func (z *porter) cvc(i int) bool {
+	if i < 2 || !z.cons(i) || z.cons(i-1) || !z.cons(i-2) {
+		return false
+	}
+	switch z.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func (z *porter) ends(s string) bool {
+	l := len(s)
+	if l > z.k+1 || string(z.b[z.k-l+1:z.k+1]) != s {
+		return false
+	}
+	z.j = z.k - l
+	return true
+}
+
+// setTo replaces b[j+1..k] with s.
+func (z *porter) setTo(s string) {
+	z.b = append(z.b[:z.j+1], s...)
+	z.k = z.j + len(s)
+}
+
+func (z *porter) r(s string) {
+	if z.m() > 0 {
+		z.setTo(s)
+	}
+}
+
+// replaceFirst applies the first rule whose suffix matches.
+func (z *porter) replaceFirst(rules [][2]string) {
+	for _, rule := range rules {
+		if z.ends(rule[0]) {
+			z.r(rule[1])
+			return
+		}
+	}
+}
+
+// step1ab removes plurals and -ed or -ing.
+func (z *porter) step1ab() {
+	if z.b[z.k] == 's' {
+		switch {
+		case z.ends("sses"):
+			z.k -= 2
+		case z.ends("ies"):
+			z.setTo("i")
+		case z.b[z.k-1] != 's':
+			z.k--
+		}
+	}
+	if z.ends("eed") {
+		if z.m() > 0 {
+			z.k--
+		}
+	} else if (z.ends("ed") || z.ends("ing")) && z.vowelInStem() {
+		z.k = z.j
+		switch {
+		case z.ends("at"):
+			z.setTo("ate")
+		case z.ends("bl"):
+			z.setTo("ble")
+		case z.ends("iz"):
+			z.setTo("ize")
+		case z.doubleC(z.k):
+			z.k--
+			switch z.b[z.k] {
+			case 'l', 's', 'z':
+				z.k++
+			}
+		default:
+			z.j = z.k
+			if z.m() == 1 && z.cvc(z.k) {
+				z.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem.
+func (z *porter) step1c() {
+	if z.ends("y") && z.vowelInStem() {
+		z.b[z.k] = 'i'
+	}
+}
+
+var porterStep2 = map[byte][][2]string{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// step2 maps double suffixes to single ones.
+func (z *porter) step2() {
+	z.replaceFirst(porterStep2[z.b[z.k-1]])
+}
+
+var porterStep3 = map[byte][][2]string{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// step3 deals with -ic-, -full, -ness etc.
+func (z *porter) step3() {
+	z.replaceFirst(porterStep3[z.b[z.k]])
+}
+
+var porterStep4 = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step4 removes -ant, -ence etc. in context <c>vcvc<v>.
+func (z *porter) step4() {
+	found := false
+	if z.b[z.k-1] == 'o' {
+		found = z.ends("ion") && z.j >= 0 && (z.b[z.j] == 's' || z.b[z.j] == 't') || z.ends("ou")
+	} else {
+		for _, s := range porterStep4[z.b[z.k-1]] {
+			if z.ends(s) {
+				found = true
+				break
+			}
+		}
+	}
+	if found && z.m() > 1 {
+		z.k = z.j
+	}
+}
+
+// step5 removes a final -e and changes -ll to -l when m() > 1.
+func (z *porter) step5() {
+	z.j = z.k
+	if z.b[z.k] == 'e' {
+		if a := z.m(); a > 1 || a == 1 && !z.cvc(z.k-1) {
+			z.k--
+		}
+	}
+	if z.b[z.k] == 'l' && z.doubleC(z.k) && z.m() > 1 {
+		z.k--
+	}
+}
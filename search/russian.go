@@ -0,0 +1,130 @@
+package search
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Ending groups of the Snowball Russian stemmer. Endings in the *AYa groups
+// only match after а or я, which stays part of the stem.
+var (
+	ruPerfectiveGerundAYa = []string{"в", "вши", "вшись"}
+	ruPerfectiveGerund    = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	ruAdjective           = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	ruParticipleAYa       = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple          = []string{"ивш", "ывш", "ующ"}
+	ruReflexive           = []string{"ся", "сь"}
+	ruVerbAYa             = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	ruVerb                = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	ruNoun                = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	ruSuperlative         = []string{"ейш", "ейше"}
+	ruDerivational        = []string{"ост", "ость"}
+)
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// stemRussian implements the Snowball Russian stemming algorithm for lower
+// case words. ё is folded to е by the analyzer beforehand.
+func stemRussian(word string) string {
+	rv, r2 := russianRegions(word)
+	if rv >= len(word) {
+		return word
+	}
+	s := &ruStem{word: word, rv: rv}
+
+	// Step 1.
+	if !s.cut(ruPerfectiveGerundAYa, true) && !s.cut(ruPerfectiveGerund, false) {
+		s.cut(ruReflexive, false)
+		if s.cut(ruAdjective, false) {
+			if !s.cut(ruParticipleAYa, true) {
+				s.cut(ruParticiple, false)
+			}
+		} else if !s.cut(ruVerbAYa, true) && !s.cut(ruVerb, false) {
+			s.cut(ruNoun, false)
+		}
+	}
+
+	// Step 2.
+	s.cut([]string{"и"}, false)
+
+	// Step 3: derivational endings count only inside R2.
+	if r2 < len(s.word) {
+		rvWas := s.rv
+		s.rv = max(r2, rvWas)
+		s.cut(ruDerivational, false)
+		s.rv = rvWas
+	}
+
+	// Step 4.
+	switch {
+	case s.cut([]string{"нн"}, false):
+		s.word += "н"
+	case s.cut(ruSuperlative, false):
+		if s.cut([]string{"нн"}, false) {
+			s.word += "н"
+		}
+	default:
+		s.cut([]string{"ь"}, false)
+	}
+	return s.word
+}
+
+// ruStem is a word being stemmed; endings are only removed inside
+// word[rv:].
+type ruStem struct {
+	word string
+	rv   int
+}
+
+// cut removes the longest of endings found inside RV and reports whether it
+// removed one. With afterAYa the ending must follow а or я, also inside RV.
+func (s *ruStem) cut(endings []string, afterAYa bool) bool {
+	best := ""
+	for _, e := range endings {
+		if len(e) <= len(best) || !strings.HasSuffix(s.word, e) {
+			continue
+		}
+		start := len(s.word) - len(e)
+		if start < s.rv {
+			continue
+		}
+		if afterAYa {
+			prev, size := utf8.DecodeLastRuneInString(s.word[:start])
+			if start-size < s.rv || prev != 'а' && prev != 'я' {
+				continue
+			}
+		}
+		best = e
+	}
+	if best == "" {
+		return false
+	}
+	s.word = s.word[:len(s.word)-len(best)]
+	return true
+}
+
+// russianRegions returns the byte offsets where RV and R2 start. RV follows
+// the first vowel. R1 follows the first consonant after a vowel and R2 the
+// next such consonant, which is R1 of R1.
+func russianRegions(word string) (rv, r2 int) {
+	rv, r2 = len(word), len(word)
+	prevVowel := false
+	transitions := 0
+	for i, r := range word {
+		vowel := isRussianVowel(r)
+		if vowel && rv == len(word) {
+			rv = i + utf8.RuneLen(r)
+		}
+		if !vowel && prevVowel {
+			transitions++
+			if transitions == 2 {
+				r2 = i + utf8.RuneLen(r)
+				break
+			}
+		}
+		prevVowel = vowel
+	}
+	return rv, r2
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version string
+
+// buildVersion is version, else the VCS revision go build recorded.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "dev"
+}
+
+// Exit codes of the server and the subcommands, taken from sysexits.h so
+// that a supervisor can tell a bad deploy from an unreachable dependency.
+const (
+	exitOK          = 0
+	exitFailure     = 1  // the command failed, or requests outlived the grace period
+	exitUsage       = 2  // invalid command-line flags
+	exitUnavailable = 69 // Postgres could not be reached or migrated
+	exitListen      = 71 // the port could not be bound
+	exitConfig      = 78 // invalid configuration or seed file
+)
+
+// closers releases what the server opened, last opened first, once it no
+// longer takes requests.
+type closers []namedCloser
+
+type namedCloser struct {
+	name  string
+	close func() error
+}
+
+func (c *closers) add(name string, close func() error) {
+	*c = append(*c, namedCloser{name: name, close: close})
+}
+
+func (c *closers) closeAll() {
+	for i := len(*c) - 1; i >= 0; i-- {
+		if err := (*c)[i].close(); err != nil {
+			slog.Error("Unable to close", "resource", (*c)[i].name, "error", err)
+		}
+	}
+}
+
+// serve runs server on ln until ctx is done, then stops accepting
+// connections and gives in-flight requests up to grace to finish. It
+// returns the exit code.
+func serve(ctx context.Context, server *http.Server, ln net.Listener, grace time.Duration) int {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server failed", "error", err)
+			return exitFailure
+		}
+		return exitOK
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, waiting for requests in flight", "grace", grace.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Requests still running after the grace period", "error", err)
+		server.Close()
+		return exitFailure
+	}
+	return exitOK
+}
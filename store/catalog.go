@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidOrder is returned by ReorderPages when the order is not a
+// permutation of the chapter's page indexes.
+var ErrInvalidOrder = errors.New("store: order must list every page index exactly once")
+
+// CatalogStore creates, edits and deletes rows of "Anime" and "Chapter".
+// AverageRating, RatingCount and Popularity are derived from ratings and
+// favorites and are never written through it.
+type CatalogStore interface {
+	// CreateManga inserts manga and returns it with its id. It returns
+	// ErrConflict when the name is taken.
+	CreateManga(ctx context.Context, manga Manga) (Manga, error)
+	// UpdateManga replaces the editable fields of the manga called name.
+	// Renaming carries chapters, progress and ratings over to the new name.
+	UpdateManga(ctx context.Context, name string, manga Manga) (Manga, error)
+	// DeleteManga deletes the manga with its chapters, progress, ratings
+	// and favorites.
+	DeleteManga(ctx context.Context, name string) error
+
+	// CreateChapter returns ErrConflict when the chapter number is taken.
+	CreateChapter(ctx context.Context, c Chapter) (Chapter, error)
+	// UpdateChapter replaces the name and pages of a chapter. Numbers are
+	// changed with RenumberChapters.
+	UpdateChapter(ctx context.Context, animeName string, chapter int, c Chapter) (Chapter, error)
+	DeleteChapter(ctx context.Context, animeName string, chapter int) error
+	// RenumberChapters moves every chapter numbered by a key of numbers to
+	// the value at once, so numbers may be swapped. Read marks and reading
+	// progress follow their chapter. It returns the manga's chapters.
+	RenumberChapters(ctx context.Context, animeName string, numbers map[int]int) ([]Chapter, error)
+	// ReorderPages rearranges the chapter's Img so that page i is the page
+	// previously at order[i].
+	ReorderPages(ctx context.Context, animeName string, chapter int, order []int) (Chapter, error)
+}
+
+// checkRenumber validates numbers against the manga's current chapter
+// numbers: every key must exist and no two chapters may end up with the
+// same number.
+func checkRenumber(existing []int, numbers map[int]int) error {
+	final := map[int]bool{}
+	moved := 0
+	for _, n := range existing {
+		if to, ok := numbers[n]; ok {
+			n = to
+			moved++
+		}
+		if final[n] {
+			return ErrConflict
+		}
+		final[n] = true
+	}
+	if moved != len(numbers) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// reorder returns pages rearranged by order.
+func reorder(pages []string, order []int) ([]string, error) {
+	if len(order) != len(pages) {
+		return nil, ErrInvalidOrder
+	}
+	seen := make([]bool, len(pages))
+	out := make([]string, len(pages))
+	for i, from := range order {
+		if from < 0 || from >= len(pages) || seen[from] {
+			return nil, ErrInvalidOrder
+		}
+		seen[from] = true
+		out[i] = pages[from]
+	}
+	return out, nil
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *Memory) CreateManga(ctx context.Context, manga Manga) (Manga, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mangaIndex(manga.Name) >= 0 {
+		return Manga{}, ErrConflict
+	}
+	manga.Id = m.nextId
+	m.nextId++
+	manga.AverageRating, manga.RatingCount, manga.Popularity = 0, 0, 0
+	manga.Chapters = nil
+	m.mangas = append(m.mangas, manga)
+	return manga, nil
+}
+
+func (m *Memory) UpdateManga(ctx context.Context, name string, manga Manga) (Manga, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.mangaIndex(name)
+	if i < 0 {
+		return Manga{}, ErrNotFound
+	}
+	if manga.Name != name && m.mangaIndex(manga.Name) >= 0 {
+		return Manga{}, ErrConflict
+	}
+
+	old := m.mangas[i]
+	manga.Id = old.Id
+	manga.AverageRating, manga.RatingCount, manga.Popularity = old.AverageRating, old.RatingCount, old.Popularity
+	manga.Chapters = nil
+	m.mangas[i] = manga
+	if manga.Name != name {
+		m.rename(name, manga.Name)
+	}
+	return manga, nil
+}
+
+// rename moves everything stored by manga name. It must be called with
+// m.mu held.
+func (m *Memory) rename(from, to string) {
+	if chapters, ok := m.chapters[from]; ok {
+		for i := range chapters {
+			chapters[i].AnimeName = to
+		}
+		m.chapters[to] = chapters
+		delete(m.chapters, from)
+	}
+	for key, p := range m.progress {
+		if key.animeName == from {
+			delete(m.progress, key)
+			p.AnimeName = to
+			m.progress[userMangaKey{key.userId, to}] = p
+		}
+	}
+	for key, read := range m.read {
+		if key.animeName == from {
+			delete(m.read, key)
+			m.read[userMangaKey{key.userId, to}] = read
+		}
+	}
+	for key, r := range m.ratings {
+		if key.animeName == from {
+			delete(m.ratings, key)
+			r.AnimeName = to
+			m.ratings[userMangaKey{key.userId, to}] = r
+		}
+	}
+}
+
+func (m *Memory) DeleteManga(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.mangaIndex(name)
+	if i < 0 {
+		return ErrNotFound
+	}
+	id := m.mangas[i].Id
+	m.mangas = append(m.mangas[:i], m.mangas[i+1:]...)
+	delete(m.chapters, name)
+	for key := range m.progress {
+		if key.animeName == name {
+			delete(m.progress, key)
+		}
+	}
+	for key := range m.read {
+		if key.animeName == name {
+			delete(m.read, key)
+		}
+	}
+	for key := range m.ratings {
+		if key.animeName == name {
+			delete(m.ratings, key)
+		}
+	}
+	for userId, ids := range m.favorites {
+		kept := ids[:0]
+		for _, favId := range ids {
+			if favId != id {
+				kept = append(kept, favId)
+			}
+		}
+		m.favorites[userId] = kept
+	}
+	return nil
+}
+
+// chapterIndex must be called with m.mu held.
+func (m *Memory) chapterIndex(animeName string, chapter int) int {
+	for i, c := range m.chapters[animeName] {
+		if c.Chapter == chapter {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Memory) CreateChapter(ctx context.Context, c Chapter) (Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mangaIndex(c.AnimeName) < 0 {
+		return c, ErrNotFound
+	}
+	if m.chapterIndex(c.AnimeName, c.Chapter) >= 0 {
+		return c, ErrConflict
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	m.chapters[c.AnimeName] = append(m.chapters[c.AnimeName], c)
+	return c, nil
+}
+
+func (m *Memory) UpdateChapter(ctx context.Context, animeName string, chapter int, c Chapter) (Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chapterIndex(animeName, chapter)
+	if i < 0 {
+		return Chapter{}, ErrNotFound
+	}
+	updated := &m.chapters[animeName][i]
+	updated.Name = c.Name
+	updated.Img = append(updated.Img[:0:0], c.Img...)
+	return *updated, nil
+}
+
+func (m *Memory) DeleteChapter(ctx context.Context, animeName string, chapter int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chapterIndex(animeName, chapter)
+	if i < 0 {
+		return ErrNotFound
+	}
+	chapters := m.chapters[animeName]
+	m.chapters[animeName] = append(chapters[:i], chapters[i+1:]...)
+	for key, read := range m.read {
+		if key.animeName == animeName {
+			delete(read, chapter)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) RenumberChapters(ctx context.Context, animeName string, numbers map[int]int) ([]Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chapters := m.chapters[animeName]
+	existing := make([]int, len(chapters))
+	for i, c := range chapters {
+		existing[i] = c.Chapter
+	}
+	if err := checkRenumber(existing, numbers); err != nil {
+		return nil, err
+	}
+
+	for i := range chapters {
+		if to, ok := numbers[chapters[i].Chapter]; ok {
+			chapters[i].Chapter = to
+		}
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Chapter < chapters[j].Chapter })
+	for key, read := range m.read {
+		if key.animeName != animeName {
+			continue
+		}
+		moved := map[int]struct{}{}
+		for c := range read {
+			if to, ok := numbers[c]; ok {
+				c = to
+			}
+			moved[c] = struct{}{}
+		}
+		m.read[key] = moved
+	}
+	for key, p := range m.progress {
+		if to, ok := numbers[p.Chapter]; ok && key.animeName == animeName {
+			p.Chapter = to
+			m.progress[key] = p
+		}
+	}
+	return append([]Chapter(nil), chapters...), nil
+}
+
+func (m *Memory) ReorderPages(ctx context.Context, animeName string, chapter int, order []int) (Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chapterIndex(animeName, chapter)
+	if i < 0 {
+		return Chapter{}, ErrNotFound
+	}
+	c := &m.chapters[animeName][i]
+	pages, err := reorder(c.Img, order)
+	if err != nil {
+		return Chapter{}, err
+	}
+	c.Img = pages
+	return *c, nil
+}
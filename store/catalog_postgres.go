@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// nonNil keeps NOT NULL text[] columns from receiving NULL.
+func nonNil(a pq.StringArray) pq.StringArray {
+	if a == nil {
+		return pq.StringArray{}
+	}
+	return a
+}
+
+func (p *Postgres) CreateManga(ctx context.Context, manga Manga) (Manga, error) {
+	manga.AverageRating, manga.RatingCount, manga.Popularity = 0, 0, 0
+	manga.Genres = nonNil(manga.Genres)
+	err := p.db.GetContext(ctx, &manga.Id, `
+		INSERT INTO "Anime" (name, img, "imgHeader", describe, genres, author, country, published, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		manga.Name, manga.Img, manga.ImgHeader, manga.Describe, manga.Genres, manga.Author, manga.Country, manga.Published, manga.Status)
+	return manga, wrapErr(err)
+}
+
+func (p *Postgres) UpdateManga(ctx context.Context, name string, manga Manga) (Manga, error) {
+	var updated Manga
+	// Renames reach "Chapter", "ReadingProgress", "ChapterRead" and
+	// "Rating" through their ON UPDATE CASCADE foreign keys.
+	err := p.db.GetContext(ctx, &updated, `
+		UPDATE "Anime" SET name = $1, img = $2, "imgHeader" = $3, describe = $4, genres = $5,
+			author = $6, country = $7, published = $8, status = $9
+		WHERE name = $10
+		RETURNING `+mangaColumns,
+		manga.Name, manga.Img, manga.ImgHeader, manga.Describe, nonNil(manga.Genres),
+		manga.Author, manga.Country, manga.Published, manga.Status, name)
+	return updated, wrapErr(err)
+}
+
+func (p *Postgres) DeleteManga(ctx context.Context, name string) error {
+	return p.inTx(ctx, func(tx *sqlx.Tx) error {
+		var id int
+		if err := tx.GetContext(ctx, &id, `SELECT id FROM "Anime" WHERE name = $1 FOR UPDATE`, name); err != nil {
+			return wrapErr(err)
+		}
+		// Dependent rows are deleted explicitly so databases created before
+		// the migrations, without ON DELETE CASCADE, behave the same.
+		for _, query := range []string{
+			`DELETE FROM "ChapterRead" WHERE "animeName" = $1`,
+			`DELETE FROM "ReadingProgress" WHERE "animeName" = $1`,
+			`DELETE FROM "Rating" WHERE "animeName" = $1`,
+			`DELETE FROM "Chapter" WHERE "animeName" = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, name); err != nil {
+				return wrapErr(err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "Favorite" WHERE "animeId" = $1`, id); err != nil {
+			return wrapErr(err)
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM "Anime" WHERE id = $1`, id)
+		return wrapErr(err)
+	})
+}
+
+func (p *Postgres) CreateChapter(ctx context.Context, c Chapter) (Chapter, error) {
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	c.Img = nonNil(c.Img)
+	ok, err := affected(p.db.ExecContext(ctx, `
+		INSERT INTO "Chapter" (chapter, img, name, "animeName", "createdAt")
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM "Chapter" WHERE "animeName" = $4 AND chapter = $1)`,
+		c.Chapter, c.Img, c.Name, c.AnimeName, c.CreatedAt))
+	if err != nil {
+		return c, err
+	}
+	if !ok {
+		return c, ErrConflict
+	}
+	return c, nil
+}
+
+func (p *Postgres) UpdateChapter(ctx context.Context, animeName string, chapter int, c Chapter) (Chapter, error) {
+	var updated Chapter
+	err := p.db.GetContext(ctx, &updated, `
+		UPDATE "Chapter" SET name = $1, img = $2
+		WHERE "animeName" = $3 AND chapter = $4
+		RETURNING `+chapterColumns,
+		c.Name, nonNil(c.Img), animeName, chapter)
+	return updated, wrapErr(err)
+}
+
+func (p *Postgres) DeleteChapter(ctx context.Context, animeName string, chapter int) error {
+	return p.inTx(ctx, func(tx *sqlx.Tx) error {
+		ok, err := affected(tx.ExecContext(ctx, `DELETE FROM "Chapter" WHERE "animeName" = $1 AND chapter = $2`, animeName, chapter))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotFound
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM "ChapterRead" WHERE "animeName" = $1 AND chapter = $2`, animeName, chapter)
+		return wrapErr(err)
+	})
+}
+
+func (p *Postgres) RenumberChapters(ctx context.Context, animeName string, numbers map[int]int) ([]Chapter, error) {
+	var chapters []Chapter
+	err := p.inTx(ctx, func(tx *sqlx.Tx) error {
+		var existing []int
+		err := tx.SelectContext(ctx, &existing, `SELECT chapter FROM "Chapter" WHERE "animeName" = $1 FOR UPDATE`, animeName)
+		if err != nil {
+			return wrapErr(err)
+		}
+		if err := checkRenumber(existing, numbers); err != nil {
+			return err
+		}
+
+		from := make([]int64, 0, len(numbers))
+		to := make([]int64, 0, len(numbers))
+		for f, t := range numbers {
+			from = append(from, int64(f))
+			to = append(to, int64(t))
+		}
+		for _, table := range []string{`"Chapter"`, `"ChapterRead"`, `"ReadingProgress"`} {
+			// Moving through negative numbers keeps unique constraints
+			// satisfied while chapters swap numbers.
+			_, err := tx.ExecContext(ctx, `
+				UPDATE `+table+` t SET chapter = -m.new
+				FROM unnest($2::int[], $3::int[]) AS m(old, new)
+				WHERE t."animeName" = $1 AND t.chapter = m.old`,
+				animeName, pq.Array(from), pq.Array(to))
+			if err != nil {
+				return wrapErr(err)
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE `+table+` SET chapter = -chapter WHERE "animeName" = $1 AND chapter < 0`, animeName)
+			if err != nil {
+				return wrapErr(err)
+			}
+		}
+
+		err = tx.SelectContext(ctx, &chapters, `SELECT `+chapterColumns+` FROM "Chapter" WHERE "animeName" = $1 ORDER BY chapter`, animeName)
+		return wrapErr(err)
+	})
+	return chapters, err
+}
+
+func (p *Postgres) ReorderPages(ctx context.Context, animeName string, chapter int, order []int) (Chapter, error) {
+	var c Chapter
+	err := p.inTx(ctx, func(tx *sqlx.Tx) error {
+		var pages pq.StringArray
+		err := tx.GetContext(ctx, &pages, `SELECT img FROM "Chapter" WHERE "animeName" = $1 AND chapter = $2 FOR UPDATE`, animeName, chapter)
+		if err != nil {
+			return wrapErr(err)
+		}
+		reordered, err := reorder(pages, order)
+		if err != nil {
+			return err
+		}
+		err = tx.GetContext(ctx, &c, `
+			UPDATE "Chapter" SET img = $1 WHERE "animeName" = $2 AND chapter = $3
+			RETURNING `+chapterColumns,
+			pq.StringArray(reordered), animeName, chapter)
+		return wrapErr(err)
+	})
+	return c, err
+}
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckRenumber(t *testing.T) {
+	existing := []int{1, 2, 3}
+	tests := []struct {
+		name    string
+		numbers map[int]int
+		want    error
+	}{
+		{"move to a free number", map[int]int{3: 4}, nil},
+		{"swap", map[int]int{1: 2, 2: 1}, nil},
+		{"rotate", map[int]int{1: 2, 2: 3, 3: 1}, nil},
+		{"nothing", map[int]int{}, nil},
+		{"onto a chapter that stays", map[int]int{1: 2}, ErrConflict},
+		{"two onto one", map[int]int{1: 5, 2: 5}, ErrConflict},
+		{"unknown chapter", map[int]int{7: 8}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		if err := checkRenumber(existing, tt.numbers); !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkRenumber(%v) = %v, want %v", tt.name, tt.numbers, err, tt.want)
+		}
+	}
+}
+
+func TestReorder(t *testing.T) {
+	pages := []string{"a", "b", "c"}
+	got, err := reorder(pages, []int{2, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c", "a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("reorder = %v, want %v", got, want)
+	}
+	if !slices.Equal(pages, []string{"a", "b", "c"}) {
+		t.Errorf("reorder modified its input: %v", pages)
+	}
+
+	for _, order := range [][]int{{0, 1}, {0, 1, 2, 3}, {0, 0, 1}, {0, 1, 3}, {-1, 0, 1}} {
+		if _, err := reorder(pages, order); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("reorder(%v) error = %v, want ErrInvalidOrder", order, err)
+		}
+	}
+}
+
+func TestCreateManga(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga, err := s.CreateManga(ctx, Manga{Name: "Berserk", AverageRating: 9, RatingCount: 4, Popularity: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manga.Id == 0 {
+			t.Error("CreateManga returned no id")
+		}
+		// Derived columns are never written through the catalog.
+		if manga.AverageRating != 0 || manga.RatingCount != 0 || manga.Popularity != 0 {
+			t.Errorf("CreateManga kept derived fields: %+v", manga)
+		}
+		if _, err := s.CreateManga(ctx, Manga{Name: "Berserk"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("CreateManga(duplicate) error = %v, want ErrConflict", err)
+		}
+	})
+}
+
+func TestUpdateManga(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		created := createManga(t, s, Manga{Name: "Berserk"})
+		createManga(t, s, Manga{Name: "Monster"})
+		createChapter(t, s, "Berserk", 1)
+		createUser(t, s, "u1")
+		rate(t, s, "u1", 9)
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{1}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		updated, err := s.UpdateManga(ctx, "Berserk", Manga{Name: "Berserk Deluxe", Author: "Kentaro Miura"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Id != created.Id || updated.Author != "Kentaro Miura" || updated.AverageRating != 9 {
+			t.Errorf("UpdateManga = %+v", updated)
+		}
+		// A rename carries everything stored by name over.
+		if chapters, err := s.Chapters(ctx, "Berserk Deluxe"); err != nil || len(chapters) != 1 {
+			t.Errorf("chapters after rename = %v, %v", chapters, err)
+		}
+		if read, err := s.ReadChapters(ctx, "u1", "Berserk Deluxe"); err != nil || !slices.Equal(read, []int{1}) {
+			t.Errorf("read chapters after rename = %v, %v", read, err)
+		}
+		if r, err := s.UserRating(ctx, "u1", "Berserk Deluxe"); err != nil || r.Score != 9 {
+			t.Errorf("rating after rename = %+v, %v", r, err)
+		}
+
+		if _, err := s.UpdateManga(ctx, "Berserk Deluxe", Manga{Name: "Monster"}); !errors.Is(err, ErrConflict) {
+			t.Errorf("UpdateManga(taken name) error = %v, want ErrConflict", err)
+		}
+		if _, err := s.UpdateManga(ctx, "Berserk", Manga{Name: "Berserk"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateManga(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDeleteManga(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createChapter(t, s, "Berserk", 1)
+		createUser(t, s, "u1")
+		rate(t, s, "u1", 9)
+		if _, err := s.SetFavorite(ctx, "u1", manga.Id, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.DeleteManga(ctx, "Berserk"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ByName(ctx, "Berserk"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ByName after delete error = %v, want ErrNotFound", err)
+		}
+		if chapters, err := s.Chapters(ctx, "Berserk"); err != nil || len(chapters) != 0 {
+			t.Errorf("chapters after delete = %v, %v", chapters, err)
+		}
+		if ids, err := s.FavoriteIds(ctx, "u1"); err != nil || len(ids) != 0 {
+			t.Errorf("favorites after delete = %v, %v", ids, err)
+		}
+		if err := s.DeleteManga(ctx, "Berserk"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeleteManga error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestCreateChapter(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+
+		c, err := s.CreateChapter(ctx, Chapter{AnimeName: "Berserk", Chapter: 1, Name: "The Black Swordsman"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.CreatedAt.IsZero() {
+			t.Error("CreateChapter left CreatedAt empty")
+		}
+		if _, err := s.CreateChapter(ctx, Chapter{AnimeName: "Berserk", Chapter: 1}); !errors.Is(err, ErrConflict) {
+			t.Errorf("CreateChapter(taken number) error = %v, want ErrConflict", err)
+		}
+		if _, err := s.CreateChapter(ctx, Chapter{AnimeName: "Vagabond", Chapter: 1}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("CreateChapter(missing manga) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUpdateChapter(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createChapter(t, s, "Berserk", 1, "a.png")
+
+		c, err := s.UpdateChapter(ctx, "Berserk", 1, Chapter{Chapter: 5, Name: "Guts", Img: []string{"b.png", "c.png"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Numbers only change through RenumberChapters.
+		if c.Chapter != 1 || c.Name != "Guts" || !slices.Equal(c.Img, []string{"b.png", "c.png"}) {
+			t.Errorf("UpdateChapter = %+v", c)
+		}
+		if _, err := s.UpdateChapter(ctx, "Berserk", 2, Chapter{}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateChapter(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDeleteChapter(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createChapter(t, s, "Berserk", 1)
+		createChapter(t, s, "Berserk", 2)
+		createUser(t, s, "u1")
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{1, 2}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.DeleteChapter(ctx, "Berserk", 1); err != nil {
+			t.Fatal(err)
+		}
+		if chapters, err := s.Chapters(ctx, "Berserk"); err != nil || !slices.Equal(chapterNumbers(chapters), []int{2}) {
+			t.Errorf("chapters after delete = %v, %v", chapterNumbers(chapters), err)
+		}
+		if read, err := s.ReadChapters(ctx, "u1", "Berserk"); err != nil || !slices.Equal(read, []int{2}) {
+			t.Errorf("read chapters after delete = %v, %v", read, err)
+		}
+		if err := s.DeleteChapter(ctx, "Berserk", 1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeleteChapter error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestRenumberChapters(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createChapter(t, s, "Berserk", 1, "one.png")
+		createChapter(t, s, "Berserk", 2, "two.png")
+		createChapter(t, s, "Berserk", 3, "three.png")
+		createUser(t, s, "u1")
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{1}, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveProgress(ctx, Progress{UserId: "u1", AnimeName: "Berserk", Chapter: 2, Page: 4, UpdatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+
+		chapters, err := s.RenumberChapters(ctx, "Berserk", map[int]int{1: 2, 2: 1, 3: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range chapters {
+			got = append(got, c.Img[0])
+		}
+		if want := []string{"two.png", "one.png", "three.png"}; !slices.Equal(got, want) || !slices.Equal(chapterNumbers(chapters), []int{1, 2, 10}) {
+			t.Errorf("RenumberChapters = %v %v, want %v numbered 1, 2, 10", chapterNumbers(chapters), got, want)
+		}
+		// Read marks and progress follow their chapter.
+		if read, err := s.ReadChapters(ctx, "u1", "Berserk"); err != nil || !slices.Equal(read, []int{2}) {
+			t.Errorf("read chapters after renumber = %v, %v, want [2]", read, err)
+		}
+		if progress, err := s.ContinueReading(ctx, "u1", 1); err != nil || len(progress) != 1 || progress[0].Chapter != 1 {
+			t.Errorf("progress after renumber = %+v, %v, want chapter 1", progress, err)
+		}
+
+		if _, err := s.RenumberChapters(ctx, "Berserk", map[int]int{1: 2}); !errors.Is(err, ErrConflict) {
+			t.Errorf("RenumberChapters(collision) error = %v, want ErrConflict", err)
+		}
+		if _, err := s.RenumberChapters(ctx, "Berserk", map[int]int{3: 4}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("RenumberChapters(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestReorderPages(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createChapter(t, s, "Berserk", 1, "a.png", "b.png", "c.png")
+
+		c, err := s.ReorderPages(ctx, "Berserk", 1, []int{1, 2, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"b.png", "c.png", "a.png"}; !slices.Equal(c.Img, want) {
+			t.Errorf("ReorderPages = %v, want %v", c.Img, want)
+		}
+		stored, err := s.Chapter(ctx, "Berserk", 1)
+		if err != nil || !slices.Equal(stored.Img, c.Img) {
+			t.Errorf("stored pages = %v, %v, want %v", stored.Img, err, c.Img)
+		}
+		if _, err := s.ReorderPages(ctx, "Berserk", 1, []int{0, 1}); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("ReorderPages(short order) error = %v, want ErrInvalidOrder", err)
+		}
+		if _, err := s.ReorderPages(ctx, "Berserk", 2, []int{0}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ReorderPages(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
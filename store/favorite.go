@@ -0,0 +1,16 @@
+package store
+
+import "context"
+
+// FavoriteStore keeps favorites in the "Favorite" join table. Adding or
+// removing a favorite adjusts "Anime".popularity in the same transaction.
+type FavoriteStore interface {
+	// ToggleFavorite flips the favorite and reports whether the manga is a
+	// favorite afterwards.
+	ToggleFavorite(ctx context.Context, userId string, animeId int) (bool, error)
+	// SetFavorite adds or removes a favorite and reports whether anything
+	// changed. Repeating a call is a no-op.
+	SetFavorite(ctx context.Context, userId string, animeId int, favorite bool) (bool, error)
+	IsFavorite(ctx context.Context, userId string, animeId int) (bool, error)
+	FavoriteIds(ctx context.Context, userId string) ([]int, error)
+}
@@ -0,0 +1,74 @@
+package store
+
+import "context"
+
+func (m *Memory) ToggleFavorite(ctx context.Context, userId string, animeId int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	favorite := !m.hasFavorite(userId, animeId)
+	_, err := m.setFavorite(userId, animeId, favorite)
+	return favorite, err
+}
+
+func (m *Memory) SetFavorite(ctx context.Context, userId string, animeId int, favorite bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setFavorite(userId, animeId, favorite)
+}
+
+// setFavorite, hasFavorite and mangaById must be called with m.mu held.
+func (m *Memory) setFavorite(userId string, animeId int, favorite bool) (bool, error) {
+	i := m.mangaById(animeId)
+	if i < 0 {
+		return false, ErrNotFound
+	}
+	if m.hasFavorite(userId, animeId) == favorite {
+		return false, nil
+	}
+	if favorite {
+		m.favorites[userId] = append(m.favorites[userId], animeId)
+		m.mangas[i].Popularity++
+		return true, nil
+	}
+	ids := m.favorites[userId][:0]
+	for _, id := range m.favorites[userId] {
+		if id != animeId {
+			ids = append(ids, id)
+		}
+	}
+	m.favorites[userId] = ids
+	if m.mangas[i].Popularity > 0 {
+		m.mangas[i].Popularity--
+	}
+	return true, nil
+}
+
+func (m *Memory) IsFavorite(ctx context.Context, userId string, animeId int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.hasFavorite(userId, animeId), nil
+}
+
+func (m *Memory) FavoriteIds(ctx context.Context, userId string) ([]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]int{}, m.favorites[userId]...), nil
+}
+
+func (m *Memory) hasFavorite(userId string, animeId int) bool {
+	for _, id := range m.favorites[userId] {
+		if id == animeId {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) mangaById(id int) int {
+	for i := range m.mangas {
+		if m.mangas[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
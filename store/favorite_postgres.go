@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func addFavorite(ctx context.Context, tx *sqlx.Tx, userId string, animeId int) (bool, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO "Favorite" ("userId", "animeId") VALUES ($1, $2) ON CONFLICT DO NOTHING`, userId, animeId)
+	return affected(res, err)
+}
+
+func removeFavorite(ctx context.Context, tx *sqlx.Tx, userId string, animeId int) (bool, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM "Favorite" WHERE "userId" = $1 AND "animeId" = $2`, userId, animeId)
+	return affected(res, err)
+}
+
+func addPopularity(ctx context.Context, tx *sqlx.Tx, animeId int, delta int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE "Anime" SET popularity = GREATEST(popularity + $1, 0) WHERE id = $2`, delta, animeId)
+	return wrapErr(err)
+}
+
+// ToggleFavorite locks the user's row first: two toggles that both found
+// nothing to delete would otherwise both insert, and the loser would report
+// false for a manga that is a favorite.
+func (p *Postgres) ToggleFavorite(ctx context.Context, userId string, animeId int) (bool, error) {
+	var favorite bool
+	err := p.inTx(ctx, func(tx *sqlx.Tx) error {
+		var id string
+		if err := tx.GetContext(ctx, &id, `SELECT id FROM "User" WHERE id = $1 FOR UPDATE`, userId); err != nil {
+			return wrapErr(err)
+		}
+		removed, err := removeFavorite(ctx, tx, userId, animeId)
+		if err != nil {
+			return err
+		}
+		if removed {
+			return addPopularity(ctx, tx, animeId, -1)
+		}
+		favorite, err = addFavorite(ctx, tx, userId, animeId)
+		if err != nil || !favorite {
+			return err
+		}
+		return addPopularity(ctx, tx, animeId, 1)
+	})
+	return favorite, err
+}
+
+func (p *Postgres) SetFavorite(ctx context.Context, userId string, animeId int, favorite bool) (bool, error) {
+	var changed bool
+	err := p.inTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		delta := 1
+		if favorite {
+			changed, err = addFavorite(ctx, tx, userId, animeId)
+		} else {
+			changed, err = removeFavorite(ctx, tx, userId, animeId)
+			delta = -1
+		}
+		if err != nil || !changed {
+			return err
+		}
+		return addPopularity(ctx, tx, animeId, delta)
+	})
+	return changed, err
+}
+
+func (p *Postgres) IsFavorite(ctx context.Context, userId string, animeId int) (bool, error) {
+	var favorite bool
+	err := p.db.GetContext(ctx, &favorite, `
+		SELECT EXISTS (SELECT 1 FROM "Favorite" WHERE "userId" = $1 AND "animeId" = $2)`, userId, animeId)
+	return favorite, wrapErr(err)
+}
+
+func (p *Postgres) FavoriteIds(ctx context.Context, userId string) ([]int, error) {
+	ids := []int{}
+	err := p.db.SelectContext(ctx, &ids, `
+		SELECT "animeId" FROM "Favorite" WHERE "userId" = $1 ORDER BY "createdAt"`, userId)
+	return ids, wrapErr(err)
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func popularity(t *testing.T, s backend, name string) int {
+	t.Helper()
+	manga, err := s.ByName(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manga.Popularity
+}
+
+func TestToggleFavorite(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+
+		for i, want := range []bool{true, false} {
+			favorite, err := s.ToggleFavorite(ctx, "u1", manga.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if favorite != want {
+				t.Errorf("toggle %d = %v, want %v", i+1, favorite, want)
+			}
+			if got, wantPop := popularity(t, s, "Berserk"), map[bool]int{true: 1}[want]; got != wantPop {
+				t.Errorf("popularity after toggle %d = %d, want %d", i+1, got, wantPop)
+			}
+		}
+		if _, err := s.ToggleFavorite(ctx, "u1", manga.Id+100); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ToggleFavorite(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestToggleFavoriteConcurrently(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+
+		// An even number of toggles, however they interleave, ends where it
+		// started and reports as many adds as removals.
+		const toggles = 20
+		var wg sync.WaitGroup
+		var added atomic.Int32
+		errs := make(chan error, toggles)
+		for range toggles {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				favorite, err := s.ToggleFavorite(ctx, "u1", manga.Id)
+				if err != nil {
+					errs <- err
+				}
+				if favorite {
+					added.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatal(err)
+		}
+
+		if n := added.Load(); n != toggles/2 {
+			t.Errorf("%d of %d toggles added the favorite, want half", n, toggles)
+		}
+		if favorite, err := s.IsFavorite(ctx, "u1", manga.Id); err != nil || favorite {
+			t.Errorf("IsFavorite = %v, %v, want false", favorite, err)
+		}
+		if got := popularity(t, s, "Berserk"); got != 0 {
+			t.Errorf("popularity = %d, want 0", got)
+		}
+	})
+}
+
+func TestSetFavorite(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+
+		for _, step := range []struct {
+			favorite, changed bool
+			popularity        int
+		}{
+			{true, true, 1},
+			{true, false, 1},
+			{false, true, 0},
+			{false, false, 0},
+		} {
+			changed, err := s.SetFavorite(ctx, "u1", manga.Id, step.favorite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != step.changed {
+				t.Errorf("SetFavorite(%v) changed = %v, want %v", step.favorite, changed, step.changed)
+			}
+			if got := popularity(t, s, "Berserk"); got != step.popularity {
+				t.Errorf("popularity after SetFavorite(%v) = %d, want %d", step.favorite, got, step.popularity)
+			}
+		}
+		if _, err := s.SetFavorite(ctx, "u1", manga.Id+100, true); !errors.Is(err, ErrNotFound) {
+			t.Errorf("SetFavorite(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestIsFavorite(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		if _, err := s.SetFavorite(ctx, "u1", manga.Id, true); err != nil {
+			t.Fatal(err)
+		}
+
+		for user, want := range map[string]bool{"u1": true, "u2": false} {
+			got, err := s.IsFavorite(ctx, user, manga.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("IsFavorite(%s) = %v, want %v", user, got, want)
+			}
+		}
+	})
+}
+
+func TestFavoriteIds(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createUser(t, s, "u1")
+		var want []int
+		for _, name := range []string{"Berserk", "Monster", "Vagabond"} {
+			manga := createManga(t, s, Manga{Name: name})
+			if name == "Monster" {
+				continue
+			}
+			if _, err := s.SetFavorite(ctx, "u1", manga.Id, true); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, manga.Id)
+		}
+
+		ids, err := s.FavoriteIds(ctx, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, want) {
+			t.Errorf("FavoriteIds = %v, want %v", ids, want)
+		}
+		ids, err = s.FavoriteIds(ctx, "u2")
+		if err != nil || ids == nil || len(ids) != 0 {
+			t.Errorf("FavoriteIds(no favorites) = %#v, %v, want an empty slice", ids, err)
+		}
+	})
+}
+
+func TestFilterFavorites(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		berserk := createManga(t, s, Manga{Name: "Berserk"})
+		createManga(t, s, Manga{Name: "Monster"})
+		user := createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		if _, err := s.SetFavorite(ctx, user.Id, berserk.Id, true); err != nil {
+			t.Fatal(err)
+		}
+
+		for id, want := range map[string][]string{"u1": {"Berserk"}, "u2": {}} {
+			page, err := s.Filter(ctx, MangaFilter{FavoriteOf: id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page.Items); !slices.Equal(got, want) {
+				t.Errorf("favorites of %s = %v, want %v", id, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFilter(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		berserk := createManga(t, s, Manga{Name: "Berserk", Genres: []string{"action", "horror"}, Country: "Japan", Status: "ongoing", Published: 1989})
+		createManga(t, s, Manga{Name: "Monster", Genres: []string{"thriller"}, Country: "Japan", Status: "finished", Published: 1994})
+		createManga(t, s, Manga{Name: "Solo Leveling", Genres: []string{"action"}, Country: "Korea", Status: "finished", Published: 2018})
+		createManga(t, s, Manga{Name: "100% Perfect Girl", Genres: []string{"romance"}, Country: "Korea", Status: "finished", Published: 2011})
+
+		tests := []struct {
+			name   string
+			filter MangaFilter
+			want   []string
+		}{
+			{"name", MangaFilter{Name: "ER", Sort: SortName}, []string{"100% Perfect Girl", "Berserk", "Monster"}},
+			{"name wildcard taken literally", MangaFilter{Name: "%"}, []string{"100% Perfect Girl"}},
+			{"all genres", MangaFilter{Genres: []string{"action", "horror"}}, []string{"Berserk"}},
+			{"any genre", MangaFilter{Genres: []string{"horror", "thriller"}, GenreMatch: GenreMatchAny, Sort: SortName}, []string{"Berserk", "Monster"}},
+			{"excluded genre", MangaFilter{ExcludeGenres: []string{"action"}, Sort: SortName}, []string{"100% Perfect Girl", "Monster"}},
+			{"status and country", MangaFilter{Statuses: []string{"finished"}, Countries: []string{"Korea"}, Sort: SortPublished}, []string{"100% Perfect Girl", "Solo Leveling"}},
+			{"published range", MangaFilter{PublishedFrom: 1990, PublishedTo: 2015, Sort: SortPublished, Desc: true}, []string{"100% Perfect Girl", "Monster"}},
+			{"names", MangaFilter{Names: []string{"Monster", "Vagabond"}}, []string{"Monster"}},
+			{"ids", MangaFilter{Ids: []int{berserk.Id}}, []string{"Berserk"}},
+			{"page", MangaFilter{Sort: SortName, Page: 2, PerPage: 2}, []string{"Monster", "Solo Leveling"}},
+		}
+		for _, tt := range tests {
+			page, err := s.Filter(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			got := names(page.Items)
+			if tt.filter.Sort == "" {
+				slices.Sort(got)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s: Filter = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestFilterWhere(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter MangaFilter
+		where  string
+		args   []interface{}
+	}{
+		{"no constraint", MangaFilter{Sort: SortName, Page: 2, PerPage: 10}, "", nil},
+		{"name", MangaFilter{Name: `50%_off\`}, ` WHERE "name" ILIKE ?`, []interface{}{`%50\%\_off\\%`}},
+		{
+			"all genres and exclusions",
+			MangaFilter{Genres: []string{"action"}, ExcludeGenres: []string{"horror"}},
+			` WHERE "genres" @> ? AND NOT ("genres" && ?)`,
+			[]interface{}{pq.Array([]string{"action"}), pq.Array([]string{"horror"})},
+		},
+		{
+			"any genre",
+			MangaFilter{Genres: []string{"action", "drama"}, GenreMatch: GenreMatchAny},
+			` WHERE "genres" && ?`,
+			[]interface{}{pq.Array([]string{"action", "drama"})},
+		},
+		{
+			"status, country and ranges",
+			MangaFilter{Statuses: []string{"ongoing"}, Countries: []string{"Japan"}, PublishedFrom: 1990, PublishedTo: 2000, RatingMin: 7.5},
+			` WHERE "status" = ANY(?) AND "country" = ANY(?) AND "published" >= ? AND "published" <= ? AND "averageRating" >= ?`,
+			[]interface{}{pq.Array([]string{"ongoing"}), pq.Array([]string{"Japan"}), 1990, 2000, 7.5},
+		},
+		{
+			"names, ids and favorites",
+			MangaFilter{Names: []string{"Berserk"}, Ids: []int{1, 2}, FavoriteOf: "u1"},
+			` WHERE "name" = ANY(?) AND "id" = ANY(?) AND "id" IN (SELECT "animeId" FROM "Favorite" WHERE "userId" = ?)`,
+			[]interface{}{pq.Array([]string{"Berserk"}), pq.Array([]int{1, 2}), "u1"},
+		},
+		// An empty, non-nil list matches nothing rather than everything.
+		{"empty names", MangaFilter{Names: []string{}}, ` WHERE "name" = ANY(?)`, []interface{}{pq.Array([]string{})}},
+	}
+	for _, tt := range tests {
+		w := filterWhere(tt.filter)
+		if got := w.String(); got != tt.where {
+			t.Errorf("%s: where = %q, want %q", tt.name, got, tt.where)
+		}
+		if !reflect.DeepEqual(w.args, tt.args) {
+			t.Errorf("%s: args = %#v, want %#v", tt.name, w.args, tt.args)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	if got, want := containsPattern("a_b"), `%a\_b%`; got != want {
+		t.Errorf("containsPattern = %q, want %q", got, want)
+	}
+	if got, want := prefixPattern("100%"), `100\%%`; got != want {
+		t.Errorf("prefixPattern = %q, want %q", got, want)
+	}
+}
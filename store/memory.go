@@ -0,0 +1,344 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process implementation of every store interface. It is
+// meant for tests and for running the API without Postgres.
+type Memory struct {
+	mu       sync.RWMutex
+	mangas   []Manga
+	chapters map[string][]Chapter
+	users    map[string]User
+	nextId   int
+
+	progress map[userMangaKey]Progress
+	read     map[userMangaKey]map[int]struct{}
+	ratings  map[userMangaKey]Rating
+	// favorites holds manga ids per user id, oldest first.
+	favorites map[string][]int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		chapters: map[string][]Chapter{},
+		users:    map[string]User{},
+		nextId:   1,
+		progress: map[userMangaKey]Progress{},
+		read:     map[userMangaKey]map[int]struct{}{},
+		ratings:  map[userMangaKey]Rating{},
+
+		favorites: map[string][]int{},
+	}
+}
+
+// Seed is the JSON document accepted by LoadSeed. Chapters listed inside a
+// manga are added as well.
+type Seed struct {
+	Mangas   []Manga   `json:"mangas"`
+	Chapters []Chapter `json:"chapters"`
+	Users    []User    `json:"users"`
+}
+
+func (m *Memory) LoadSeed(r io.Reader) error {
+	var seed Seed
+	if err := json.NewDecoder(r).Decode(&seed); err != nil {
+		return err
+	}
+	for _, manga := range seed.Mangas {
+		for _, c := range manga.Chapters {
+			if c.AnimeName == "" {
+				c.AnimeName = manga.Name
+			}
+			m.AddChapter(c)
+		}
+		m.AddManga(manga)
+	}
+	for _, c := range seed.Chapters {
+		m.AddChapter(c)
+	}
+	for _, u := range seed.Users {
+		m.Create(context.Background(), u)
+	}
+	return nil
+}
+
+func (m *Memory) AddManga(manga Manga) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if manga.Id == 0 {
+		manga.Id = m.nextId
+	}
+	if manga.Id >= m.nextId {
+		m.nextId = manga.Id + 1
+	}
+	manga.Chapters = nil
+	m.mangas = append(m.mangas, manga)
+}
+
+func (m *Memory) AddChapter(c Chapter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	m.chapters[c.AnimeName] = append(m.chapters[c.AnimeName], c)
+}
+
+func (m *Memory) All(ctx context.Context) ([]Manga, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Manga(nil), m.mangas...), nil
+}
+
+func (m *Memory) ByName(ctx context.Context, name string) (Manga, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, manga := range m.mangas {
+		if manga.Name == name {
+			return manga, nil
+		}
+	}
+	return Manga{}, ErrNotFound
+}
+
+func (m *Memory) Chapters(ctx context.Context, animeName string) ([]Chapter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chapters := append([]Chapter(nil), m.chapters[animeName]...)
+	slices.SortFunc(chapters, func(a, b Chapter) int { return a.Chapter - b.Chapter })
+	return chapters, nil
+}
+
+func (m *Memory) Chapter(ctx context.Context, animeName string, chapter int) (Chapter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, c := range m.chapters[animeName] {
+		if c.Chapter == chapter {
+			return c, nil
+		}
+	}
+	return Chapter{}, ErrNotFound
+}
+
+func (m *Memory) Filter(ctx context.Context, f MangaFilter) (Page[Manga], error) {
+	m.mu.RLock()
+	var mangas []Manga
+	for _, manga := range m.mangas {
+		if m.matchFilter(f, manga) {
+			mangas = append(mangas, manga)
+		}
+	}
+	m.mu.RUnlock()
+	total := len(mangas)
+
+	if f.After != nil {
+		f.Sort = SortPopularity
+	}
+	if f.Sort != "" {
+		sort.SliceStable(mangas, func(i, j int) bool {
+			if f.Desc {
+				return lessManga(mangas[j], mangas[i], f.Sort)
+			}
+			return lessManga(mangas[i], mangas[j], f.Sort)
+		})
+	}
+
+	start := 0
+	if f.After != nil {
+		after := Manga{Popularity: f.After.Popularity, Id: f.After.Id}
+		for start < len(mangas) {
+			if f.Desc && lessManga(mangas[start], after, SortPopularity) {
+				break
+			}
+			if !f.Desc && lessManga(after, mangas[start], SortPopularity) {
+				break
+			}
+			start++
+		}
+	} else if f.Page > 1 && f.PerPage > 0 {
+		start = (f.Page - 1) * f.PerPage
+	}
+	if start > len(mangas) {
+		start = len(mangas)
+	}
+	mangas = mangas[start:]
+	if f.PerPage > 0 && len(mangas) > f.PerPage+1 {
+		mangas = mangas[:f.PerPage+1]
+	}
+	return newMangaPage(mangas, total, f), nil
+}
+
+// matchFilter must be called with m.mu held.
+func (m *Memory) matchFilter(f MangaFilter, manga Manga) bool {
+	if f.Name != "" && !strings.Contains(strings.ToLower(manga.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !contains(f.Statuses, manga.Status) {
+		return false
+	}
+	if len(f.Countries) > 0 && !contains(f.Countries, manga.Country) {
+		return false
+	}
+	if len(f.Genres) > 0 {
+		if f.GenreMatch == GenreMatchAny && !containsAny(manga.Genres, f.Genres) {
+			return false
+		}
+		if f.GenreMatch != GenreMatchAny && !containsAll(manga.Genres, f.Genres) {
+			return false
+		}
+	}
+	if containsAny(manga.Genres, f.ExcludeGenres) {
+		return false
+	}
+	if f.PublishedFrom > 0 && manga.Published < f.PublishedFrom {
+		return false
+	}
+	if f.PublishedTo > 0 && manga.Published > f.PublishedTo {
+		return false
+	}
+	if f.Names != nil && !contains(f.Names, manga.Name) {
+		return false
+	}
+	if f.Ids != nil && !slices.Contains(f.Ids, manga.Id) {
+		return false
+	}
+	if f.FavoriteOf != "" && !m.hasFavorite(f.FavoriteOf, manga.Id) {
+		return false
+	}
+	return manga.AverageRating >= f.RatingMin
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(have, want []string) bool {
+	for _, w := range want {
+		if contains(have, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// lessManga orders by field, then by id like the Postgres store does.
+func lessManga(a, b Manga, field SortField) bool {
+	switch field {
+	case SortName:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	case SortPublished:
+		if a.Published != b.Published {
+			return a.Published < b.Published
+		}
+	case SortAverageRating:
+		if a.AverageRating != b.AverageRating {
+			return a.AverageRating < b.AverageRating
+		}
+	case SortRatingCount:
+		if a.RatingCount != b.RatingCount {
+			return a.RatingCount < b.RatingCount
+		}
+	case SortPopularity:
+		if a.Popularity != b.Popularity {
+			return a.Popularity < b.Popularity
+		}
+	}
+	return a.Id < b.Id
+}
+
+func (m *Memory) ByEmail(ctx context.Context, email string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[email]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	user.FavoriteIds = append([]int{}, m.favorites[user.Id]...)
+	return user, nil
+}
+
+// Create returns ErrConflict when the e-mail or id is taken, as the unique
+// constraints of "User" do.
+func (m *Memory) Create(ctx context.Context, u User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.users {
+		if existing.Email == u.Email || existing.Id == u.Id {
+			return ErrConflict
+		}
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	// Seeded favorites are assumed to be counted in popularity already.
+	m.favorites[u.Id] = append([]int(nil), u.FavoriteIds...)
+	u.FavoriteIds = nil
+	m.users[u.Email] = u
+	return nil
+}
+
+func (m *Memory) DeleteByEmail(ctx context.Context, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[email]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.users, email)
+	for _, id := range m.favorites[user.Id] {
+		if i := m.mangaById(id); i >= 0 && m.mangas[i].Popularity > 0 {
+			m.mangas[i].Popularity--
+		}
+	}
+	delete(m.favorites, user.Id)
+	// Ratings go with the user, the mangas they rated are re-averaged.
+	var rated []string
+	for key := range m.ratings {
+		if key.userId == user.Id {
+			delete(m.ratings, key)
+			rated = append(rated, key.animeName)
+		}
+	}
+	for _, name := range rated {
+		if i := m.mangaIndex(name); i >= 0 {
+			m.refreshSummary(i)
+		}
+	}
+	// So do progress and read marks, as ON DELETE CASCADE does.
+	for key := range m.progress {
+		if key.userId == user.Id {
+			delete(m.progress, key)
+		}
+	}
+	for key := range m.read {
+		if key.userId == user.Id {
+			delete(m.read, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Page is one page of a list response.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Cursor points at the last manga of a page ordered by popularity and id.
+// Clients get it as an opaque string in Page.NextCursor.
+type Cursor struct {
+	Popularity int `json:"p"`
+	Id         int `json:"i"`
+}
+
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Id <= 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func cursorOf(m Manga) Cursor {
+	return Cursor{Popularity: m.Popularity, Id: m.Id}
+}
+
+// newMangaPage trims the extra row fetched to detect a next page and fills
+// in NextCursor when the results are ordered by popularity.
+func newMangaPage(items []Manga, total int, f MangaFilter) Page[Manga] {
+	page := Page[Manga]{Items: items, Total: total, Page: f.Page, PerPage: f.PerPage}
+	if f.After != nil {
+		page.Page = 0
+	}
+	if f.PerPage > 0 && len(items) > f.PerPage {
+		page.Items = items[:f.PerPage]
+		if f.Sort == SortPopularity {
+			page.NextCursor = cursorOf(page.Items[f.PerPage-1]).String()
+		}
+	}
+	if page.Items == nil {
+		page.Items = []Manga{}
+	}
+	return page
+}
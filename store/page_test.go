@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	for _, c := range []Cursor{{Popularity: 0, Id: 1}, {Popularity: 1234, Id: 99}, {Popularity: -1, Id: 7}} {
+		got, err := ParseCursor(c.String())
+		if err != nil {
+			t.Fatalf("ParseCursor(%q): %v", c.String(), err)
+		}
+		if got != c {
+			t.Errorf("round trip of %+v = %+v", c, got)
+		}
+	}
+
+	for _, s := range []string{"", "not base64!", "bm90IGpzb24", Cursor{Popularity: 5}.String(), Cursor{Id: -3}.String()} {
+		if _, err := ParseCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ParseCursor(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestNewMangaPage(t *testing.T) {
+	items := []Manga{{Id: 1, Popularity: 9}, {Id: 2, Popularity: 8}, {Id: 3, Popularity: 7}}
+
+	page := newMangaPage(items, 10, MangaFilter{Sort: SortPopularity, Page: 1, PerPage: 2})
+	if len(page.Items) != 2 || page.Total != 10 {
+		t.Fatalf("page = %+v, want 2 of 10 items", page)
+	}
+	if c, err := ParseCursor(page.NextCursor); err != nil || c != (Cursor{Popularity: 8, Id: 2}) {
+		t.Errorf("NextCursor = %q (%+v, %v), want the second item", page.NextCursor, c, err)
+	}
+
+	if page := newMangaPage(items, 10, MangaFilter{Sort: SortName, PerPage: 2}); page.NextCursor != "" {
+		t.Errorf("NextCursor when not ordered by popularity = %q, want none", page.NextCursor)
+	}
+	if page := newMangaPage(items[:2], 2, MangaFilter{Sort: SortPopularity, PerPage: 2}); page.NextCursor != "" {
+		t.Errorf("NextCursor on the last page = %q, want none", page.NextCursor)
+	}
+	if page := newMangaPage(nil, 0, MangaFilter{}); page.Items == nil {
+		t.Error("empty page has nil Items, want an empty slice")
+	}
+}
+
+func TestFilterCursor(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			createManga(t, s, Manga{Name: name})
+		}
+
+		var got []string
+		f := MangaFilter{Sort: SortPopularity, PerPage: 2}
+		for i := 0; i < 5; i++ {
+			page, err := s.Filter(ctx, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 5 {
+				t.Errorf("Total = %d, want 5", page.Total)
+			}
+			got = append(got, names(page.Items)...)
+			if page.NextCursor == "" {
+				break
+			}
+			cursor, err := ParseCursor(page.NextCursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.After = &cursor
+		}
+		if want := []string{"a", "b", "c", "d", "e"}; !slices.Equal(got, want) {
+			t.Errorf("pages = %v, want %v", got, want)
+		}
+	})
+}
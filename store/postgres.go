@@ -0,0 +1,248 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Postgres implements every store interface on top of a sqlx pool.
+type Postgres struct {
+	db *sqlx.DB
+}
+
+func NewPostgres(db *sqlx.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// wrapErr translates driver errors into the store sentinel errors.
+func wrapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if isConnErr(err) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503":
+			// foreign_key_violation: the referenced manga or user does not exist.
+			return ErrNotFound
+		case "23505":
+			// unique_violation: a manga name or chapter number is taken.
+			return ErrConflict
+		}
+	}
+	return err
+}
+
+// affected reports whether an Exec changed any row.
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, wrapErr(err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, wrapErr(err)
+}
+
+func isConnErr(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is "connection exception", 57P0x are shutdown/cannot connect now.
+		return pqErr.Code.Class() == "08" || strings.HasPrefix(string(pqErr.Code), "57P0")
+	}
+	return false
+}
+
+// mangaColumns and chapterColumns list the selected columns explicitly, so
+// adding a column in a migration does not break scanning into the structs.
+const (
+	mangaColumns   = `id, name, img, "imgHeader", describe, genres, author, country, published, "averageRating", "ratingCount", status, popularity`
+	chapterColumns = `chapter, img, name, "animeName", "createdAt"`
+)
+
+func (p *Postgres) All(ctx context.Context) ([]Manga, error) {
+	var mangas []Manga
+	err := p.db.SelectContext(ctx, &mangas, `SELECT `+mangaColumns+` FROM "Anime"`)
+	return mangas, wrapErr(err)
+}
+
+func (p *Postgres) ByName(ctx context.Context, name string) (Manga, error) {
+	var manga Manga
+	err := p.db.GetContext(ctx, &manga, `SELECT `+mangaColumns+` FROM "Anime" WHERE name=$1`, name)
+	return manga, wrapErr(err)
+}
+
+func (p *Postgres) Chapters(ctx context.Context, animeName string) ([]Chapter, error) {
+	var chapters []Chapter
+	err := p.db.SelectContext(ctx, &chapters, `SELECT `+chapterColumns+` FROM "Chapter" WHERE "animeName" =$1 ORDER BY chapter`, animeName)
+	return chapters, wrapErr(err)
+}
+
+func (p *Postgres) Chapter(ctx context.Context, animeName string, chapter int) (Chapter, error) {
+	var c Chapter
+	err := p.db.GetContext(ctx, &c, `SELECT `+chapterColumns+` FROM "Chapter" WHERE "animeName" =$1 AND chapter=$2`, animeName, chapter)
+	return c, wrapErr(err)
+}
+
+// sortColumns maps every SortField to the column it orders by.
+var sortColumns = map[SortField]string{
+	SortPopularity:    `"popularity"`,
+	SortAverageRating: `"averageRating"`,
+	SortRatingCount:   `"ratingCount"`,
+	SortPublished:     `"published"`,
+	SortName:          `"name"`,
+	// "Anime" has no creation timestamp, ids grow with every insert.
+	SortCreatedAt: `"id"`,
+}
+
+func filterWhere(f MangaFilter) *where {
+	w := &where{}
+	if f.Name != "" {
+		w.add(`"name" ILIKE ?`, containsPattern(f.Name))
+	}
+	if len(f.Statuses) > 0 {
+		w.add(`"status" = ANY(?)`, pq.Array(f.Statuses))
+	}
+	if len(f.Countries) > 0 {
+		w.add(`"country" = ANY(?)`, pq.Array(f.Countries))
+	}
+	if len(f.Genres) > 0 {
+		if f.GenreMatch == GenreMatchAny {
+			w.add(`"genres" && ?`, pq.Array(f.Genres))
+		} else {
+			w.add(`"genres" @> ?`, pq.Array(f.Genres))
+		}
+	}
+	if len(f.ExcludeGenres) > 0 {
+		w.add(`NOT ("genres" && ?)`, pq.Array(f.ExcludeGenres))
+	}
+	if f.PublishedFrom > 0 {
+		w.add(`"published" >= ?`, f.PublishedFrom)
+	}
+	if f.PublishedTo > 0 {
+		w.add(`"published" <= ?`, f.PublishedTo)
+	}
+	if f.RatingMin > 0 {
+		w.add(`"averageRating" >= ?`, f.RatingMin)
+	}
+	if f.Names != nil {
+		w.add(`"name" = ANY(?)`, pq.Array(f.Names))
+	}
+	if f.Ids != nil {
+		w.add(`"id" = ANY(?)`, pq.Array(f.Ids))
+	}
+	if f.FavoriteOf != "" {
+		w.add(`"id" IN (SELECT "animeId" FROM "Favorite" WHERE "userId" = ?)`, f.FavoriteOf)
+	}
+	return w
+}
+
+func (p *Postgres) Filter(ctx context.Context, f MangaFilter) (Page[Manga], error) {
+	w := filterWhere(f)
+
+	var total int
+	err := p.db.GetContext(ctx, &total, p.db.Rebind(`SELECT COUNT(*) FROM "Anime"`+w.String()), w.args...)
+	if err != nil {
+		return Page[Manga]{}, wrapErr(err)
+	}
+
+	if f.After != nil {
+		f.Sort = SortPopularity
+		if f.Desc {
+			w.add(`("popularity", "id") < (?, ?)`, f.After.Popularity, f.After.Id)
+		} else {
+			w.add(`("popularity", "id") > (?, ?)`, f.After.Popularity, f.After.Id)
+		}
+	}
+	query := `SELECT ` + mangaColumns + ` FROM "Anime"` + w.String()
+	args := w.args
+
+	if column, ok := sortColumns[f.Sort]; ok {
+		dir := "ASC"
+		if f.Desc {
+			dir = "DESC"
+		}
+		query += ` ORDER BY ` + column + ` ` + dir + `, "id" ` + dir
+	}
+	if f.PerPage > 0 {
+		// One extra row tells whether there is a next page.
+		query += ` LIMIT ?`
+		args = append(args, f.PerPage+1)
+		if f.After == nil && f.Page > 1 {
+			query += ` OFFSET ?`
+			args = append(args, (f.Page-1)*f.PerPage)
+		}
+	}
+
+	var mangas []Manga
+	if err := p.db.SelectContext(ctx, &mangas, p.db.Rebind(query), args...); err != nil {
+		return Page[Manga]{}, wrapErr(err)
+	}
+	return newMangaPage(mangas, total, f), nil
+}
+
+func (p *Postgres) ByEmail(ctx context.Context, email string) (User, error) {
+	var user User
+	err := p.db.GetContext(ctx, &user, `SELECT id, email, name, image, "createdAt" FROM "User" WHERE "email" = $1`, email)
+	if err != nil {
+		return user, wrapErr(err)
+	}
+	user.FavoriteIds, err = p.FavoriteIds(ctx, user.Id)
+	return user, err
+}
+
+func (p *Postgres) Create(ctx context.Context, u User) error {
+	query := `INSERT INTO "User" (id, email, name, image ) VALUES (:id, :email, :name, :image)`
+	_, err := p.db.NamedExecContext(ctx, query, u)
+	return wrapErr(err)
+}
+
+func (p *Postgres) DeleteByEmail(ctx context.Context, email string) error {
+	return p.inTx(ctx, func(tx *sqlx.Tx) error {
+		// Favorites go away with the user, so does the popularity they added.
+		_, err := tx.ExecContext(ctx, `
+			UPDATE "Anime" SET popularity = GREATEST(popularity - 1, 0)
+			WHERE id IN (SELECT f."animeId" FROM "Favorite" f JOIN "User" u ON u.id = f."userId" WHERE u.email = $1)`, email)
+		if err != nil {
+			return wrapErr(err)
+		}
+		// Ratings go too, through ON DELETE CASCADE, so the mangas they
+		// rated get their summaries recomputed afterwards.
+		var rated []string
+		err = tx.SelectContext(ctx, &rated, `
+			SELECT r."animeName" FROM "Rating" r JOIN "User" u ON u.id = r."userId" WHERE u.email = $1
+			ORDER BY r."animeName"`, email)
+		if err != nil {
+			return wrapErr(err)
+		}
+		deleted, err := affected(tx.ExecContext(ctx, `DELETE FROM "User" WHERE "email" = $1`, email))
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return ErrNotFound
+		}
+		for _, name := range rated {
+			if _, err := updateSummary(ctx, tx, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
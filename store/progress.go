@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Progress is the last position a user reached in a manga.
+type Progress struct {
+	UserId    string    `json:"userId" db:"userId"`
+	AnimeName string    `json:"animeName" db:"animeName"`
+	Chapter   int       `json:"chapter"`
+	Page      int       `json:"page"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+// ProgressStore keeps reading positions in "ReadingProgress" and read
+// chapters in "ChapterRead".
+type ProgressStore interface {
+	// SaveProgress records p as the user's latest position in the manga,
+	// replacing the previous one.
+	SaveProgress(ctx context.Context, p Progress) error
+	// ContinueReading lists the user's positions, most recent first.
+	ContinueReading(ctx context.Context, userId string, limit int) ([]Progress, error)
+	// SetRead marks chapters of a manga as read or unread.
+	SetRead(ctx context.Context, userId, animeName string, chapters []int, read bool) error
+	// ReadChapters returns the chapter numbers the user has read.
+	ReadChapters(ctx context.Context, userId, animeName string) ([]int, error)
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// userMangaKey indexes per-user, per-manga state in Memory.
+type userMangaKey struct {
+	userId    string
+	animeName string
+}
+
+func (m *Memory) SaveProgress(ctx context.Context, p Progress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now()
+	}
+	m.progress[userMangaKey{p.UserId, p.AnimeName}] = p
+	return nil
+}
+
+func (m *Memory) ContinueReading(ctx context.Context, userId string, limit int) ([]Progress, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var progress []Progress
+	for key, p := range m.progress {
+		if key.userId == userId {
+			progress = append(progress, p)
+		}
+	}
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].UpdatedAt.After(progress[j].UpdatedAt)
+	})
+	if len(progress) > limit {
+		progress = progress[:limit]
+	}
+	return progress, nil
+}
+
+func (m *Memory) SetRead(ctx context.Context, userId, animeName string, chapters []int, read bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userMangaKey{userId, animeName}
+	if m.read[key] == nil {
+		m.read[key] = map[int]struct{}{}
+	}
+	for _, c := range chapters {
+		if read {
+			m.read[key][c] = struct{}{}
+		} else {
+			delete(m.read[key], c)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ReadChapters(ctx context.Context, userId, animeName string) ([]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var chapters []int
+	for c := range m.read[userMangaKey{userId, animeName}] {
+		chapters = append(chapters, c)
+	}
+	sort.Ints(chapters)
+	return chapters, nil
+}
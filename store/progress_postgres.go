@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+func (p *Postgres) SaveProgress(ctx context.Context, pr Progress) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO "ReadingProgress" ("userId", "animeName", chapter, page, "updatedAt")
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ("userId", "animeName")
+		DO UPDATE SET chapter = EXCLUDED.chapter, page = EXCLUDED.page, "updatedAt" = EXCLUDED."updatedAt"`,
+		pr.UserId, pr.AnimeName, pr.Chapter, pr.Page, pr.UpdatedAt)
+	return wrapErr(err)
+}
+
+func (p *Postgres) ContinueReading(ctx context.Context, userId string, limit int) ([]Progress, error) {
+	var progress []Progress
+	err := p.db.SelectContext(ctx, &progress, `
+		SELECT "userId", "animeName", chapter, page, "updatedAt" FROM "ReadingProgress"
+		WHERE "userId" = $1 ORDER BY "updatedAt" DESC LIMIT $2`, userId, limit)
+	return progress, wrapErr(err)
+}
+
+func (p *Postgres) SetRead(ctx context.Context, userId, animeName string, chapters []int, read bool) error {
+	var err error
+	if read {
+		_, err = p.db.ExecContext(ctx, `
+			INSERT INTO "ChapterRead" ("userId", "animeName", chapter, "readAt")
+			SELECT $1, $2, c, now() FROM unnest($3::int[]) AS c
+			ON CONFLICT DO NOTHING`, userId, animeName, pq.Array(chapters))
+	} else {
+		_, err = p.db.ExecContext(ctx, `
+			DELETE FROM "ChapterRead" WHERE "userId" = $1 AND "animeName" = $2 AND chapter = ANY($3)`,
+			userId, animeName, pq.Array(chapters))
+	}
+	return wrapErr(err)
+}
+
+func (p *Postgres) ReadChapters(ctx context.Context, userId, animeName string) ([]int, error) {
+	var chapters []int
+	err := p.db.SelectContext(ctx, &chapters, `
+		SELECT chapter FROM "ChapterRead" WHERE "userId" = $1 AND "animeName" = $2 ORDER BY chapter`,
+		userId, animeName)
+	return chapters, wrapErr(err)
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSaveProgress(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+		for _, p := range []Progress{
+			{UserId: "u1", AnimeName: "Berserk", Chapter: 1, Page: 3, UpdatedAt: at},
+			{UserId: "u1", AnimeName: "Berserk", Chapter: 2, Page: 7, UpdatedAt: at.Add(time.Hour)},
+		} {
+			if err := s.SaveProgress(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		progress, err := s.ContinueReading(ctx, "u1", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(progress) != 1 || progress[0].Chapter != 2 || progress[0].Page != 7 {
+			t.Errorf("ContinueReading = %+v, want only chapter 2 page 7", progress)
+		}
+	})
+}
+
+func TestContinueReading(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for i, name := range []string{"Berserk", "Monster", "Vagabond"} {
+			createManga(t, s, Manga{Name: name})
+			if err := s.SaveProgress(ctx, Progress{UserId: "u1", AnimeName: name, Chapter: 1, UpdatedAt: at.Add(time.Duration(i) * time.Minute)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SaveProgress(ctx, Progress{UserId: "u2", AnimeName: "Berserk", Chapter: 1, UpdatedAt: at.Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+
+		progress, err := s.ContinueReading(ctx, "u1", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range progress {
+			got = append(got, p.AnimeName)
+		}
+		if want := []string{"Vagabond", "Monster"}; !slices.Equal(got, want) {
+			t.Errorf("ContinueReading = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestSetRead(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{1, 2, 3}, true); err != nil {
+			t.Fatal(err)
+		}
+		// Marking twice is not an error.
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{3}, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{2, 9}, false); err != nil {
+			t.Fatal(err)
+		}
+		read, err := s.ReadChapters(ctx, "u1", "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{1, 3}; !slices.Equal(read, want) {
+			t.Errorf("ReadChapters = %v, want %v", read, want)
+		}
+	})
+}
+
+func TestReadChapters(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createManga(t, s, Manga{Name: "Monster"})
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{5, 4}, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRead(ctx, "u2", "Berserk", []int{1}, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRead(ctx, "u1", "Monster", []int{2}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		read, err := s.ReadChapters(ctx, "u1", "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{4, 5}; !slices.Equal(read, want) {
+			t.Errorf("ReadChapters = %v, want %v", read, want)
+		}
+		read, err = s.ReadChapters(ctx, "u2", "Monster")
+		if err != nil || len(read) != 0 {
+			t.Errorf("ReadChapters(nothing read) = %v, %v, want none", read, err)
+		}
+	})
+}
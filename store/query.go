@@ -0,0 +1,35 @@
+package store
+
+import "strings"
+
+// where collects SQL conditions written with ? placeholders. Values are
+// always passed as arguments, never formatted into the query.
+type where struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *where) add(cond string, args ...interface{}) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern builds an ILIKE pattern matching s anywhere, with LIKE
+// wildcards in s taken literally.
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// prefixPattern builds an ILIKE pattern matching strings starting with s.
+func prefixPattern(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	MinScore = 1
+	MaxScore = 10
+)
+
+// Rating is the score a user gave a manga.
+type Rating struct {
+	UserId    string    `json:"userId" db:"userId"`
+	AnimeName string    `json:"animeName" db:"animeName"`
+	Score     int       `json:"score"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+// RatingSummary is the aggregate stored on "Anime" after a change.
+type RatingSummary struct {
+	AverageRating float64 `json:"averageRating" db:"averageRating"`
+	RatingCount   int     `json:"ratingCount" db:"ratingCount"`
+}
+
+type RatingBucket struct {
+	Score int `json:"score"`
+	Count int `json:"count"`
+}
+
+// RatingStore keeps one rating per user and manga in "Rating". Every write
+// updates "Anime"."averageRating" and "ratingCount" in the same
+// transaction.
+type RatingStore interface {
+	Rate(ctx context.Context, r Rating) (RatingSummary, error)
+	DeleteRating(ctx context.Context, userId, animeName string) (RatingSummary, error)
+	UserRating(ctx context.Context, userId, animeName string) (Rating, error)
+	// RatingDistribution returns one bucket per score from MinScore to
+	// MaxScore.
+	RatingDistribution(ctx context.Context, animeName string) ([]RatingBucket, error)
+}
+
+// summarize averages scores. The summary is always recomputed from every
+// rating rather than adjusted, so repeated updates cannot drift.
+func summarize(scores []int) RatingSummary {
+	if len(scores) == 0 {
+		return RatingSummary{}
+	}
+	sum := 0
+	for _, score := range scores {
+		sum += score
+	}
+	return RatingSummary{AverageRating: float64(sum) / float64(len(scores)), RatingCount: len(scores)}
+}
+
+func emptyDistribution() []RatingBucket {
+	buckets := make([]RatingBucket, 0, MaxScore-MinScore+1)
+	for score := MinScore; score <= MaxScore; score++ {
+		buckets = append(buckets, RatingBucket{Score: score})
+	}
+	return buckets
+}
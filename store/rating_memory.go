@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// mangaIndex and refreshSummary must be called with m.mu held.
+func (m *Memory) mangaIndex(animeName string) int {
+	for i := range m.mangas {
+		if m.mangas[i].Name == animeName {
+			return i
+		}
+	}
+	return -1
+}
+
+// refreshSummary recomputes the rating summary of m.mangas[i].
+func (m *Memory) refreshSummary(i int) RatingSummary {
+	var scores []int
+	for key, r := range m.ratings {
+		if key.animeName == m.mangas[i].Name {
+			scores = append(scores, r.Score)
+		}
+	}
+	s := summarize(scores)
+	m.mangas[i].AverageRating = s.AverageRating
+	m.mangas[i].RatingCount = s.RatingCount
+	return s
+}
+
+func (m *Memory) Rate(ctx context.Context, r Rating) (RatingSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.mangaIndex(r.AnimeName)
+	if i < 0 {
+		return RatingSummary{}, ErrNotFound
+	}
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = time.Now()
+	}
+	key := userMangaKey{r.UserId, r.AnimeName}
+	m.ratings[key] = r
+	return m.refreshSummary(i), nil
+}
+
+func (m *Memory) DeleteRating(ctx context.Context, userId, animeName string) (RatingSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.mangaIndex(animeName)
+	if i < 0 {
+		return RatingSummary{}, ErrNotFound
+	}
+	key := userMangaKey{userId, animeName}
+	if _, ok := m.ratings[key]; !ok {
+		return RatingSummary{}, ErrNotFound
+	}
+	delete(m.ratings, key)
+	return m.refreshSummary(i), nil
+}
+
+func (m *Memory) UserRating(ctx context.Context, userId, animeName string) (Rating, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.ratings[userMangaKey{userId, animeName}]
+	if !ok {
+		return Rating{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (m *Memory) RatingDistribution(ctx context.Context, animeName string) ([]RatingBucket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	buckets := emptyDistribution()
+	for key, r := range m.ratings {
+		if key.animeName == animeName && r.Score >= MinScore && r.Score <= MaxScore {
+			buckets[r.Score-MinScore].Count++
+		}
+	}
+	return buckets, nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// inTx runs fn in a transaction, committing when it returns nil.
+func (p *Postgres) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return wrapErr(tx.Commit())
+}
+
+// lockManga locks the "Anime" row so concurrent ratings of the same manga
+// apply one after another.
+func lockManga(ctx context.Context, tx *sqlx.Tx, animeName string) error {
+	var id int
+	err := tx.GetContext(ctx, &id, `SELECT id FROM "Anime" WHERE name = $1 FOR UPDATE`, animeName)
+	return wrapErr(err)
+}
+
+// updateSummary recomputes "averageRating" and "ratingCount" from every
+// rating of the manga, so repeated updates cannot drift.
+func updateSummary(ctx context.Context, tx *sqlx.Tx, animeName string) (RatingSummary, error) {
+	var s RatingSummary
+	err := tx.GetContext(ctx, &s, `
+		UPDATE "Anime" a SET "averageRating" = r.average, "ratingCount" = r.count
+		FROM (SELECT COALESCE(AVG(score), 0)::float8 AS average, COUNT(*) AS count FROM "Rating" WHERE "animeName" = $1) r
+		WHERE a.name = $1
+		RETURNING a."averageRating", a."ratingCount"`, animeName)
+	return s, wrapErr(err)
+}
+
+func (p *Postgres) Rate(ctx context.Context, r Rating) (RatingSummary, error) {
+	var summary RatingSummary
+	err := p.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := lockManga(ctx, tx, r.AnimeName); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO "Rating" ("userId", "animeName", score, "updatedAt") VALUES ($1, $2, $3, $4)
+			ON CONFLICT ("userId", "animeName") DO UPDATE SET score = EXCLUDED.score, "updatedAt" = EXCLUDED."updatedAt"`,
+			r.UserId, r.AnimeName, r.Score, r.UpdatedAt)
+		if err != nil {
+			return wrapErr(err)
+		}
+
+		summary, err = updateSummary(ctx, tx, r.AnimeName)
+		return err
+	})
+	return summary, err
+}
+
+func (p *Postgres) DeleteRating(ctx context.Context, userId, animeName string) (RatingSummary, error) {
+	var summary RatingSummary
+	err := p.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := lockManga(ctx, tx, animeName); err != nil {
+			return err
+		}
+
+		deleted, err := affected(tx.ExecContext(ctx, `DELETE FROM "Rating" WHERE "userId" = $1 AND "animeName" = $2`, userId, animeName))
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return ErrNotFound
+		}
+
+		summary, err = updateSummary(ctx, tx, animeName)
+		return err
+	})
+	return summary, err
+}
+
+func (p *Postgres) UserRating(ctx context.Context, userId, animeName string) (Rating, error) {
+	var r Rating
+	err := p.db.GetContext(ctx, &r, `
+		SELECT "userId", "animeName", score, "updatedAt" FROM "Rating" WHERE "userId" = $1 AND "animeName" = $2`,
+		userId, animeName)
+	return r, wrapErr(err)
+}
+
+func (p *Postgres) RatingDistribution(ctx context.Context, animeName string) ([]RatingBucket, error) {
+	var rows []RatingBucket
+	err := p.db.SelectContext(ctx, &rows, `
+		SELECT score, COUNT(*) AS count FROM "Rating" WHERE "animeName" = $1 GROUP BY score`, animeName)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	buckets := emptyDistribution()
+	for _, row := range rows {
+		if row.Score >= MinScore && row.Score <= MaxScore {
+			buckets[row.Score-MinScore].Count = row.Count
+		}
+	}
+	return buckets, nil
+}
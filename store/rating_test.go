@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		scores []int
+		want   RatingSummary
+	}{
+		{nil, RatingSummary{}},
+		{[]int{7}, RatingSummary{AverageRating: 7, RatingCount: 1}},
+		{[]int{10, 5, 6}, RatingSummary{AverageRating: 7, RatingCount: 3}},
+		{[]int{1, 2}, RatingSummary{AverageRating: 1.5, RatingCount: 2}},
+	}
+	for _, tt := range tests {
+		if got := summarize(tt.scores); got != tt.want {
+			t.Errorf("summarize(%v) = %+v, want %+v", tt.scores, got, tt.want)
+		}
+	}
+}
+
+func rate(t *testing.T, s backend, userId string, score int) RatingSummary {
+	t.Helper()
+	summary, err := s.Rate(context.Background(), Rating{UserId: userId, AnimeName: "Berserk", Score: score})
+	if err != nil {
+		t.Fatalf("Rate(%s, %d): %v", userId, score, err)
+	}
+	return summary
+}
+
+func TestRate(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+
+		rate(t, s, "u1", 10)
+		if got, want := rate(t, s, "u2", 5), (RatingSummary{AverageRating: 7.5, RatingCount: 2}); got != want {
+			t.Errorf("Rate = %+v, want %+v", got, want)
+		}
+		// Rating again replaces the score instead of adding one.
+		if got, want := rate(t, s, "u1", 7), (RatingSummary{AverageRating: 6, RatingCount: 2}); got != want {
+			t.Errorf("re-Rate = %+v, want %+v", got, want)
+		}
+		manga, err := s.ByName(ctx, "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manga.AverageRating != 6 || manga.RatingCount != 2 {
+			t.Errorf("stored summary = %v over %d, want 6 over 2", manga.AverageRating, manga.RatingCount)
+		}
+		if _, err := s.Rate(ctx, Rating{UserId: "u1", AnimeName: "Vagabond", Score: 5}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Rate(missing manga) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDeleteRating(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		rate(t, s, "u1", 10)
+		rate(t, s, "u2", 4)
+
+		summary, err := s.DeleteRating(ctx, "u1", "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (RatingSummary{AverageRating: 4, RatingCount: 1}); summary != want {
+			t.Errorf("DeleteRating = %+v, want %+v", summary, want)
+		}
+		summary, err = s.DeleteRating(ctx, "u2", "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary != (RatingSummary{}) {
+			t.Errorf("DeleteRating(last) = %+v, want zero", summary)
+		}
+		if _, err := s.DeleteRating(ctx, "u2", "Berserk"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DeleteRating(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUserRating(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		rate(t, s, "u1", 8)
+
+		r, err := s.UserRating(ctx, "u1", "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Score != 8 || r.UserId != "u1" || r.AnimeName != "Berserk" {
+			t.Errorf("UserRating = %+v", r)
+		}
+		if _, err := s.UserRating(ctx, "u2", "Berserk"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UserRating(unrated) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestRatingDistribution(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		createManga(t, s, Manga{Name: "Berserk"})
+		for i, score := range []int{10, 10, 3} {
+			user := string(rune('a' + i))
+			createUser(t, s, user)
+			rate(t, s, user, score)
+		}
+
+		buckets, err := s.RatingDistribution(context.Background(), "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buckets) != MaxScore-MinScore+1 {
+			t.Fatalf("got %d buckets, want %d", len(buckets), MaxScore-MinScore+1)
+		}
+		for _, b := range buckets {
+			want := map[int]int{10: 2, 3: 1}[b.Score]
+			if b.Count != want {
+				t.Errorf("bucket %d has %d ratings, want %d", b.Score, b.Count, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"html"
+	"strings"
+)
+
+// SearchQuery is a free-text query over manga name, author and describe.
+type SearchQuery struct {
+	Query   string
+	Page    int
+	PerPage int
+}
+
+// SearchHit is a manga matching a SearchQuery. Snippet is an HTML excerpt
+// of describe: the text is escaped and matched words are wrapped in
+// <mark></mark>, the only tags it can contain.
+type SearchHit struct {
+	Manga
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchStore ranks mangas by relevance and tolerates misspelled names.
+type SearchStore interface {
+	// Search returns a page of hits, best match first.
+	Search(ctx context.Context, q SearchQuery) (Page[SearchHit], error)
+	// Suggest returns up to limit manga names for an autocomplete prefix.
+	Suggest(ctx context.Context, prefix string, limit int) ([]string, error)
+}
+
+// SearchIndex is a SearchStore that has to be told about changed mangas.
+// Indexes that read the "Anime" table directly treat Index and Remove as
+// no-ops.
+type SearchIndex interface {
+	SearchStore
+	// Index adds manga to the index or replaces the entry with its name.
+	Index(ctx context.Context, manga Manga) error
+	// Remove drops the manga with the given name from the index.
+	Remove(ctx context.Context, name string) error
+}
+
+// Highlight markers used in SearchHit.Snippet.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// escapedMarkers turns the escaped highlight markers back into tags.
+var escapedMarkers = strings.NewReplacer(
+	html.EscapeString(HighlightStart), HighlightStart,
+	html.EscapeString(HighlightStop), HighlightStop,
+)
+
+// EscapeSnippet HTML-escapes a snippet highlighted with HighlightStart and
+// HighlightStop, keeping the markers. Markers that were already in the
+// text become tags too, which is harmless.
+func EscapeSnippet(s string) string {
+	return escapedMarkers.Replace(html.EscapeString(s))
+}
+
+// NewSearchPage wraps one page of hits.
+func NewSearchPage(hits []SearchHit, total int, q SearchQuery) Page[SearchHit] {
+	if hits == nil {
+		hits = []SearchHit{}
+	}
+	return Page[SearchHit]{Items: hits, Total: total, Page: q.Page, PerPage: q.PerPage}
+}
@@ -0,0 +1,56 @@
+package store
+
+import "context"
+
+// searchFrom matches rows whose "search" tsvector (see the 0005_search
+// migration) matches the query, or whose name or author is close to it by
+// pg_trgm similarity, so misspelled names still match.
+const searchFrom = ` FROM "Anime",
+	(SELECT websearch_to_tsquery('simple', $1) AS tsq, $1::text AS raw) q
+	WHERE search @@ q.tsq OR name % q.raw OR q.raw <% name OR q.raw <% author`
+
+const searchRank = `ts_rank_cd(search, q.tsq) + GREATEST(similarity(name, q.raw), word_similarity(q.raw, name), word_similarity(q.raw, author) / 2)`
+
+const searchSnippet = `ts_headline('simple', describe, q.tsq, 'StartSel=` + HighlightStart + `, StopSel=` + HighlightStop + `, MinWords=10, MaxWords=30, MaxFragments=2')`
+
+func (p *Postgres) Search(ctx context.Context, q SearchQuery) (Page[SearchHit], error) {
+	var total int
+	if err := p.db.GetContext(ctx, &total, `SELECT COUNT(*)`+searchFrom, q.Query); err != nil {
+		return Page[SearchHit]{}, wrapErr(err)
+	}
+
+	query := `SELECT ` + mangaColumns + `, ` + searchRank + ` AS rank, ` + searchSnippet + ` AS snippet` +
+		searchFrom + ` ORDER BY rank DESC, id LIMIT $2 OFFSET $3`
+	var hits []SearchHit
+	err := p.db.SelectContext(ctx, &hits, query, q.Query, q.PerPage, (q.Page-1)*q.PerPage)
+	if err != nil {
+		return Page[SearchHit]{}, wrapErr(err)
+	}
+	for i := range hits {
+		hits[i].Snippet = EscapeSnippet(hits[i].Snippet)
+	}
+	return NewSearchPage(hits, total, q), nil
+}
+
+func (p *Postgres) Suggest(ctx context.Context, prefix string, limit int) ([]string, error) {
+	// Titles starting with the prefix come first, then titles with a word
+	// starting with it, then near misses.
+	names := []string{}
+	err := p.db.SelectContext(ctx, &names, `
+		SELECT name FROM "Anime"
+		WHERE name ILIKE $1 OR name ILIKE $2 OR $3 <% name
+		ORDER BY name ILIKE $1 DESC, name ILIKE $2 DESC, word_similarity($3, name) DESC, popularity DESC, id
+		LIMIT $4`,
+		prefixPattern(prefix), "% "+prefixPattern(prefix), prefix, limit)
+	return names, wrapErr(err)
+}
+
+// Index is a no-op, the "search" column is generated by Postgres.
+func (p *Postgres) Index(ctx context.Context, manga Manga) error {
+	return nil
+}
+
+// Remove is a no-op, see Index.
+func (p *Postgres) Remove(ctx context.Context, name string) error {
+	return nil
+}
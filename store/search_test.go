@@ -0,0 +1,17 @@
+package store
+
+import "testing"
+
+func TestEscapeSnippet(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain text", "plain text"},
+		{"a <mark>hit</mark> here", "a <mark>hit</mark> here"},
+		{`<script>alert("x")</script> & <mark>co</mark>`, `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; <mark>co</mark>`},
+		{"<mark><b>bold</b></mark>", "<mark>&lt;b&gt;bold&lt;/b&gt;</mark>"},
+	}
+	for _, tt := range tests {
+		if got := EscapeSnippet(tt.in); got != tt.want {
+			t.Errorf("EscapeSnippet(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
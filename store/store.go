@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned by every store when the requested row does not exist.
+	ErrNotFound = errors.New("store: not found")
+	// ErrUnavailable wraps errors caused by the backing database being
+	// unreachable, as opposed to a bad query or bad data.
+	ErrUnavailable = errors.New("store: unavailable")
+	// ErrConflict is returned when a write would duplicate a unique name or
+	// chapter number.
+	ErrConflict = errors.New("store: conflict")
+)
+
+type Manga struct {
+	Name          string         `json:"name"`
+	Img           string         `json:"img"`
+	ImgHeader     string         `json:"imgHeader" db:"imgHeader"`
+	Describe      string         `json:"describe"`
+	Genres        pq.StringArray `json:"genres" db:"genres"`
+	Author        string         `json:"author"`
+	Country       string         `json:"country"`
+	Published     int            `json:"published"`
+	AverageRating float64        `json:"averageRating" db:"averageRating"`
+	RatingCount   int            `json:"ratingCount" db:"ratingCount"`
+	Status        string         `json:"status"`
+	Popularity    int            `json:"popularity"`
+	Id            int            `json:"id"`
+	Chapters      []Chapter      `json:"chapters"`
+	// Thumbnail is filled in per request for uploaded covers, it is not a
+	// column.
+	Thumbnail string `json:"thumbnail,omitempty" db:"-"`
+}
+
+type Chapter struct {
+	Chapter   int            `json:"chapter"`
+	Img       pq.StringArray `json:"img" db:"img"`
+	Name      string         `json:"name"`
+	AnimeName string         `json:"animeName" db:"animeName"`
+	CreatedAt time.Time      `json:"createdAt" db:"createdAt"`
+	// Read is filled in per request for signed-in users, it is not a column.
+	Read bool `json:"read" db:"-"`
+	// Thumbnails holds a thumbnail URL per page, "" for pages that were
+	// not uploaded. Filled in per request like Read.
+	Thumbnails []string `json:"thumbnails,omitempty" db:"-"`
+}
+
+type User struct {
+	Id        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Image     string    `json:"image"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+	// FavoriteIds is loaded from "Favorite" by UserStore.ByEmail.
+	FavoriteIds []int `json:"favoriteIds" db:"-"`
+}
+
+// SortField is a column MangaStore.Filter may order by. Only the values
+// below are accepted, anything else is rejected by ParseSortField.
+type SortField string
+
+const (
+	SortPopularity    SortField = "popularity"
+	SortAverageRating SortField = "averageRating"
+	SortRatingCount   SortField = "ratingCount"
+	SortPublished     SortField = "published"
+	SortName          SortField = "name"
+	SortCreatedAt     SortField = "createdAt"
+)
+
+var sortFields = []SortField{SortPopularity, SortAverageRating, SortRatingCount, SortPublished, SortName, SortCreatedAt}
+
+func ParseSortField(s string) (SortField, bool) {
+	for _, f := range sortFields {
+		if string(f) == s {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// GenreMatch tells Filter whether a manga needs every requested genre or
+// just one of them.
+type GenreMatch string
+
+const (
+	GenreMatchAll GenreMatch = "all"
+	GenreMatchAny GenreMatch = "any"
+)
+
+// MangaFilter describes the criteria accepted by MangaStore.Filter. Zero
+// values mean "no constraint".
+type MangaFilter struct {
+	Name          string
+	Genres        []string
+	GenreMatch    GenreMatch
+	ExcludeGenres []string
+	Statuses      []string
+	Countries     []string
+	PublishedFrom int
+	PublishedTo   int
+	RatingMin     float64
+	// Names limits the result to the given manga names.
+	Names []string
+	// Ids limits the result to the given manga ids.
+	Ids []int
+	// FavoriteOf limits the result to the favorites of a user id.
+	FavoriteOf string
+	Sort       SortField
+	Desc       bool
+	Page       int
+	PerPage    int
+	// After switches to keyset pagination: only mangas ordered after the
+	// cursor are returned and Sort is forced to SortPopularity.
+	After *Cursor
+}
+
+// MangaStore gives read access to "Anime" and "Chapter".
+type MangaStore interface {
+	All(ctx context.Context) ([]Manga, error)
+	ByName(ctx context.Context, name string) (Manga, error)
+	// Chapters returns the chapters of a manga ordered by number.
+	Chapters(ctx context.Context, animeName string) ([]Chapter, error)
+	Chapter(ctx context.Context, animeName string, chapter int) (Chapter, error)
+	Filter(ctx context.Context, f MangaFilter) (Page[Manga], error)
+}
+
+// UserStore gives access to "User".
+type UserStore interface {
+	ByEmail(ctx context.Context, email string) (User, error)
+	Create(ctx context.Context, u User) error
+	DeleteByEmail(ctx context.Context, email string) error
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chimas/GoProject/db"
+	"github.com/jmoiron/sqlx"
+)
+
+// backend is every store interface Memory and Postgres both implement.
+type backend interface {
+	MangaStore
+	UserStore
+	ProgressStore
+	RatingStore
+	FavoriteStore
+	CatalogStore
+}
+
+var (
+	_ backend = (*Memory)(nil)
+	_ backend = (*Postgres)(nil)
+)
+
+var (
+	testDBOnce sync.Once
+	testDB     *sqlx.DB
+	testDBErr  error
+)
+
+// postgresForTest returns the database named by TEST_DB_URL, migrated and
+// emptied, and skips the test when the variable is not set.
+func postgresForTest(t *testing.T) *sqlx.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	testDBOnce.Do(func() {
+		testDB, testDBErr = sqlx.Connect("postgres", url)
+		if testDBErr != nil {
+			return
+		}
+		var m *db.Migrator
+		if m, testDBErr = db.NewMigrator(testDB); testDBErr == nil {
+			_, testDBErr = m.Up(context.Background())
+		}
+	})
+	if testDBErr != nil {
+		t.Fatal(testDBErr)
+	}
+	_, err := testDB.Exec(`TRUNCATE "Anime", "Chapter", "User", "ReadingProgress", "ChapterRead", "Rating", "Favorite" RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testDB
+}
+
+// eachBackend runs test against a fresh Memory and, when TEST_DB_URL is
+// set, against an empty Postgres database.
+func eachBackend(t *testing.T, test func(t *testing.T, s backend)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, NewPostgres(postgresForTest(t)))
+	})
+}
+
+func createManga(t *testing.T, s backend, manga Manga) Manga {
+	t.Helper()
+	created, err := s.CreateManga(context.Background(), manga)
+	if err != nil {
+		t.Fatalf("CreateManga(%q): %v", manga.Name, err)
+	}
+	return created
+}
+
+func createChapter(t *testing.T, s backend, animeName string, chapter int, pages ...string) {
+	t.Helper()
+	_, err := s.CreateChapter(context.Background(), Chapter{AnimeName: animeName, Chapter: chapter, Img: pages})
+	if err != nil {
+		t.Fatalf("CreateChapter(%q, %d): %v", animeName, chapter, err)
+	}
+}
+
+func createUser(t *testing.T, s backend, id string) User {
+	t.Helper()
+	u := User{Id: id, Email: id + "@example.com", Name: id}
+	if err := s.Create(context.Background(), u); err != nil {
+		t.Fatalf("Create(%q): %v", id, err)
+	}
+	return u
+}
+
+func names(mangas []Manga) []string {
+	out := make([]string, len(mangas))
+	for i, m := range mangas {
+		out[i] = m.Name
+	}
+	return out
+}
+
+func chapterNumbers(chapters []Chapter) []int {
+	out := make([]int, len(chapters))
+	for i, c := range chapters {
+		out[i] = c.Chapter
+	}
+	return out
+}
+
+func TestAll(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		createManga(t, s, Manga{Name: "Berserk"})
+		createManga(t, s, Manga{Name: "Monster"})
+
+		mangas, err := s.All(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := names(mangas)
+		slices.Sort(got)
+		if want := []string{"Berserk", "Monster"}; !slices.Equal(got, want) {
+			t.Errorf("All = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestByName(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		created := createManga(t, s, Manga{Name: "Berserk", Author: "Kentaro Miura", Genres: []string{"dark fantasy"}})
+
+		manga, err := s.ByName(context.Background(), "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manga.Id != created.Id || manga.Author != "Kentaro Miura" || !slices.Equal(manga.Genres, []string{"dark fantasy"}) {
+			t.Errorf("ByName = %+v, want %+v", manga, created)
+		}
+		if _, err := s.ByName(context.Background(), "Vagabond"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ByName(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestChapters(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		createManga(t, s, Manga{Name: "Berserk"})
+		createManga(t, s, Manga{Name: "Monster"})
+		for _, n := range []int{3, 1, 2} {
+			createChapter(t, s, "Berserk", n)
+		}
+		createChapter(t, s, "Monster", 1)
+
+		chapters, err := s.Chapters(context.Background(), "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := chapterNumbers(chapters), []int{1, 2, 3}; !slices.Equal(got, want) {
+			t.Errorf("Chapters = %v, want %v", got, want)
+		}
+		chapters, err = s.Chapters(context.Background(), "Vagabond")
+		if err != nil || len(chapters) != 0 {
+			t.Errorf("Chapters(missing) = %v, %v, want none", chapters, err)
+		}
+	})
+}
+
+func TestChapter(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		createManga(t, s, Manga{Name: "Berserk"})
+		createChapter(t, s, "Berserk", 1, "a.png", "b.png")
+
+		c, err := s.Chapter(context.Background(), "Berserk", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.AnimeName != "Berserk" || !slices.Equal(c.Img, []string{"a.png", "b.png"}) {
+			t.Errorf("Chapter = %+v", c)
+		}
+		if _, err := s.Chapter(context.Background(), "Berserk", 2); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Chapter(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestByEmail(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		if _, err := s.SetFavorite(ctx, "u1", manga.Id, true); err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := s.ByEmail(ctx, "u1@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Id != "u1" || !slices.Equal(u.FavoriteIds, []int{manga.Id}) {
+			t.Errorf("ByEmail = %+v", u)
+		}
+		if _, err := s.ByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ByEmail(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestCreate(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		createUser(t, s, "u1")
+		u, err := s.ByEmail(context.Background(), "u1@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Name != "u1" || u.CreatedAt.IsZero() || len(u.FavoriteIds) != 0 {
+			t.Errorf("created user = %+v", u)
+		}
+
+		for _, dup := range []User{
+			{Id: "u2", Email: "u1@example.com"},
+			{Id: "u1", Email: "other@example.com"},
+		} {
+			if err := s.Create(context.Background(), dup); !errors.Is(err, ErrConflict) {
+				t.Errorf("Create(%+v) error = %v, want ErrConflict", dup, err)
+			}
+		}
+	})
+}
+
+func TestDeleteByEmail(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s backend) {
+		ctx := context.Background()
+		manga := createManga(t, s, Manga{Name: "Berserk"})
+		createUser(t, s, "u1")
+		createUser(t, s, "u2")
+		if _, err := s.SetFavorite(ctx, "u1", manga.Id, true); err != nil {
+			t.Fatal(err)
+		}
+		for user, score := range map[string]int{"u1": 10, "u2": 6} {
+			if _, err := s.Rate(ctx, Rating{UserId: user, AnimeName: "Berserk", Score: score}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SaveProgress(ctx, Progress{UserId: "u1", AnimeName: "Berserk", Chapter: 1, Page: 1, UpdatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetRead(ctx, "u1", "Berserk", []int{1}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.DeleteByEmail(ctx, "u1@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ByEmail(ctx, "u1@example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ByEmail after delete error = %v, want ErrNotFound", err)
+		}
+		got, err := s.ByName(ctx, "Berserk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Popularity != 0 || got.RatingCount != 1 || got.AverageRating != 6 {
+			t.Errorf("manga after delete: popularity %d, %d ratings averaging %v, want 0, 1 and 6",
+				got.Popularity, got.RatingCount, got.AverageRating)
+		}
+		// The same user signing up again starts afresh.
+		createUser(t, s, "u1")
+		if progress, err := s.ContinueReading(ctx, "u1", 10); err != nil || len(progress) != 0 {
+			t.Errorf("progress of the new u1 = %v, %v, want none", progress, err)
+		}
+		if read, err := s.ReadChapters(ctx, "u1", "Berserk"); err != nil || len(read) != 0 {
+			t.Errorf("read chapters of the new u1 = %v, %v, want none", read, err)
+		}
+		if err := s.DeleteByEmail(ctx, "u1@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteByEmail(ctx, "u1@example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeleteByEmail error = %v, want ErrNotFound", err)
+		}
+	})
+}
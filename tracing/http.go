@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.ResponseWriter.WriteHeader(status)
+	w.status = status
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware starts a server span per request, continuing the trace of the
+// caller's traceparent header. Spans are named by the mux pattern, like
+// the metrics, and the trace ID goes back in a traceparent header.
+func Middleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		_, route := mux.Handler(r)
+		name := route
+		if name == "" {
+			name = r.Method
+		}
+		ctx, span := tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLScheme(scheme(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+		propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+		}()
+		next.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
+
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
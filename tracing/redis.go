@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/go-redis/redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook records a client span per command or pipeline. Keys are left
+// out, only command names are recorded. Add it with rdb.AddHook.
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := tracer().Start(ctx, "redis dial", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis))
+		defer span.End()
+		conn, err := next(ctx, network, addr)
+		recordError(span, err)
+		return conn, err
+	}
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := tracer().Start(ctx, "redis "+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName(cmd.Name())))
+		defer span.End()
+		err := next(ctx, cmd)
+		recordError(span, err)
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.Name()
+		}
+		ctx, span := tracer().Start(ctx, "redis pipeline", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis,
+				attribute.String("db.redis.commands", strings.Join(names, " ")),
+				attribute.Int("db.redis.num_cmd", len(cmds))))
+		defer span.End()
+		err := next(ctx, cmds)
+		recordError(span, err)
+		return err
+	}
+}
+
+// recordError marks the span failed, except for redis.Nil which is a cache miss.
+func recordError(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
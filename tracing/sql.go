@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/chimas/GoProject/db"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryHook records a client span per Postgres statement, named by
+// db.StatementName. The statement text carries placeholders, never the
+// arguments. Pass it to db.Connect.
+func QueryHook(ctx context.Context, query string) func(error) {
+	_, span := tracer().Start(ctx, db.StatementName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(query),
+		),
+	)
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,81 @@
+// Package tracing sets up OpenTelemetry and traces HTTP requests, Postgres
+// statements and Redis commands. Spans follow r.Context() down to the
+// stores and the cache, every layer in between passes the context on.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/chimas/GoProject/tracing"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup installs the global tracer provider. exporter is "otlp", which
+// reads the standard OTEL_EXPORTER_OTLP_* variables, "file", which writes
+// one JSON span per line to file, or "" to trace nothing. Sampling follows
+// OTEL_TRACES_SAMPLER. The returned function flushes pending spans.
+func Setup(ctx context.Context, exporter, file, version string) (func(context.Context) error, error) {
+	var exp sdktrace.SpanExporter
+	var closeFile func() error
+	switch exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		exp = e
+	case "file":
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		e, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		exp, closeFile = e, f.Close
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", exporter)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName("manka-api"), semconv.ServiceVersion(version)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			if cerr := closeFile(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
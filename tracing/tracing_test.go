@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-redis/redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a tracer provider that keeps finished spans in memory for
+// the length of the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+// onlySpan returns the single span the recorder holds.
+func onlySpan(t *testing.T, recorder *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /manga/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	req := httptest.NewRequest("GET", "/manga/Berserk", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	Middleware(mux, mux).ServeHTTP(rec, req)
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "GET /manga/{name}" {
+		t.Errorf("span name = %q, want the route pattern", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the caller's", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span = %s, want the caller's", got)
+	}
+	if got := attr(span, "http.response.status_code").AsInt64(); got != http.StatusServiceUnavailable {
+		t.Errorf("status attribute = %d, want 503", got)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want an error for a 5xx", span.Status())
+	}
+	if got := rec.Header().Get("traceparent"); len(got) < 35 || got[3:35] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("response traceparent = %q, want the trace ID", got)
+	}
+}
+
+func TestMiddlewareUnmatched(t *testing.T) {
+	recorder := record(t)
+	mux := http.NewServeMux()
+	Middleware(mux, mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/user/alice@example.com", nil))
+
+	if span := onlySpan(t, recorder); span.Name() != "POST" {
+		t.Errorf("span name = %q, want only the method", span.Name())
+	}
+}
+
+func TestQueryHook(t *testing.T) {
+	recorder := record(t)
+	query := `SELECT * FROM "Anime" WHERE name = $1`
+	QueryHook(context.Background(), query)(errors.New("connection reset"))
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "select Anime" {
+		t.Errorf("span name = %q, want select Anime", span.Name())
+	}
+	if got := attr(span, "db.query.text").AsString(); got != query {
+		t.Errorf("query text = %q, want the statement", got)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want an error", span.Status())
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	recorder := record(t)
+	ctx := context.Background()
+	miss := RedisHook{}.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error { return redis.Nil })
+	if err := miss(ctx, redis.NewStringCmd(ctx, "get", "manga:Berserk")); !errors.Is(err, redis.Nil) {
+		t.Fatalf("error = %v, want redis.Nil passed through", err)
+	}
+
+	span := onlySpan(t, recorder)
+	if span.Name() != "redis get" {
+		t.Errorf("span name = %q, want redis get", span.Name())
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("a cache miss marked the span failed")
+	}
+	for _, kv := range span.Attributes() {
+		if kv.Value.Emit() == "manga:Berserk" {
+			t.Errorf("attribute %s records the key", kv.Key)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "", "", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if _, err := Setup(context.Background(), "jaeger", "", "test"); err == nil {
+		t.Error("unknown exporter accepted")
+	}
+}